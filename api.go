@@ -82,76 +82,18 @@ func (c *Client) ProductInfo(ctx context.Context, now time.Time, setTime bool) (
 	return reply, nil
 }
 
-// Settings returns the system version information.
-func (c *Client) Settings(ctx context.Context) (*api.Settings, error) {
-	req := api.SettingInfoRequest{}
-	reply := req.Reply()
-
-	err := c.Send(ctx, newRequest(req), reply)
-	if err != nil {
-		return nil, errors.Errorf("Settings failed: %w", err)
-	}
-	return reply, nil
-}
-
-// SystemVersion returns the system version information.
-func (c *Client) SystemVersion(ctx context.Context) (*api.SystemVersion, error) {
-	req := api.SystemVersionRequest{}
-	reply := req.Reply()
-
-	err := c.Send(ctx, newRequest(req), reply)
-	if err != nil {
-		return nil, errors.Errorf("SystemVersion failed: %w", err)
-	}
-	return reply, nil
-}
-
-// NetworkInfo returns the network information.
-//
-// WARNING: Returns WiFi password in plain text.
-func (c *Client) NetworkInfo(ctx context.Context) (*api.NetworkInfo, error) {
-	req := api.NetworkInfoRequest{}
-	reply := req.Reply()
-
-	err := c.Send(ctx, newRequest(req), reply)
-	if err != nil {
-		return nil, errors.Errorf("NetworkInfo failed: %w", err)
-	}
-	return reply, nil
-}
-
-// PlayInfo returns information on what's playing.
-func (c *Client) PlayInfo(ctx context.Context) (*api.PlayInfo, error) {
-	req := api.PlayInfoRequest{}
-	reply := req.Reply()
-
-	err := c.Send(ctx, newRequest(req), reply)
-	if err != nil {
-		return nil, errors.Errorf("PlayInfo failed: %w", err)
-	}
-	return reply, nil
-}
-
-// EqualizerInfo returns information on what's playing.
-func (c *Client) EqualizerInfo(ctx context.Context) (*api.EqualizerInfo, error) {
-	req := api.EqualizerInfoRequest{}
-	reply := req.Reply()
-
-	err := c.Send(ctx, newRequest(req), reply)
-	if err != nil {
-		return nil, errors.Errorf("EqualizerInfo failed: %w", err)
-	}
-	return reply, nil
-}
-
 // EqualizerSetting represents an equalizer setting.
 type EqualizerSetting func(context.Context, *Client) error
 
 // SetBass sets the bass level.
 func SetBass(value int) EqualizerSetting {
 	return func(ctx context.Context, c *Client) error {
+		err := c.supports(ctx, "bass", hasToneControl)
+		if err != nil {
+			return errors.Errorf("SetBass failed: %w", err)
+		}
 		req := api.EqualizerSetRequest{Type: api.SetBass, Value: value}
-		err := c.Send(ctx, newRequest(req), nil)
+		err = c.Send(ctx, newRequest(req), nil)
 		if err != nil {
 			return errors.Errorf("SetBass failed: %w", err)
 		}
@@ -162,8 +104,14 @@ func SetBass(value int) EqualizerSetting {
 // SetEqualizer sets the equalizer.
 func SetEqualizer(value api.Equalizer) EqualizerSetting {
 	return func(ctx context.Context, c *Client) error {
-		req := api.EqualizerSetRequest{Type: api.SetBass, Value: int(value)}
-		err := c.Send(ctx, newRequest(req), nil)
+		err := c.supports(ctx, value.String(), func(caps *Capabilities) bool {
+			return caps.HasEqualizer(value)
+		})
+		if err != nil {
+			return errors.Errorf("SetEqualizer failed: %w", err)
+		}
+		req := api.EqualizerSetRequest{Type: api.SetEqualizer, Value: int(value)}
+		err = c.Send(ctx, newRequest(req), nil)
 		if err != nil {
 			return errors.Errorf("SetEqualizer failed: %w", err)
 		}
@@ -174,8 +122,14 @@ func SetEqualizer(value api.Equalizer) EqualizerSetting {
 // SetLeftRightBalance sets the left right balance.
 func SetLeftRightBalance(value int) EqualizerSetting {
 	return func(ctx context.Context, c *Client) error {
+		err := c.supports(ctx, "balance", func(caps *Capabilities) bool {
+			return caps.Balance
+		})
+		if err != nil {
+			return errors.Errorf("SetLeftRightBalance failed: %w", err)
+		}
 		req := api.EqualizerSetRequest{Type: api.SetLeftRightBalance, Value: value}
-		err := c.Send(ctx, newRequest(req), nil)
+		err = c.Send(ctx, newRequest(req), nil)
 		if err != nil {
 			return errors.Errorf("SetLeftRightBalance failed: %w", err)
 		}
@@ -186,8 +140,12 @@ func SetLeftRightBalance(value int) EqualizerSetting {
 // SetTreble sets treble.
 func SetTreble(value int) EqualizerSetting {
 	return func(ctx context.Context, c *Client) error {
+		err := c.supports(ctx, "treble", hasToneControl)
+		if err != nil {
+			return errors.Errorf("SetTreble failed: %w", err)
+		}
 		req := api.EqualizerSetRequest{Type: api.SetTreble, Value: value}
-		err := c.Send(ctx, newRequest(req), nil)
+		err = c.Send(ctx, newRequest(req), nil)
 		if err != nil {
 			return errors.Errorf("SetTreble failed: %w", err)
 		}
@@ -195,6 +153,8 @@ func SetTreble(value int) EqualizerSetting {
 	}
 }
 
+func hasToneControl(caps *Capabilities) bool { return caps.ToneControl }
+
 // SaveEqualizerSettings saves the current equalizer settings as default.
 func SaveEqualizerSettings() EqualizerSetting {
 	return func(ctx context.Context, c *Client) error {
@@ -230,38 +190,18 @@ func (c *Client) Equalizer(ctx context.Context, eq ...EqualizerSetting) error {
 	return nil
 }
 
-// FunctionInfo sets the provided equalizer settings.
-func (c *Client) FunctionInfo(ctx context.Context) (*api.FunctionInfo, error) {
-	req := api.FunctionInfoRequest{}
-	reply := req.Reply()
-
-	err := c.Send(ctx, newRequest(req), reply)
-	if err != nil {
-		return nil, errors.Errorf("FunctionInfo failed: %w", err)
-	}
-	return reply, nil
-}
-
 // Function activates the provided function.
 func (c *Client) Function(ctx context.Context, f api.Function) error {
-	req := api.FunctionSetRequest{Type: f}
-	err := c.Send(ctx, newRequest(req), nil)
+	err := c.supports(ctx, f.String(), func(caps *Capabilities) bool {
+		return caps.HasFunction(f)
+	})
 	if err != nil {
-		return errors.Errorf("EqualizerInfo failed: %w", err)
+		return errors.Errorf("Function failed: %w", err)
 	}
-	return nil
-}
-
-// NightMode sets the nightmode on or off.
-func (c *Client) NightMode(ctx context.Context, on bool) error {
-	req := api.NightModeSetRequest{NightMode: on}
-	reply := req.Reply()
-	err := c.Send(ctx, newRequest(req), reply)
+	req := api.FunctionSetRequest{Type: f}
+	err = c.Send(ctx, newRequest(req), nil)
 	if err != nil {
-		return errors.Errorf("NightMode failed: %w", err)
-	}
-	if reply.NightMode != on {
-		return errors.New("NightMode: wrong return value")
+		return errors.Errorf("Function failed: %w", err)
 	}
 	return nil
 }
@@ -276,88 +216,104 @@ func (c *Client) Volume(ctx context.Context, volume, fadetime int) error {
 	return nil
 }
 
-// WooferLevel sets the woofer level.
-func (c *Client) WooferLevel(ctx context.Context, level int) error {
-	req := api.WooferLevelSetRequest{Level: level}
+// AlarmCreate creates a new alarm, returns the ID of the created alarm.
+func (c *Client) AlarmCreate(ctx context.Context, a api.Alarm) (id int, err error) {
+	a.ID = -1
+	a.ModifiedID = 0
+	a.Mode = api.AlarmCreate
+	req := api.AlarmSetRequest{
+		Alarm: a,
+	}
 	reply := req.Reply()
-	err := c.Send(ctx, newRequest(req), reply)
+	err = c.Send(ctx, newRequest(req), reply)
 	if err != nil {
-		return errors.Errorf("WooferLevel failed: %w", err)
+		return 0, errors.Errorf("AlarmCreate failed: %w", err)
 	}
-	if reply.Level != level {
-		return errors.New("WooferLevel: wrong return value")
-	}
-	return nil
+	return reply.ID, nil
 }
 
-// Mute the speaker.
-func (c *Client) Mute(ctx context.Context, on bool) error {
-	req := api.MuteSetRequest{Mute: on}
-	err := c.Send(ctx, newRequest(req), nil)
-	if err != nil {
-		return errors.Errorf("Mute failed: %w", err)
+// AlarmUpdate modifies an existing alarm, the alarm is identified by
+// ID and ModifiedID is set to the ID.
+//
+// Experimental: the modify mode (AlarmModify) has not been observed,
+// the request may change or be removed. Use AlarmDelete and
+// AlarmCreate if the speaker rejects it.
+func (c *Client) AlarmUpdate(ctx context.Context, a api.Alarm) error {
+	a.ModifiedID = a.ID
+	a.Mode = api.AlarmModify
+	req := api.AlarmSetRequest{
+		Alarm: a,
 	}
-	return nil
-}
-
-// Alarms lists all alarms.
-func (c *Client) Alarms(ctx context.Context) ([]api.Alarm, error) {
-	req := api.AlarmListRequest{}
 	reply := req.Reply()
 	err := c.Send(ctx, newRequest(req), reply)
 	if err != nil {
-		return nil, errors.Errorf("Alarms failed: %w", err)
+		return errors.Errorf("AlarmUpdate failed: %w", err)
 	}
-	return reply.Info, nil
+	return nil
 }
 
-// AlarmCreate creates a new alarm.
-func (c *Client) AlarmCreate(ctx context.Context, a api.Alarm) (id int, err error) {
-	a.ID = -1
-	a.Mode = api.AlarmCreate
-	a.Day = api.AlarmDays(time.Monday, time.Tuesday)
+// AlarmDelete deletes the alarm.
+func (c *Client) AlarmDelete(ctx context.Context, a api.Alarm) error {
+	a.ModifiedID = 0
+	a.Mode = api.AlarmDelete
 	req := api.AlarmSetRequest{
 		Alarm: a,
 	}
 	reply := req.Reply()
-	err = c.Send(ctx, newRequest(req), reply)
+	err := c.Send(ctx, newRequest(req), reply)
 	if err != nil {
-		return 0, errors.Errorf("SetAlarm failed: %w", err)
+		return errors.Errorf("AlarmDelete failed: %w", err)
 	}
-	return reply.ID, nil
+	return nil
 }
 
-// AlarmDelete deletes the alarm.
-func (c *Client) AlarmDelete(ctx context.Context, a api.Alarm) error {
-	a.Mode = api.AlarmDelete
+// AlarmEnable enables the alarm.
+func (c *Client) AlarmEnable(ctx context.Context, a api.Alarm) error {
+	// The app sends the state before the change, i.e. disabled.
+	a.Enable = false
+	a.ModifiedID = 0
+	a.Mode = api.AlarmEnable
 	req := api.AlarmSetRequest{
 		Alarm: a,
 	}
 	reply := req.Reply()
 	err := c.Send(ctx, newRequest(req), reply)
 	if err != nil {
-		return errors.Errorf("AlarmDelete failed: %w", err)
+		return errors.Errorf("AlarmEnable failed: %w", err)
 	}
 	return nil
 }
 
-// AlarmState returns true if an alarm is active right now.
-func (c *Client) AlarmState(ctx context.Context) (on bool, err error) {
-	req := api.AlarmStateRequest{}
+// AlarmDisable disables the alarm.
+func (c *Client) AlarmDisable(ctx context.Context, a api.Alarm) error {
+	// The app sends the state before the change, i.e. enabled.
+	a.Enable = true
+	a.ModifiedID = 0
+	a.Mode = api.AlarmDisable
+	req := api.AlarmSetRequest{
+		Alarm: a,
+	}
 	reply := req.Reply()
-	err = c.Send(ctx, newRequest(req), reply, WaitFor(api.MessageAlarmStateNotification, ""))
+	err := c.Send(ctx, newRequest(req), reply)
 	if err != nil {
-		return false, errors.Errorf("AlarmState failed: %w", err)
+		return errors.Errorf("AlarmDisable failed: %w", err)
 	}
-	return reply.On, nil
+	return nil
 }
 
-// SleepAfter sets the sleep timer. Set -1 to disable.
-func (c *Client) SleepAfter(ctx context.Context, minutes int) error {
-	req := api.SleepSetRequest{Time: minutes}
+// AlarmSetPlaylist sets the songs played by the alarm.
+//
+// Experimental: SET_ALARM_PLAYLIST has not been observed, the request
+// may change or be removed.
+func (c *Client) AlarmSetPlaylist(ctx context.Context, a api.Alarm, songs ...api.AlarmSong) error {
+	req := api.SetAlarmPlaylistRequest{
+		ID:       a.ID,
+		Shuffle:  a.Shuffle,
+		Playlist: songs,
+	}
 	err := c.Send(ctx, newRequest(req), nil)
 	if err != nil {
-		return errors.Errorf("SleepAfter failed: %w", err)
+		return errors.Errorf("AlarmSetPlaylist failed: %w", err)
 	}
 	return nil
 }
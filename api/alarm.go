@@ -1,13 +1,25 @@
 package api
 
-import "time"
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// AlarmDefaultSound is the title used for alarms without a song.
+const AlarmDefaultSound = "Default alarm sound"
 
 // AlarmMode represents the mode for the alarm.
 type AlarmMode int
 
 // AlarmMode enums.
 const (
-	AlarmCreate  AlarmMode = 0
+	AlarmCreate AlarmMode = 0
+	// AlarmModify has not been observed, it's inferred from the gap
+	// between create and delete and from ModifiedID.
+	AlarmModify  AlarmMode = 1
 	AlarmDelete  AlarmMode = 2
 	AlarmEnable  AlarmMode = 3
 	AlarmDisable AlarmMode = 4
@@ -15,31 +27,165 @@ const (
 
 // Alarm for speaker.
 type Alarm struct {
-	ID         int    `json:"id"`        // When creating alarm, -1.
-	Day        int    `json:"day"`       // Days when it is active, e.g. Monday + Tuesday = 0b1100000.
-	DayRepeat  bool   `json:"dayrepeat"` // Part of request, significance?
-	Hour       int    `json:"hour"`
-	Minute     int    `json:"minute"`
-	Type       int    `json:"type"`     // Always 0?
-	Duration   int    `json:"duration"` // Duration in minutes.
-	Volume     int    `json:"volume"`
-	Enable     bool   `json:"enable"`
-	Shuffle    bool   `json:"shuffle"`
-	Title      string `json:"title"`      // "Default alarm sound"
-	ModifiedID int    `json:"modifiedid"` // Part of request, significance?
-	M2ID       string `json:"m2id"`       // Part of response, significance?
-	IPAddr     string `json:"ipaddress"`  // Song server addr? Samba?
-	SongPath   string `json:"songpath"`   // Samba path?
+	ID         int     `json:"id"`        // When creating alarm, -1.
+	Day        DaySet  `json:"day"`       // Days when it is active, e.g. Monday + Tuesday = 0b1100000.
+	DayRepeat  bool    `json:"dayrepeat"` // Part of request, significance?
+	Hour       int     `json:"hour"`
+	Minute     int     `json:"minute"`
+	Type       int     `json:"type"`     // Always 0?
+	Duration   int     `json:"duration"` // Duration in minutes.
+	Volume     int     `json:"volume"`
+	Enable     bool    `json:"enable"`
+	Shuffle    bool    `json:"shuffle"`
+	Title      string  `json:"title"`      // "Default alarm sound"
+	ModifiedID int     `json:"modifiedid"` // ID of the alarm being modified, otherwise 0.
+	M2ID       MediaID `json:"m2id"`       // Part of response.
+	IPAddr     string  `json:"ipaddress"`  // Song server addr.
+	SongPath   string  `json:"songpath"`   // Song path or URL on the song server.
 
 	Mode AlarmMode `json:"mode,omitempty"`
 }
 
+// Song returns the song used by the alarm.
+func (a Alarm) Song() AlarmSong {
+	return AlarmSong{
+		Title:    a.Title,
+		IPAddr:   a.IPAddr,
+		SongPath: a.SongPath,
+		M2ID:     a.M2ID,
+	}
+}
+
+// SetSong sets the song used by the alarm, the zero value restores
+// the default alarm sound.
+func (a *Alarm) SetSong(s AlarmSong) {
+	if s == (AlarmSong{}) {
+		s.Title = AlarmDefaultSound
+	}
+	a.Title = s.Title
+	a.IPAddr = s.IPAddr
+	a.SongPath = s.SongPath
+	a.M2ID = s.M2ID
+}
+
+// Next returns the next time the alarm is triggered after now, in the
+// location of now. Alarms without days trigger on the next occurrence
+// of the alarm time. Returns the zero time if the alarm is disabled.
+func (a Alarm) Next(now time.Time) time.Time {
+	if !a.Enable {
+		return time.Time{}
+	}
+	for i := 0; i <= 7; i++ {
+		d := now.AddDate(0, 0, i)
+		t := time.Date(d.Year(), d.Month(), d.Day(), a.Hour, a.Minute, 0, 0, now.Location())
+		if !t.After(now) {
+			continue
+		}
+		if a.Day == 0 || a.Day.Has(t.Weekday()) {
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+// DaySet is a set of days encoded as a bitmask, Monday is the most
+// significant bit and Sunday the least, e.g. Monday + Tuesday = 0b1100000.
+type DaySet int
+
 // AlarmDays enables one or more day(s) for an alarm.
-func AlarmDays(days ...time.Weekday) int {
-	var ad int
+func AlarmDays(days ...time.Weekday) DaySet {
+	var ds DaySet
 	for _, wd := range days {
-		d := FromWeekday(wd)
-		ad |= 1 << (6 - d)
+		ds |= dayBit(FromWeekday(wd))
 	}
-	return ad
+	return ds
+}
+
+func dayBit(d Day) DaySet {
+	return 1 << (6 - d)
+}
+
+// allDays are the bits of the days, Monday to Sunday.
+const allDays DaySet = 0x7f
+
+// Has returns true if the weekday is in the set.
+func (s DaySet) Has(wd time.Weekday) bool {
+	return s&dayBit(FromWeekday(wd)) != 0
+}
+
+// Days returns the days in the set, starting from Monday.
+func (s DaySet) Days() []Day {
+	var days []Day
+	for d := Monday; d <= Sunday; d++ {
+		if s&dayBit(d) != 0 {
+			days = append(days, d)
+		}
+	}
+	return days
+}
+
+// Weekdays returns the weekdays in the set, starting from Monday.
+func (s DaySet) Weekdays() []time.Weekday {
+	var wds []time.Weekday
+	for _, d := range s.Days() {
+		wds = append(wds, d.Weekday())
+	}
+	return wds
+}
+
+// String returns the days separated by comma, e.g. "Monday, Tuesday",
+// or "None". Unknown bits are added as DaySet(N), see ParseDaySet.
+func (s DaySet) String() string {
+	var names []string
+	for _, d := range s.Days() {
+		names = append(names, d.String())
+	}
+	if unknown := s &^ allDays; unknown != 0 {
+		names = append(names, fmt.Sprintf("DaySet(%d)", int(unknown)))
+	}
+	if len(names) == 0 {
+		return "None"
+	}
+	return strings.Join(names, ", ")
+}
+
+// AlarmSong represents a song on a media server that can be used as
+// an alarm sound.
+type AlarmSong struct {
+	Title    string  `json:"title"`
+	IPAddr   string  `json:"ipaddress"`
+	SongPath string  `json:"songpath"`
+	M2ID     MediaID `json:"m2id,omitempty"`
+}
+
+// MediaID identifies media on a media server (m2id). Usually empty,
+// but both JSON strings and numbers are accepted.
+type MediaID string
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (id *MediaID) UnmarshalJSON(b []byte) error {
+	if string(b) == "null" {
+		*id = ""
+		return nil
+	}
+	if len(b) > 0 && b[0] == '"' {
+		var s string
+		if err := json.Unmarshal(b, &s); err != nil {
+			return err
+		}
+		*id = MediaID(s)
+		return nil
+	}
+	var n json.Number
+	if err := json.Unmarshal(b, &n); err != nil {
+		return err
+	}
+	*id = MediaID(n.String())
+	return nil
+}
+
+// Int returns the media ID as an integer, if possible.
+func (id MediaID) Int() (int, bool) {
+	n, err := strconv.Atoi(string(id))
+	return n, err == nil
 }
@@ -1,9 +1,6 @@
 package api
 
-import (
-	"fmt"
-	"time"
-)
+import "time"
 
 // Day represents the day of week.
 type Day int
@@ -19,27 +16,6 @@ const (
 	Sunday
 )
 
-func (d Day) String() string {
-	switch d {
-	case Monday:
-		return "Monday"
-	case Tuesday:
-		return "Tuesday"
-	case Wednesday:
-		return "Wednesday"
-	case Thursday:
-		return "Thursday"
-	case Friday:
-		return "Friday"
-	case Saturday:
-		return "Saturday"
-	case Sunday:
-		return "Sunday"
-	default:
-		return fmt.Sprintf("Day(%d)", d)
-	}
-}
-
 // FromWeekday converts the provided weekday to Day format.
 func FromWeekday(d time.Weekday) Day {
 	switch d {
@@ -49,3 +25,13 @@ func FromWeekday(d time.Weekday) Day {
 		return Day(d - 1)
 	}
 }
+
+// Weekday converts the day to time.Weekday.
+func (d Day) Weekday() time.Weekday {
+	switch d {
+	case Sunday:
+		return time.Sunday
+	default:
+		return time.Weekday(d + 1)
+	}
+}
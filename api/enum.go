@@ -1,7 +1,5 @@
 package api
 
-import "fmt"
-
 // Equalizer represents an equalizer. A list of possible equalizers are
 // available when requesting product info.
 type Equalizer int
@@ -29,49 +27,6 @@ const (
 	EqualizerBassBoostPlus Equalizer = 17
 )
 
-func (e Equalizer) String() string {
-	switch e {
-	case EqualizerStandard:
-		return "Standard"
-	case EqualizerBass:
-		return "Bass"
-	case EqualizerFlat:
-		return "Flat"
-	case EqualizerBoost:
-		return "Boost"
-	case EqualizerTrebleBass:
-		return "Treble and Bass"
-	case EqualizerUser:
-		return "User"
-	case EqualizerMusic:
-		return "Music"
-	case EqualizerCinema:
-		return "Cinema"
-	case EqualizerNight:
-		return "Night"
-	case EqualizerNews:
-		return "News"
-	case EqualizerVoice:
-		return "Voice"
-	case EqualizerISound:
-		return "ISound"
-	case EqualizerASC:
-		return "ASC"
-	case EqualizerMovie:
-		return "Movie"
-	case EqualizerBassBlast:
-		return "Bass Blast"
-	case EqualizerDolbyAtmos:
-		return "Dolby Atmos"
-	case EqualizerDTSVirtualX:
-		return "DTS Virtual X"
-	case EqualizerBassBoostPlus:
-		return "Bass Boost Plus"
-	default:
-		return fmt.Sprintf("Equalizer(%d)", e)
-	}
-}
-
 // EqualizerType is a setting type for changing equalizer settings.
 type EqualizerType int
 
@@ -91,23 +46,6 @@ const (
 	SetSaveRestore EqualizerType = 4
 )
 
-func (t EqualizerType) String() string {
-	switch t {
-	case SetEqualizer:
-		return "Equalizer"
-	case SetBass:
-		return "Bass"
-	case SetTreble:
-		return "Treble"
-	case SetLeftRightBalance:
-		return "LeftRightBalance"
-	case SetSaveRestore:
-		return "SaveRestore"
-	default:
-		return fmt.Sprintf("EqualizerType(%d)", t)
-	}
-}
-
 // Function represents a function mode for the speaker. A list of
 // available modes are available when requesting product info.
 type Function int
@@ -136,51 +74,6 @@ const (
 	FunctionUSB        Function = 18
 )
 
-func (f Function) String() string {
-	switch f {
-	case FunctionWiFi:
-		return "WiFi"
-	case FunctionBluetooth:
-		return "Bluetooth"
-	case FunctionPortable:
-		return "Portable"
-	case FunctionAUX:
-		return "AUX"
-	case FunctionOptical:
-		return "Optical"
-	case FunctionCP:
-		return "CP"
-	case FunctionHDMI:
-		return "HDMI"
-	case FunctionARC:
-		return "ARC"
-	case FunctionSpotify:
-		return "Spotify"
-	case FunctionOptical2:
-		return "Optical2"
-	case FunctionHDMI2:
-		return "HDMI2"
-	case FunctionHDMI3:
-		return "HDMI3"
-	case FunctionLGTV:
-		return "LGTV"
-	case FunctionMic:
-		return "Microphone"
-	case FunctionC4A:
-		return "C4A"
-	case FunctionOpticalARC:
-		return "Optical / HDMI ARC"
-	case FunctionLGOptical:
-		return "LG Optical"
-	case FunctionFM:
-		return "FM"
-	case FunctionUSB:
-		return "USB"
-	default:
-		return fmt.Sprintf("Function(%d)", f)
-	}
-}
-
 // Model represents the speaker model.
 type Model int
 
@@ -194,25 +87,6 @@ const (
 	ModelPortable  Model = 5
 )
 
-func (m Model) String() string {
-	switch m {
-	case ModelBridge:
-		return "Bridge"
-	case ModelBasic:
-		return "Basic"
-	case ModelSoundBar:
-		return "SoundBar"
-	case ModelMono:
-		return "Mono"
-	case ModelConnector:
-		return "Connector"
-	case ModelPortable:
-		return "Portable"
-	default:
-		return fmt.Sprintf("Model(%d)", m)
-	}
-}
-
 // Network represents the connection type.
 type Network int
 
@@ -223,19 +97,6 @@ const (
 	NetworkMeshed   Network = 2
 )
 
-func (n Network) String() string {
-	switch n {
-	case NetworkWired:
-		return "Wired"
-	case NetworkWireless:
-		return "Wireless"
-	case NetworkMeshed:
-		return "Meshed"
-	default:
-		return fmt.Sprintf("Network(%d)", n)
-	}
-}
-
 // Role represents the speakers role.
 type Role int
 
@@ -247,20 +108,3 @@ const (
 	RoleSurroundMaster Role = 3
 	RoleSurroundSlave  Role = 4
 )
-
-func (r Role) String() string {
-	switch r {
-	case RoleIndividual:
-		return "Individual"
-	case RoleMaster:
-		return "Master"
-	case RoleSlave:
-		return "Slave"
-	case RoleSurroundMaster:
-		return "Surround Master"
-	case RoleSurroundSlave:
-		return "Surround Slave"
-	default:
-		return fmt.Sprintf("Role(%d)", r)
-	}
-}
@@ -0,0 +1,566 @@
+// Code generated by protogen from api/protocol.json. DO NOT EDIT.
+
+package api
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// Number of known values per enum, the values are contiguous from zero.
+const (
+	equalizerCount     = int(EqualizerBassBoostPlus) + 1
+	equalizerTypeCount = int(SetSaveRestore) + 1
+	functionCount      = int(FunctionUSB) + 1
+	modelCount         = int(ModelPortable) + 1
+	networkCount       = int(NetworkMeshed) + 1
+	roleCount          = int(RoleSurroundSlave) + 1
+	dayCount           = int(Sunday) + 1
+)
+
+// String returns the name of the Equalizer, e.g. "Standard", or
+// Equalizer(N) for unknown values.
+func (e Equalizer) String() string {
+	switch e {
+	case EqualizerStandard:
+		return "Standard"
+	case EqualizerBass:
+		return "Bass"
+	case EqualizerFlat:
+		return "Flat"
+	case EqualizerBoost:
+		return "Boost"
+	case EqualizerTrebleBass:
+		return "Treble and Bass"
+	case EqualizerUser:
+		return "User"
+	case EqualizerMusic:
+		return "Music"
+	case EqualizerCinema:
+		return "Cinema"
+	case EqualizerNight:
+		return "Night"
+	case EqualizerNews:
+		return "News"
+	case EqualizerVoice:
+		return "Voice"
+	case EqualizerISound:
+		return "ISound"
+	case EqualizerASC:
+		return "ASC"
+	case EqualizerMovie:
+		return "Movie"
+	case EqualizerBassBlast:
+		return "Bass Blast"
+	case EqualizerDolbyAtmos:
+		return "Dolby Atmos"
+	case EqualizerDTSVirtualX:
+		return "DTS Virtual X"
+	case EqualizerBassBoostPlus:
+		return "Bass Boost Plus"
+	default:
+		return fmt.Sprintf("Equalizer(%d)", e)
+	}
+}
+
+// EqualizerValues returns all known Equalizer values.
+func EqualizerValues() []Equalizer {
+	v := make([]Equalizer, equalizerCount)
+	for i := range v {
+		v[i] = Equalizer(i)
+	}
+	return v
+}
+
+var equalizerAliases = map[string]int{
+	"treblebass": int(EqualizerTrebleBass),
+}
+
+// ParseEqualizer parses the name or number of an Equalizer.
+func ParseEqualizer(s string) (Equalizer, error) {
+	v, err := parseEnum("Equalizer", s, equalizerCount, func(i int) string { return Equalizer(i).String() }, equalizerAliases)
+	return Equalizer(v), err
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (e Equalizer) MarshalText() ([]byte, error) {
+	return []byte(e.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (e *Equalizer) UnmarshalText(b []byte) error {
+	v, err := ParseEqualizer(string(b))
+	if err != nil {
+		return err
+	}
+	*e = v
+	return nil
+}
+
+// MarshalJSON implements json.Marshaler, the numeric value is used.
+func (e Equalizer) MarshalJSON() ([]byte, error) {
+	return strconv.AppendInt(nil, int64(e), 10), nil
+}
+
+// UnmarshalJSON implements json.Unmarshaler, both numbers and names
+// are accepted.
+func (e *Equalizer) UnmarshalJSON(b []byte) error {
+	v, err := unmarshalEnumJSON(b, func(s string) (int, error) {
+		v, err := ParseEqualizer(s)
+		return int(v), err
+	})
+	if err != nil {
+		return err
+	}
+	*e = Equalizer(v)
+	return nil
+}
+
+// String returns the name of the EqualizerType, e.g. "Equalizer", or
+// EqualizerType(N) for unknown values.
+func (t EqualizerType) String() string {
+	switch t {
+	case SetEqualizer:
+		return "Equalizer"
+	case SetBass:
+		return "Bass"
+	case SetTreble:
+		return "Treble"
+	case SetLeftRightBalance:
+		return "LeftRightBalance"
+	case SetSaveRestore:
+		return "SaveRestore"
+	default:
+		return fmt.Sprintf("EqualizerType(%d)", t)
+	}
+}
+
+// EqualizerTypeValues returns all known EqualizerType values.
+func EqualizerTypeValues() []EqualizerType {
+	v := make([]EqualizerType, equalizerTypeCount)
+	for i := range v {
+		v[i] = EqualizerType(i)
+	}
+	return v
+}
+
+var equalizerTypeAliases = map[string]int{
+	"balance": int(SetLeftRightBalance),
+	"eq":      int(SetEqualizer),
+}
+
+// ParseEqualizerType parses the name or number of an EqualizerType.
+func ParseEqualizerType(s string) (EqualizerType, error) {
+	v, err := parseEnum("EqualizerType", s, equalizerTypeCount, func(i int) string { return EqualizerType(i).String() }, equalizerTypeAliases)
+	return EqualizerType(v), err
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (t EqualizerType) MarshalText() ([]byte, error) {
+	return []byte(t.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (t *EqualizerType) UnmarshalText(b []byte) error {
+	v, err := ParseEqualizerType(string(b))
+	if err != nil {
+		return err
+	}
+	*t = v
+	return nil
+}
+
+// MarshalJSON implements json.Marshaler, the numeric value is used.
+func (t EqualizerType) MarshalJSON() ([]byte, error) {
+	return strconv.AppendInt(nil, int64(t), 10), nil
+}
+
+// UnmarshalJSON implements json.Unmarshaler, both numbers and names
+// are accepted.
+func (t *EqualizerType) UnmarshalJSON(b []byte) error {
+	v, err := unmarshalEnumJSON(b, func(s string) (int, error) {
+		v, err := ParseEqualizerType(s)
+		return int(v), err
+	})
+	if err != nil {
+		return err
+	}
+	*t = EqualizerType(v)
+	return nil
+}
+
+// String returns the name of the Function, e.g. "WiFi", or
+// Function(N) for unknown values.
+func (f Function) String() string {
+	switch f {
+	case FunctionWiFi:
+		return "WiFi"
+	case FunctionBluetooth:
+		return "Bluetooth"
+	case FunctionPortable:
+		return "Portable"
+	case FunctionAUX:
+		return "AUX"
+	case FunctionOptical:
+		return "Optical"
+	case FunctionCP:
+		return "CP"
+	case FunctionHDMI:
+		return "HDMI"
+	case FunctionARC:
+		return "ARC"
+	case FunctionSpotify:
+		return "Spotify"
+	case FunctionOptical2:
+		return "Optical2"
+	case FunctionHDMI2:
+		return "HDMI2"
+	case FunctionHDMI3:
+		return "HDMI3"
+	case FunctionLGTV:
+		return "LGTV"
+	case FunctionMic:
+		return "Microphone"
+	case FunctionC4A:
+		return "C4A"
+	case FunctionOpticalARC:
+		return "Optical / HDMI ARC"
+	case FunctionLGOptical:
+		return "LG Optical"
+	case FunctionFM:
+		return "FM"
+	case FunctionUSB:
+		return "USB"
+	default:
+		return fmt.Sprintf("Function(%d)", f)
+	}
+}
+
+// FunctionValues returns all known Function values.
+func FunctionValues() []Function {
+	v := make([]Function, functionCount)
+	for i := range v {
+		v[i] = Function(i)
+	}
+	return v
+}
+
+var functionAliases = map[string]int{
+	"bt":         int(FunctionBluetooth),
+	"mic":        int(FunctionMic),
+	"opticalarc": int(FunctionOpticalARC),
+}
+
+// ParseFunction parses the name or number of a Function.
+func ParseFunction(s string) (Function, error) {
+	v, err := parseEnum("Function", s, functionCount, func(i int) string { return Function(i).String() }, functionAliases)
+	return Function(v), err
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (f Function) MarshalText() ([]byte, error) {
+	return []byte(f.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (f *Function) UnmarshalText(b []byte) error {
+	v, err := ParseFunction(string(b))
+	if err != nil {
+		return err
+	}
+	*f = v
+	return nil
+}
+
+// MarshalJSON implements json.Marshaler, the numeric value is used.
+func (f Function) MarshalJSON() ([]byte, error) {
+	return strconv.AppendInt(nil, int64(f), 10), nil
+}
+
+// UnmarshalJSON implements json.Unmarshaler, both numbers and names
+// are accepted.
+func (f *Function) UnmarshalJSON(b []byte) error {
+	v, err := unmarshalEnumJSON(b, func(s string) (int, error) {
+		v, err := ParseFunction(s)
+		return int(v), err
+	})
+	if err != nil {
+		return err
+	}
+	*f = Function(v)
+	return nil
+}
+
+// String returns the name of the Model, e.g. "Bridge", or Model(N)
+// for unknown values.
+func (m Model) String() string {
+	switch m {
+	case ModelBridge:
+		return "Bridge"
+	case ModelBasic:
+		return "Basic"
+	case ModelSoundBar:
+		return "SoundBar"
+	case ModelMono:
+		return "Mono"
+	case ModelConnector:
+		return "Connector"
+	case ModelPortable:
+		return "Portable"
+	default:
+		return fmt.Sprintf("Model(%d)", m)
+	}
+}
+
+// ModelValues returns all known Model values.
+func ModelValues() []Model {
+	v := make([]Model, modelCount)
+	for i := range v {
+		v[i] = Model(i)
+	}
+	return v
+}
+
+// ParseModel parses the name or number of a Model.
+func ParseModel(s string) (Model, error) {
+	v, err := parseEnum("Model", s, modelCount, func(i int) string { return Model(i).String() }, nil)
+	return Model(v), err
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (m Model) MarshalText() ([]byte, error) {
+	return []byte(m.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (m *Model) UnmarshalText(b []byte) error {
+	v, err := ParseModel(string(b))
+	if err != nil {
+		return err
+	}
+	*m = v
+	return nil
+}
+
+// MarshalJSON implements json.Marshaler, the numeric value is used.
+func (m Model) MarshalJSON() ([]byte, error) {
+	return strconv.AppendInt(nil, int64(m), 10), nil
+}
+
+// UnmarshalJSON implements json.Unmarshaler, both numbers and names
+// are accepted.
+func (m *Model) UnmarshalJSON(b []byte) error {
+	v, err := unmarshalEnumJSON(b, func(s string) (int, error) {
+		v, err := ParseModel(s)
+		return int(v), err
+	})
+	if err != nil {
+		return err
+	}
+	*m = Model(v)
+	return nil
+}
+
+// String returns the name of the Network, e.g. "Wired", or Network(N)
+// for unknown values.
+func (n Network) String() string {
+	switch n {
+	case NetworkWired:
+		return "Wired"
+	case NetworkWireless:
+		return "Wireless"
+	case NetworkMeshed:
+		return "Meshed"
+	default:
+		return fmt.Sprintf("Network(%d)", n)
+	}
+}
+
+// NetworkValues returns all known Network values.
+func NetworkValues() []Network {
+	v := make([]Network, networkCount)
+	for i := range v {
+		v[i] = Network(i)
+	}
+	return v
+}
+
+// ParseNetwork parses the name or number of a Network.
+func ParseNetwork(s string) (Network, error) {
+	v, err := parseEnum("Network", s, networkCount, func(i int) string { return Network(i).String() }, nil)
+	return Network(v), err
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (n Network) MarshalText() ([]byte, error) {
+	return []byte(n.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (n *Network) UnmarshalText(b []byte) error {
+	v, err := ParseNetwork(string(b))
+	if err != nil {
+		return err
+	}
+	*n = v
+	return nil
+}
+
+// MarshalJSON implements json.Marshaler, the numeric value is used.
+func (n Network) MarshalJSON() ([]byte, error) {
+	return strconv.AppendInt(nil, int64(n), 10), nil
+}
+
+// UnmarshalJSON implements json.Unmarshaler, both numbers and names
+// are accepted.
+func (n *Network) UnmarshalJSON(b []byte) error {
+	v, err := unmarshalEnumJSON(b, func(s string) (int, error) {
+		v, err := ParseNetwork(s)
+		return int(v), err
+	})
+	if err != nil {
+		return err
+	}
+	*n = Network(v)
+	return nil
+}
+
+// String returns the name of the Role, e.g. "Individual", or Role(N)
+// for unknown values.
+func (r Role) String() string {
+	switch r {
+	case RoleIndividual:
+		return "Individual"
+	case RoleMaster:
+		return "Master"
+	case RoleSlave:
+		return "Slave"
+	case RoleSurroundMaster:
+		return "Surround Master"
+	case RoleSurroundSlave:
+		return "Surround Slave"
+	default:
+		return fmt.Sprintf("Role(%d)", r)
+	}
+}
+
+// RoleValues returns all known Role values.
+func RoleValues() []Role {
+	v := make([]Role, roleCount)
+	for i := range v {
+		v[i] = Role(i)
+	}
+	return v
+}
+
+// ParseRole parses the name or number of a Role.
+func ParseRole(s string) (Role, error) {
+	v, err := parseEnum("Role", s, roleCount, func(i int) string { return Role(i).String() }, nil)
+	return Role(v), err
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (r Role) MarshalText() ([]byte, error) {
+	return []byte(r.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (r *Role) UnmarshalText(b []byte) error {
+	v, err := ParseRole(string(b))
+	if err != nil {
+		return err
+	}
+	*r = v
+	return nil
+}
+
+// MarshalJSON implements json.Marshaler, the numeric value is used.
+func (r Role) MarshalJSON() ([]byte, error) {
+	return strconv.AppendInt(nil, int64(r), 10), nil
+}
+
+// UnmarshalJSON implements json.Unmarshaler, both numbers and names
+// are accepted.
+func (r *Role) UnmarshalJSON(b []byte) error {
+	v, err := unmarshalEnumJSON(b, func(s string) (int, error) {
+		v, err := ParseRole(s)
+		return int(v), err
+	})
+	if err != nil {
+		return err
+	}
+	*r = Role(v)
+	return nil
+}
+
+// String returns the name of the Day, e.g. "Monday", or Day(N) for
+// unknown values.
+func (d Day) String() string {
+	switch d {
+	case Monday:
+		return "Monday"
+	case Tuesday:
+		return "Tuesday"
+	case Wednesday:
+		return "Wednesday"
+	case Thursday:
+		return "Thursday"
+	case Friday:
+		return "Friday"
+	case Saturday:
+		return "Saturday"
+	case Sunday:
+		return "Sunday"
+	default:
+		return fmt.Sprintf("Day(%d)", d)
+	}
+}
+
+// DayValues returns all known Day values.
+func DayValues() []Day {
+	v := make([]Day, dayCount)
+	for i := range v {
+		v[i] = Day(i)
+	}
+	return v
+}
+
+// ParseDay parses the name or number of a Day.
+func ParseDay(s string) (Day, error) {
+	v, err := parseEnum("Day", s, dayCount, func(i int) string { return Day(i).String() }, nil)
+	return Day(v), err
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (d Day) MarshalText() ([]byte, error) {
+	return []byte(d.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (d *Day) UnmarshalText(b []byte) error {
+	v, err := ParseDay(string(b))
+	if err != nil {
+		return err
+	}
+	*d = v
+	return nil
+}
+
+// MarshalJSON implements json.Marshaler, the numeric value is used.
+func (d Day) MarshalJSON() ([]byte, error) {
+	return strconv.AppendInt(nil, int64(d), 10), nil
+}
+
+// UnmarshalJSON implements json.Unmarshaler, both numbers and names
+// are accepted.
+func (d *Day) UnmarshalJSON(b []byte) error {
+	v, err := unmarshalEnumJSON(b, func(s string) (int, error) {
+		v, err := ParseDay(s)
+		return int(v), err
+	})
+	if err != nil {
+		return err
+	}
+	*d = Day(v)
+	return nil
+}
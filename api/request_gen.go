@@ -0,0 +1,921 @@
+// Code generated by protogen from api/protocol.json. DO NOT EDIT.
+
+package api
+
+import "encoding/json"
+
+// ContentProvider represents a music service supported by the
+// speaker.
+type ContentProvider struct {
+	Name      string `json:"cpName"`
+	AppID     string `json:"appId,omitempty"`
+	PackageID string `json:"pkgId,omitempty"`
+	C4A       bool   `json:"C4A"` // Cast for audio.
+}
+
+type (
+	AlarmSetRequest struct {
+		Alarm
+	}
+	AlarmSetReply struct {
+		ID int `json:"id"`
+	}
+)
+
+func (AlarmSetRequest) Message() string       { return MessageAlarmSet }
+func (AlarmSetRequest) Reply() *AlarmSetReply { return &AlarmSetReply{ID: -1} } // Starts at zero.
+
+// SetAlarmPlaylistRequest sets the songs for an alarm.
+//
+// Experimental: not observed, the payload is inferred from the alarm
+// fields and may change.
+type SetAlarmPlaylistRequest struct {
+	ID       int         `json:"id"` // Alarm ID.
+	Shuffle  bool        `json:"shuffle"`
+	Playlist []AlarmSong `json:"playlist"`
+}
+
+func (SetAlarmPlaylistRequest) Message() string { return MessageSetAlarmPlaylist }
+
+type (
+	AlarmListRequest struct {
+		emptyMessage
+	}
+	AlarmListReply struct {
+		Info []Alarm `json:"info"`
+	}
+)
+
+func (AlarmListRequest) Message() string        { return MessageAlarmListRequest }
+func (AlarmListRequest) Reply() *AlarmListReply { return &AlarmListReply{} }
+
+type (
+	AlarmStateRequest struct {
+		emptyMessage
+	}
+	AlarmStateReply struct {
+		On bool `json:"on"`
+	}
+)
+
+func (AlarmStateRequest) Message() string         { return MessageAlarmStateRequest }
+func (AlarmStateRequest) Reply() *AlarmStateReply { return &AlarmStateReply{} }
+
+// AlarmStateEvent is sent in response to ALARM_STATE_REQ.
+type AlarmStateEvent struct {
+	On bool `json:"on"`
+}
+
+func (AlarmStateEvent) Message() string { return MessageAlarmStateNotification }
+
+type SleepSetRequest struct {
+	Time int `json:"time"` // Minutes, -1 disables.
+}
+
+func (SleepSetRequest) Message() string { return MessageSleepSet }
+
+type (
+	SleepInfoRequest struct {
+		emptyMessage
+	}
+	SleepInfoReply struct {
+		Time int `json:"time"` // Remaining minutes, -1 when disabled.
+	}
+)
+
+func (SleepInfoRequest) Message() string        { return MessageSleepInfoRequest }
+func (SleepInfoRequest) Reply() *SleepInfoReply { return &SleepInfoReply{} }
+
+type SpeakerInfoModifyRequest struct {
+	Icon int    `json:"icon"`
+	Name string `json:"name"`
+}
+
+func (SpeakerInfoModifyRequest) Message() string { return MessageSpeakerInfoModify }
+
+type SpeakerNameChangeEvent struct {
+	Icon int    `json:"icon"`
+	Name string `json:"name"`
+}
+
+func (SpeakerNameChangeEvent) Message() string { return MessageSpeakerNameChange }
+
+type ProductInfoRequest struct {
+	ID     string `json:"id"`  // Device ID.
+	Day    Day    `json:"day"` // Day of week.
+	Hour   int    `json:"hour"`
+	Min    int    `json:"min"`
+	Option int    `json:"option"` // Either 0 or 1, what does it do? Update time on speaker?
+}
+
+func (ProductInfoRequest) Message() string     { return MessageProductInfo }
+func (ProductInfoRequest) Reply() *ProductInfo { return &ProductInfo{} }
+
+type (
+	NightModeSetRequest struct {
+		NightMode bool `json:"nightmode"`
+	}
+	NightModeSetReply struct {
+		NightMode bool `json:"nightmode"`
+	}
+)
+
+func (NightModeSetRequest) Message() string           { return MessageNightModeSet }
+func (NightModeSetRequest) Reply() *NightModeSetReply { return &NightModeSetReply{} }
+
+type (
+	VolumeSettingRequest struct {
+		FadeTime int `json:"fadetime"`
+		Volume   int `json:"vol"`
+	}
+	// Deprecated: VOLUME_SETTING replies have no payload, the new volume
+	// is broadcasted as VolumeChangeEvent.
+	VolumeSettingReply struct {
+		Volume int `json:"vol"`
+	}
+)
+
+func (VolumeSettingRequest) Message() string { return MessageVolumeSetting }
+
+// VolumeChangeEvent is broadcasted when the volume changes, before
+// the reply to the request that changed it.
+type VolumeChangeEvent struct {
+	Volume int `json:"vol"`
+}
+
+func (VolumeChangeEvent) Message() string { return MessageVolumeChange }
+
+// VolumeUpRequest raises the volume by one. Not observed, assumed to
+// have no payload.
+type VolumeUpRequest struct {
+	emptyMessage
+}
+
+func (VolumeUpRequest) Message() string { return MessageVolumeUp }
+
+// VolumeDownRequest lowers the volume by one. Not observed, assumed
+// to have no payload.
+type VolumeDownRequest struct {
+	emptyMessage
+}
+
+func (VolumeDownRequest) Message() string { return MessageVolumeDown }
+
+type MuteSetRequest struct {
+	Mute bool `json:"mute"`
+}
+
+func (MuteSetRequest) Message() string { return MessageMuteSet }
+
+// MuteChangeEvent is broadcasted when mute changes, before the reply
+// to the request that changed it.
+type MuteChangeEvent struct {
+	Mute bool `json:"mute"`
+}
+
+func (MuteChangeEvent) Message() string { return MessageMuteChange }
+
+type SystemVersionRequest struct {
+	emptyMessage
+}
+
+func (SystemVersionRequest) Message() string       { return MessageSystemVersionRequest }
+func (SystemVersionRequest) Reply() *SystemVersion { return &SystemVersion{} }
+
+type SettingInfoRequest struct {
+	emptyMessage
+}
+
+func (SettingInfoRequest) Message() string  { return MessageSettingInfoRequest }
+func (SettingInfoRequest) Reply() *Settings { return &Settings{} }
+
+type TestToneRequest struct {
+	Stat bool `json:"stat"`
+}
+
+func (TestToneRequest) Message() string { return MessageTestTone }
+
+type InitializationSetRequest struct {
+	Initialization
+}
+
+func (InitializationSetRequest) Message() string { return MessageInitializationSet }
+
+type NetworkInfoRequest struct {
+	emptyMessage
+}
+
+func (NetworkInfoRequest) Message() string     { return MessageNetworkInfoRequest }
+func (NetworkInfoRequest) Reply() *NetworkInfo { return &NetworkInfo{} }
+
+type PlayInfoRequest struct {
+	emptyMessage
+}
+
+func (PlayInfoRequest) Message() string  { return MessagePlayInfoRequest }
+func (PlayInfoRequest) Reply() *PlayInfo { return &PlayInfo{} }
+
+type (
+	WooferLevelSetRequest struct {
+		Level int `json:"wooferlevel"`
+	}
+	WooferLevelSetReply struct {
+		Level int `json:"wooferlevel"`
+	}
+)
+
+func (WooferLevelSetRequest) Message() string             { return MessageWooferLevelSet }
+func (WooferLevelSetRequest) Reply() *WooferLevelSetReply { return &WooferLevelSetReply{} }
+
+type (
+	// RearBoxLevelSetRequest sets the rear box level. Not observed (not
+	// available on SJ 6), assumed to mirror WOOFER_LEVEL_SET.
+	RearBoxLevelSetRequest struct {
+		Level int `json:"rearboxlevel"`
+	}
+	RearBoxLevelSetReply struct {
+		Level int `json:"rearboxlevel"`
+	}
+)
+
+func (RearBoxLevelSetRequest) Message() string              { return MessageRearboxLevelSet }
+func (RearBoxLevelSetRequest) Reply() *RearBoxLevelSetReply { return &RearBoxLevelSetReply{} }
+
+type EqualizerInfoRequest struct {
+	emptyMessage
+}
+
+func (EqualizerInfoRequest) Message() string       { return MessageEqualizerInfoRequest }
+func (EqualizerInfoRequest) Reply() *EqualizerInfo { return &EqualizerInfo{} }
+
+type EqualizerSetRequest struct {
+	Type  EqualizerType `json:"type"`
+	Value int           `json:"value"` // E.g. for SetEqualizer, use int(Equalizer).
+}
+
+func (EqualizerSetRequest) Message() string { return MessageEqualizerSetting }
+
+type FunctionSetRequest struct {
+	Type Function `json:"type"`
+}
+
+func (FunctionSetRequest) Message() string { return MessageFunctionSet }
+
+type FunctionInfoRequest struct {
+	emptyMessage
+}
+
+func (FunctionInfoRequest) Message() string      { return MessageFunctionInfoRequest }
+func (FunctionInfoRequest) Reply() *FunctionInfo { return &FunctionInfo{} }
+
+// FunctionInfoEvent is broadcasted when the function or its
+// connection state changes.
+type FunctionInfoEvent struct {
+	FunctionInfo
+}
+
+func (FunctionInfoEvent) Message() string { return MessageFunctionInfo }
+
+type NewVersionSearchRequest struct {
+	emptyMessage
+}
+
+func (NewVersionSearchRequest) Message() string { return MessageNewVersionSearch }
+
+type (
+	UpdateStartRequest struct {
+		Mandatory bool `json:"mandatory"`
+		Update    bool `json:"upt"`
+	}
+	UpdateStartReply struct {
+		Update bool `json:"upt"`
+	}
+)
+
+func (UpdateStartRequest) Message() string          { return MessageUpdateStart }
+func (UpdateStartRequest) Reply() *UpdateStartReply { return &UpdateStartReply{} }
+
+type UpdateProgressEvent struct {
+	Progress int `json:"prog"` // Percentage, 1-100.
+}
+
+func (UpdateProgressEvent) Message() string { return MessageUpdateProgress }
+
+type UpdateDownResultEvent struct {
+	Result bool `json:"result"` // True when the download succeeded.
+}
+
+func (UpdateDownResultEvent) Message() string { return MessageUpdateDownResult }
+
+// UpdateStartWriteEvent has not been observed, payload unknown.
+type UpdateStartWriteEvent struct{}
+
+func (UpdateStartWriteEvent) Message() string { return MessageUpdateStartWrite }
+
+// UpdateStartRebootEvent has not been observed, payload unknown.
+type UpdateStartRebootEvent struct{}
+
+func (UpdateStartRebootEvent) Message() string { return MessageUpdateStartReboot }
+
+type UpdateCompleteEvent struct {
+	Complete bool `json:"complete"`
+}
+
+func (UpdateCompleteEvent) Message() string { return MessageUpdateComplete }
+
+// UpdateResultEvent has not been observed, payload assumed to be the
+// same as for UPDATE_DOWN_RESULT.
+type UpdateResultEvent struct {
+	Result bool `json:"result"`
+}
+
+func (UpdateResultEvent) Message() string { return MessageUpdateResult }
+
+type TimezoneSetRequest struct {
+	Timezone string `json:"timezone"` // IANA name, e.g. "Europe/Helsinki".
+}
+
+func (TimezoneSetRequest) Message() string { return MessageTimezoneSet }
+
+type ShareHomeSSIDRequest struct {
+	Channel int    `json:"channel"`
+	SSID    string `json:"ssid"`
+}
+
+func (ShareHomeSSIDRequest) Message() string { return MessageShareHomeSSID }
+
+type SpeakerAddSetRequest struct {
+	Icon int    `json:"icon"`
+	Name string `json:"name"`
+}
+
+func (SpeakerAddSetRequest) Message() string { return MessageSpeakerAddSet }
+
+// SpeakerAddEvent is sent (without payload) after SPK_ADD_SET.
+type SpeakerAddEvent struct{}
+
+func (SpeakerAddEvent) Message() string { return MessageSpeakerAddNotification }
+
+// ShareNetworkWirelessRequest shares the wireless network settings.
+// Not observed, payload assumed to be the same as NETWORK_INFO_REQ.
+type ShareNetworkWirelessRequest struct {
+	NetworkInfo
+}
+
+func (ShareNetworkWirelessRequest) Message() string { return MessageShareNWWireless }
+
+// ShareNetworkWiredRequest shares the wired network settings. Not
+// observed, payload assumed to be the same as NETWORK_INFO_REQ.
+type ShareNetworkWiredRequest struct {
+	NetworkInfo
+}
+
+func (ShareNetworkWiredRequest) Message() string { return MessageShareNWWired }
+
+type (
+	C4ATOSGetRequest struct {
+		emptyMessage
+	}
+	C4ATOSGetReply struct {
+		Agree bool `json:"agree"`
+	}
+)
+
+func (C4ATOSGetRequest) Message() string        { return MessageC4ATOSGet }
+func (C4ATOSGetRequest) Reply() *C4ATOSGetReply { return &C4ATOSGetReply{} }
+
+// FactorySetRequest resets the speaker to factory settings. Not
+// observed, assumed to have no payload.
+type FactorySetRequest struct {
+	emptyMessage
+}
+
+func (FactorySetRequest) Message() string { return MessageFactorySet }
+
+// PowerOffRequest powers off the speaker. Not observed, assumed to
+// have no payload.
+type PowerOffRequest struct {
+	emptyMessage
+}
+
+func (PowerOffRequest) Message() string { return MessagePowerOff }
+
+type (
+	// DRCSetRequest sets dynamic range compression on or off.
+	DRCSetRequest struct {
+		DRC bool `json:"drc"`
+	}
+	DRCSetReply struct {
+		DRC bool `json:"drc"`
+	}
+)
+
+func (DRCSetRequest) Message() string     { return MessageDRCSet }
+func (DRCSetRequest) Reply() *DRCSetReply { return &DRCSetReply{} }
+
+type (
+	AutoVolumeSetRequest struct {
+		AutoVolume bool `json:"autovol"`
+	}
+	AutoVolumeSetReply struct {
+		AutoVolume bool `json:"autovol"`
+	}
+)
+
+func (AutoVolumeSetRequest) Message() string            { return MessageAutoVolumeSet }
+func (AutoVolumeSetRequest) Reply() *AutoVolumeSetReply { return &AutoVolumeSetReply{} }
+
+type (
+	AutoPowerSetRequest struct {
+		AutoPower bool `json:"autopower"`
+	}
+	AutoPowerSetReply struct {
+		AutoPower bool `json:"autopower"`
+	}
+)
+
+func (AutoPowerSetRequest) Message() string           { return MessageAutoPowerSet }
+func (AutoPowerSetRequest) Reply() *AutoPowerSetReply { return &AutoPowerSetReply{} }
+
+type (
+	AVSyncSetRequest struct {
+		AVSync int `json:"avsync"` // Audio delay, unit unknown (observed 0-30).
+	}
+	AVSyncSetReply struct {
+		AVSync int `json:"avsync"`
+	}
+)
+
+func (AVSyncSetRequest) Message() string        { return MessageAVSyncSet }
+func (AVSyncSetRequest) Reply() *AVSyncSetReply { return &AVSyncSetReply{} }
+
+type (
+	LedSetRequest struct {
+		On bool `json:"stat"`
+	}
+	LedSetReply struct {
+		On bool `json:"stat"`
+	}
+)
+
+func (LedSetRequest) Message() string     { return MessageLedSet }
+func (LedSetRequest) Reply() *LedSetReply { return &LedSetReply{} }
+
+type BluetoothStandbySetRequest struct {
+	On bool `json:"on"`
+}
+
+func (BluetoothStandbySetRequest) Message() string { return MessageBluetoothStandbySet }
+
+type BluetoothStandbyStateEvent struct {
+	On bool `json:"on"`
+}
+
+func (BluetoothStandbyStateEvent) Message() string { return MessageBluetoothStandbyStateNotification }
+
+type BluetoothLimitSetRequest struct {
+	Limit bool `json:"limit_bt_conn"`
+}
+
+func (BluetoothLimitSetRequest) Message() string { return MessageBluetoothLimitSet }
+
+type BluetoothLimitSetEvent struct {
+	Limit bool `json:"limit_bt_conn"`
+}
+
+func (BluetoothLimitSetEvent) Message() string { return MessageBluetoothLimitSetNotification }
+
+type GroupCompressSetRequest struct {
+	Status int `json:"status"`
+}
+
+func (GroupCompressSetRequest) Message() string { return MessageGroupCompressSet }
+
+type GroupCompressStateEvent struct {
+	Status int `json:"status"`
+}
+
+func (GroupCompressStateEvent) Message() string { return MessageGroupCompressStateNotification }
+
+type (
+	UsageShareGetRequest struct {
+		emptyMessage
+	}
+	UsageShareGetReply struct {
+		Sharing bool `json:"sharing"`
+	}
+)
+
+func (UsageShareGetRequest) Message() string            { return MessageUsageShareGet }
+func (UsageShareGetRequest) Reply() *UsageShareGetReply { return &UsageShareGetReply{} }
+
+type UsageShareSetRequest struct {
+	Sharing bool `json:"sharing"`
+}
+
+func (UsageShareSetRequest) Message() string { return MessageUsageShareSet }
+
+type UsageShareSetEvent struct {
+	Sharing bool `json:"sharing"`
+}
+
+func (UsageShareSetEvent) Message() string { return MessageUsageShareSetNotification }
+
+// PlayTimeSetRequest is sent by the app when opening the player,
+// significance unknown.
+type PlayTimeSetRequest struct {
+	Set bool `json:"set"`
+}
+
+func (PlayTimeSetRequest) Message() string { return MessagePlayTimeSet }
+
+type (
+	PlaylistTransRequest struct {
+		All        bool `json:"all"`
+		StartIndex int  `json:"startidx"`
+	}
+	PlaylistTransReply struct {
+		Playlist    []json.RawMessage `json:"playlist"` // Only empty playlists observed.
+		TotalSize   int               `json:"totalsize"`
+		StartIndex  int               `json:"startidx"`
+		CurrentSize int               `json:"cursize"`
+	}
+)
+
+func (PlaylistTransRequest) Message() string            { return MessagePlaylistTransRequest }
+func (PlaylistTransRequest) Reply() *PlaylistTransReply { return &PlaylistTransReply{} }
+
+type (
+	ContentProviderListRequest struct {
+		emptyMessage
+	}
+	ContentProviderListReply struct {
+		ContentProviders []ContentProvider `json:"c4acplist"`
+	}
+)
+
+func (ContentProviderListRequest) Message() string { return MessageSDPCPListRequest }
+func (ContentProviderListRequest) Reply() *ContentProviderListReply {
+	return &ContentProviderListReply{}
+}
+
+// AutoDisplaySetRequest is not observed (not available on SJ 6), the
+// payload is inferred from the settings.
+type AutoDisplaySetRequest struct {
+	AutoDisplay bool `json:"autodisplay"`
+}
+
+func (AutoDisplaySetRequest) Message() string { return MessageAutoDisplaySet }
+
+// SoundEffectSetRequest is not observed (not available on SJ 6), the
+// payload is inferred from the settings.
+type SoundEffectSetRequest struct {
+	SoundEffect bool `json:"soundeffect"`
+}
+
+func (SoundEffectSetRequest) Message() string { return MessageSoundEffectSet }
+
+// StartupSoundSetRequest is not observed (not available on SJ 6), the
+// payload is inferred from the settings.
+type StartupSoundSetRequest struct {
+	On bool `json:"startsoundon"`
+}
+
+func (StartupSoundSetRequest) Message() string { return MessageStartupSoundSet }
+
+// TVRemoteSetRequest is not observed (not available on SJ 6), the
+// payload is inferred from the settings.
+type TVRemoteSetRequest struct {
+	TVRemote bool `json:"tvremote"`
+}
+
+func (TVRemoteSetRequest) Message() string { return MessageTVRemoteSet }
+
+// NewEvent returns a new event for the message, or nil when the
+// message is not a known event.
+func NewEvent(message string) interface{ Message() string } {
+	switch message {
+	case MessageAlarmStateNotification:
+		return &AlarmStateEvent{}
+	case MessageSpeakerNameChange:
+		return &SpeakerNameChangeEvent{}
+	case MessageVolumeChange:
+		return &VolumeChangeEvent{}
+	case MessageMuteChange:
+		return &MuteChangeEvent{}
+	case MessageFunctionInfo:
+		return &FunctionInfoEvent{}
+	case MessageUpdateProgress:
+		return &UpdateProgressEvent{}
+	case MessageUpdateDownResult:
+		return &UpdateDownResultEvent{}
+	case MessageUpdateStartWrite:
+		return &UpdateStartWriteEvent{}
+	case MessageUpdateStartReboot:
+		return &UpdateStartRebootEvent{}
+	case MessageUpdateComplete:
+		return &UpdateCompleteEvent{}
+	case MessageUpdateResult:
+		return &UpdateResultEvent{}
+	case MessageSpeakerAddNotification:
+		return &SpeakerAddEvent{}
+	case MessageBluetoothStandbyStateNotification:
+		return &BluetoothStandbyStateEvent{}
+	case MessageBluetoothLimitSetNotification:
+		return &BluetoothLimitSetEvent{}
+	case MessageGroupCompressStateNotification:
+		return &GroupCompressStateEvent{}
+	case MessageUsageShareSetNotification:
+		return &UsageShareSetEvent{}
+	}
+	return nil
+}
+
+// NewRequest returns a new request for the message, or nil when the
+// message is not a known request.
+func NewRequest(message string) interface{ Message() string } {
+	switch message {
+	case MessageAlarmSet:
+		return &AlarmSetRequest{}
+	case MessageSetAlarmPlaylist:
+		return &SetAlarmPlaylistRequest{}
+	case MessageAlarmListRequest:
+		return &AlarmListRequest{}
+	case MessageAlarmStateRequest:
+		return &AlarmStateRequest{}
+	case MessageSleepSet:
+		return &SleepSetRequest{}
+	case MessageSleepInfoRequest:
+		return &SleepInfoRequest{}
+	case MessageSpeakerInfoModify:
+		return &SpeakerInfoModifyRequest{}
+	case MessageProductInfo:
+		return &ProductInfoRequest{}
+	case MessageNightModeSet:
+		return &NightModeSetRequest{}
+	case MessageVolumeSetting:
+		return &VolumeSettingRequest{}
+	case MessageVolumeUp:
+		return &VolumeUpRequest{}
+	case MessageVolumeDown:
+		return &VolumeDownRequest{}
+	case MessageMuteSet:
+		return &MuteSetRequest{}
+	case MessageSystemVersionRequest:
+		return &SystemVersionRequest{}
+	case MessageSettingInfoRequest:
+		return &SettingInfoRequest{}
+	case MessageTestTone:
+		return &TestToneRequest{}
+	case MessageInitializationSet:
+		return &InitializationSetRequest{}
+	case MessageNetworkInfoRequest:
+		return &NetworkInfoRequest{}
+	case MessagePlayInfoRequest:
+		return &PlayInfoRequest{}
+	case MessageWooferLevelSet:
+		return &WooferLevelSetRequest{}
+	case MessageRearboxLevelSet:
+		return &RearBoxLevelSetRequest{}
+	case MessageEqualizerInfoRequest:
+		return &EqualizerInfoRequest{}
+	case MessageEqualizerSetting:
+		return &EqualizerSetRequest{}
+	case MessageFunctionSet:
+		return &FunctionSetRequest{}
+	case MessageFunctionInfoRequest:
+		return &FunctionInfoRequest{}
+	case MessageNewVersionSearch:
+		return &NewVersionSearchRequest{}
+	case MessageUpdateStart:
+		return &UpdateStartRequest{}
+	case MessageTimezoneSet:
+		return &TimezoneSetRequest{}
+	case MessageShareHomeSSID:
+		return &ShareHomeSSIDRequest{}
+	case MessageSpeakerAddSet:
+		return &SpeakerAddSetRequest{}
+	case MessageShareNWWireless:
+		return &ShareNetworkWirelessRequest{}
+	case MessageShareNWWired:
+		return &ShareNetworkWiredRequest{}
+	case MessageC4ATOSGet:
+		return &C4ATOSGetRequest{}
+	case MessageFactorySet:
+		return &FactorySetRequest{}
+	case MessagePowerOff:
+		return &PowerOffRequest{}
+	case MessageDRCSet:
+		return &DRCSetRequest{}
+	case MessageAutoVolumeSet:
+		return &AutoVolumeSetRequest{}
+	case MessageAutoPowerSet:
+		return &AutoPowerSetRequest{}
+	case MessageAVSyncSet:
+		return &AVSyncSetRequest{}
+	case MessageLedSet:
+		return &LedSetRequest{}
+	case MessageBluetoothStandbySet:
+		return &BluetoothStandbySetRequest{}
+	case MessageBluetoothLimitSet:
+		return &BluetoothLimitSetRequest{}
+	case MessageGroupCompressSet:
+		return &GroupCompressSetRequest{}
+	case MessageUsageShareGet:
+		return &UsageShareGetRequest{}
+	case MessageUsageShareSet:
+		return &UsageShareSetRequest{}
+	case MessagePlayTimeSet:
+		return &PlayTimeSetRequest{}
+	case MessagePlaylistTransRequest:
+		return &PlaylistTransRequest{}
+	case MessageSDPCPListRequest:
+		return &ContentProviderListRequest{}
+	case MessageAutoDisplaySet:
+		return &AutoDisplaySetRequest{}
+	case MessageSoundEffectSet:
+		return &SoundEffectSetRequest{}
+	case MessageStartupSoundSet:
+		return &StartupSoundSetRequest{}
+	case MessageTVRemoteSet:
+		return &TVRemoteSetRequest{}
+	}
+	return nil
+}
+
+// NewReply returns a new reply for the request message, or nil when
+// the message is not a known request with a reply.
+func NewReply(message string) interface{} {
+	switch message {
+	case MessageAlarmSet:
+		return AlarmSetRequest{}.Reply()
+	case MessageAlarmListRequest:
+		return AlarmListRequest{}.Reply()
+	case MessageAlarmStateRequest:
+		return AlarmStateRequest{}.Reply()
+	case MessageSleepInfoRequest:
+		return SleepInfoRequest{}.Reply()
+	case MessageProductInfo:
+		return ProductInfoRequest{}.Reply()
+	case MessageNightModeSet:
+		return NightModeSetRequest{}.Reply()
+	case MessageSystemVersionRequest:
+		return SystemVersionRequest{}.Reply()
+	case MessageSettingInfoRequest:
+		return SettingInfoRequest{}.Reply()
+	case MessageNetworkInfoRequest:
+		return NetworkInfoRequest{}.Reply()
+	case MessagePlayInfoRequest:
+		return PlayInfoRequest{}.Reply()
+	case MessageWooferLevelSet:
+		return WooferLevelSetRequest{}.Reply()
+	case MessageRearboxLevelSet:
+		return RearBoxLevelSetRequest{}.Reply()
+	case MessageEqualizerInfoRequest:
+		return EqualizerInfoRequest{}.Reply()
+	case MessageFunctionInfoRequest:
+		return FunctionInfoRequest{}.Reply()
+	case MessageUpdateStart:
+		return UpdateStartRequest{}.Reply()
+	case MessageC4ATOSGet:
+		return C4ATOSGetRequest{}.Reply()
+	case MessageDRCSet:
+		return DRCSetRequest{}.Reply()
+	case MessageAutoVolumeSet:
+		return AutoVolumeSetRequest{}.Reply()
+	case MessageAutoPowerSet:
+		return AutoPowerSetRequest{}.Reply()
+	case MessageAVSyncSet:
+		return AVSyncSetRequest{}.Reply()
+	case MessageLedSet:
+		return LedSetRequest{}.Reply()
+	case MessageUsageShareGet:
+		return UsageShareGetRequest{}.Reply()
+	case MessagePlaylistTransRequest:
+		return PlaylistTransRequest{}.Reply()
+	case MessageSDPCPListRequest:
+		return ContentProviderListRequest{}.Reply()
+	}
+	return nil
+}
+
+// Messages returns all known messages (the Message constants),
+// sorted.
+func Messages() []string {
+	return []string{
+		MessageAddClient,
+		MessageAddPlaylist,
+		MessageAddVMSPlaylist,
+		MessageAlarmBegin,
+		MessageAlarmListRequest,
+		MessageAlarmSet,
+		MessageAlarmStateNotification,
+		MessageAlarmStateRequest,
+		MessageAutoDisplaySet,
+		MessageAutoPowerSet,
+		MessageAutoVolumeSet,
+		MessageAVSyncSet,
+		MessageBluetoothConnection,
+		MessageBluetoothDisconnection,
+		MessageBluetoothInfoRequest,
+		MessageBluetoothPairingResult,
+		MessageBluetoothLimitSet,
+		MessageBluetoothLimitSetNotification,
+		MessageBluetoothPartymodeSet,
+		MessageBluetoothStandbySet,
+		MessageBluetoothStandbyStateNotification,
+		MessageC4AGroupCancelNotification,
+		MessageC4ATOSGet,
+		MessageChangePlaylistIndex,
+		MessageChannelInfoRequest,
+		MessageChannelSet,
+		MessageChannelChangeStatus,
+		MessageCPAddPlaylist,
+		MessageCPInfoRequest,
+		MessageCPPlaylistRequest,
+		MessageCPPlayURL,
+		MessageDeletePlaylist,
+		MessageDRCSet,
+		MessageEqualizerInfoRequest,
+		MessageEqualizerChangeNotification,
+		MessageEqualizerSetting,
+		MessageFactorySet,
+		MessageFunctionSet,
+		MessageFunctionInfo,
+		MessageFunctionInfoRequest,
+		MessageGroupCompressSet,
+		MessageGroupCompressStateNotification,
+		MessageGroupDestroy,
+		MessageGroupSet,
+		MessageIHRLogon,
+		MessageInitializationSet,
+		MessageLedSet,
+		MessageLocalPlayURL,
+		MessageLocalTimeSearch,
+		MessageParsingError,
+		MessageMusicIndexUpdate,
+		MessageMusicIndexUpdateInfoRequest,
+		MessageMuteChange,
+		MessageMuteSet,
+		MessageNetworkInfoRequest,
+		MessageNetworkStatusNotification,
+		MessageNewVersionSearch,
+		MessageNightModeSet,
+		MessageOnSurroundSet,
+		MessagePlaylistChange,
+		MessagePlaylistTransRequest,
+		MessagePlayCmd,
+		MessagePlayInfo,
+		MessagePlayInfoRequest,
+		MessagePlayTime,
+		MessagePlayTimeSet,
+		MessagePowerOff,
+		MessageProductInfo,
+		MessageProductInfoUpdate,
+		MessageRearboxLevelSet,
+		MessageReturnLGGroupRequest,
+		MessageRhapsodyEvent,
+		MessageRhapsodyLogon,
+		MessageSDPCPListRequest,
+		MessageSettingInfoNotification,
+		MessageSettingInfoRequest,
+		MessageSetAlarmPlaylist,
+		MessageShareHomeInfo,
+		MessageShareHomeSSID,
+		MessageShareNWWired,
+		MessageShareNWWireless,
+		MessageSleepInfoRequest,
+		MessageSleepSet,
+		MessageSoundEffectSet,
+		MessageSpeakerAddNotification,
+		MessageSpeakerAddSet,
+		MessageSpeakerAlive,
+		MessageSpeakerChannelNotification,
+		MessageSpeakerChannelSet,
+		MessageSpeakerInfoModify,
+		MessageSpeakerNameChange,
+		MessageStartupSoundSet,
+		MessageSurroundDestroy,
+		MessageSurroundSet,
+		MessageSystemVersionRequest,
+		MessageTestTone,
+		MessageTimezoneSet,
+		MessageTVRemoteSet,
+		MessageUpdateComplete,
+		MessageUpdateDownResult,
+		MessageUpdateProgress,
+		MessageUpdateResult,
+		MessageUpdateStart,
+		MessageUpdateStartReboot,
+		MessageUpdateStartWrite,
+		MessageUsageShareGet,
+		MessageUsageShareSet,
+		MessageUsageShareSetNotification,
+		MessageVMSScanResult,
+		MessageVolumeChange,
+		MessageVolumeDown,
+		MessageVolumeSetting,
+		MessageVolumeUp,
+		MessageWooferLevelSet,
+	}
+}
@@ -0,0 +1,154 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// The enums are encoded as numbers in JSON (the protocol) and as names
+// in text, e.g. in configuration files and flags. Unknown values are
+// encoded using the String representation, e.g. "Function(42)", and
+// can be parsed back. The methods of each enum are generated from
+// api/protocol.json (enum_gen.go), the shared parts are here.
+
+// normalizeName lowercases s and removes spaces and punctuation so
+// that e.g. "Optical / HDMI ARC" and "optical-hdmi-arc" are equal.
+func normalizeName(s string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(s) {
+		if ('a' <= r && r <= 'z') || ('0' <= r && r <= '9') {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// parseEnum parses s as one of the n known values (0 to n-1) of typ.
+// The name, an alias, an unambiguous prefix of the name or the numeric
+// value (e.g. "42" or "Function(42)") is accepted.
+func parseEnum(typ, s string, n int, name func(int) string, aliases map[string]int) (int, error) {
+	s = strings.TrimSpace(s)
+	if v, err := strconv.Atoi(s); err == nil {
+		return v, nil
+	}
+	if strings.HasPrefix(s, typ+"(") && strings.HasSuffix(s, ")") {
+		if v, err := strconv.Atoi(s[len(typ)+1 : len(s)-1]); err == nil {
+			return v, nil
+		}
+	}
+
+	key := normalizeName(s)
+	if key == "" {
+		return 0, fmt.Errorf("api: invalid %s: %q", typ, s)
+	}
+	if v, ok := aliases[key]; ok {
+		return v, nil
+	}
+	for i := 0; i < n; i++ {
+		if normalizeName(name(i)) == key {
+			return i, nil
+		}
+	}
+	match := -1
+	for i := 0; i < n; i++ {
+		if strings.HasPrefix(normalizeName(name(i)), key) {
+			if match >= 0 {
+				return 0, fmt.Errorf("api: ambiguous %s: %q", typ, s)
+			}
+			match = i
+		}
+	}
+	if match < 0 {
+		return 0, fmt.Errorf("api: unknown %s: %q", typ, s)
+	}
+	return match, nil
+}
+
+// unmarshalEnumJSON decodes a number or a name (see parseEnum).
+func unmarshalEnumJSON(b []byte, parse func(string) (int, error)) (int, error) {
+	if len(b) > 0 && b[0] == '"' {
+		var s string
+		if err := json.Unmarshal(b, &s); err != nil {
+			return 0, err
+		}
+		return parse(s)
+	}
+	var v int
+	if err := json.Unmarshal(b, &v); err != nil {
+		return 0, err
+	}
+	return v, nil
+}
+
+// ParseDaySet parses a comma separated list of days (see ParseDay),
+// "None" or the numeric bitmask. Bits that are not days are accepted
+// as DaySet(N) in the list (see DaySet.String).
+func ParseDaySet(s string) (DaySet, error) {
+	s = strings.TrimSpace(s)
+	if v, err := strconv.Atoi(s); err == nil {
+		return DaySet(v), nil
+	}
+	if s == "" || strings.EqualFold(s, "none") {
+		return 0, nil
+	}
+	var ds DaySet
+	for _, name := range strings.Split(s, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		if strings.HasPrefix(name, "DaySet(") && strings.HasSuffix(name, ")") {
+			v, err := strconv.Atoi(name[len("DaySet(") : len(name)-1])
+			if err != nil {
+				return 0, fmt.Errorf("api: invalid DaySet: %q", name)
+			}
+			ds |= DaySet(v)
+			continue
+		}
+		d, err := ParseDay(name)
+		if err != nil {
+			return 0, err
+		}
+		if d < Monday || d > Sunday {
+			return 0, fmt.Errorf("api: invalid Day: %q", name)
+		}
+		ds |= dayBit(d)
+	}
+	return ds, nil
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (s DaySet) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (s *DaySet) UnmarshalText(b []byte) error {
+	v, err := ParseDaySet(string(b))
+	if err != nil {
+		return err
+	}
+	*s = v
+	return nil
+}
+
+// MarshalJSON implements json.Marshaler, the bitmask is used.
+func (s DaySet) MarshalJSON() ([]byte, error) {
+	return strconv.AppendInt(nil, int64(s), 10), nil
+}
+
+// UnmarshalJSON implements json.Unmarshaler, both the bitmask and a
+// list of days are accepted.
+func (s *DaySet) UnmarshalJSON(b []byte) error {
+	v, err := unmarshalEnumJSON(b, func(str string) (int, error) {
+		v, err := ParseDaySet(str)
+		return int(v), err
+	})
+	if err != nil {
+		return err
+	}
+	*s = DaySet(v)
+	return nil
+}
@@ -0,0 +1,47 @@
+package api
+
+import (
+	"testing"
+	"testing/quick"
+)
+
+func TestEnumTextRoundTrip(t *testing.T) {
+	for i := -1; i <= functionCount; i++ {
+		b, _ := Function(i).MarshalText()
+		var got Function
+		if err := got.UnmarshalText(b); err != nil || got != Function(i) {
+			t.Errorf("Function(%d): UnmarshalText(%q) = %d, %v", i, b, got, err)
+		}
+	}
+	for _, tt := range []struct {
+		s    string
+		want Equalizer
+	}{
+		{"treble-bass", EqualizerTrebleBass},
+		{"bass blast", EqualizerBassBlast},
+		{"dolby", EqualizerDolbyAtmos},
+		{"Equalizer(42)", 42},
+	} {
+		if got, err := ParseEqualizer(tt.s); err != nil || got != tt.want {
+			t.Errorf("ParseEqualizer(%q) = %v, %v; want %v", tt.s, got, err, tt.want)
+		}
+	}
+}
+
+func TestDaySetTextRoundTrip(t *testing.T) {
+	f := func(v int32) bool {
+		s := DaySet(v)
+		got, err := ParseDaySet(s.String())
+		if err != nil || got != s {
+			t.Logf("ParseDaySet(%q) = %d, %v; want %d", s.String(), got, err, s)
+			return false
+		}
+		return true
+	}
+	if err := quick.Check(f, nil); err != nil {
+		t.Error(err)
+	}
+	if got, want := (DaySet(0x80) | dayBit(Monday)).String(), "Monday, DaySet(128)"; got != want {
+		t.Errorf("String() = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,150 @@
+package musicflow
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	errors "golang.org/x/xerrors"
+
+	"github.com/mafredri/musicflow/api"
+)
+
+// ErrUnsupported is returned when the speaker does not support the
+// requested feature, the request is not sent.
+var ErrUnsupported = errors.New("not supported by the speaker")
+
+// ErrProtocol is returned when the speaker rejects a request or sends
+// a reply that does not match the request.
+var ErrProtocol = errors.New("protocol error")
+
+// Capabilities describes the features supported by a speaker. Settings
+// that are missing from the settings reply (e.g. the rear box on SJ 6)
+// are considered unsupported.
+type Capabilities struct {
+	Model      api.Model
+	ModelName  string
+	Equalizers []api.Equalizer // Available equalizers (eqlist).
+	Functions  []api.Function  // Available functions (functionlist).
+
+	Alarm            bool // Alarms (visible_alarm).
+	SleepTimer       bool // Sleep timer (visible_reserve_sleep).
+	ToneControl      bool // Bass and treble (visible_tonectrl).
+	Balance          bool // Left right balance (enable_balance).
+	TVConnection     bool // visible_tvconn.
+	Initialization   bool // visible_init.
+	MediaLibrarySync bool // visible_mlib_sync.
+	Woofer           bool // Woofer level (woofermax).
+	RearBox          bool // Rear box level (rearbox*).
+	AutoDisplay      bool // autodisplay.
+	SoundEffect      bool // soundeffect.
+	StartupSound     bool // startsoundon.
+	TVRemote         bool // tvremote.
+}
+
+// NewCapabilities returns the capabilities based on the product info
+// and the settings (SETTING_INFO_REQ) reply data. The raw reply is
+// needed to tell missing settings apart from disabled ones.
+func NewCapabilities(info *api.ProductInfo, settings json.RawMessage) (*Capabilities, error) {
+	var s api.Settings
+	var keys map[string]json.RawMessage
+	if err := json.Unmarshal(settings, &s); err != nil {
+		return nil, errors.Errorf("NewCapabilities: decode settings failed: %w", err)
+	}
+	if err := json.Unmarshal(settings, &keys); err != nil {
+		return nil, errors.Errorf("NewCapabilities: decode settings failed: %w", err)
+	}
+	has := func(key ...string) bool {
+		for _, k := range key {
+			if _, ok := keys[k]; !ok {
+				return false
+			}
+		}
+		return true
+	}
+
+	return &Capabilities{
+		Model:      info.ModelType,
+		ModelName:  info.ModelName,
+		Equalizers: info.Info.Equalizers,
+		Functions:  info.Info.Functions,
+
+		Alarm:            s.VisibleAlarm,
+		SleepTimer:       s.VisibleReserveSleep,
+		ToneControl:      s.VisibleToneControl,
+		Balance:          s.EnableBalance,
+		TVConnection:     s.VisibleTVConnection,
+		Initialization:   s.VisibleInit,
+		MediaLibrarySync: s.VisibleMlibSync,
+		Woofer:           has("wooferlevel") && s.WooferMax > 0,
+		RearBox:          has("rearboxlevel", "rearboxon") && s.RearBoxMax > 0,
+		AutoDisplay:      has("autodisplay"),
+		SoundEffect:      has("soundeffect"),
+		StartupSound:     has("startsoundon"),
+		TVRemote:         has("tvremote"),
+	}, nil
+}
+
+// HasEqualizer returns true if the equalizer is available.
+func (c *Capabilities) HasEqualizer(eq api.Equalizer) bool {
+	for _, e := range c.Equalizers {
+		if e == eq {
+			return true
+		}
+	}
+	return false
+}
+
+// HasFunction returns true if the function is available.
+func (c *Capabilities) HasFunction(f api.Function) bool {
+	for _, fn := range c.Functions {
+		if fn == f {
+			return true
+		}
+	}
+	return false
+}
+
+// Capabilities returns the capabilities of the speaker. They are
+// requested once and cached until the client reconnects.
+func (c *Client) Capabilities(ctx context.Context) (*Capabilities, error) {
+	c.mu.RLock()
+	caps := c.caps
+	c.mu.RUnlock()
+	if caps != nil {
+		return caps, nil
+	}
+
+	info, err := c.ProductInfo(ctx, time.Now(), false)
+	if err != nil {
+		return nil, errors.Errorf("Capabilities failed: %w", err)
+	}
+	var settings json.RawMessage
+	err = c.Send(ctx, newRequest(api.SettingInfoRequest{}), &settings)
+	if err != nil {
+		return nil, errors.Errorf("Capabilities failed: %w", err)
+	}
+	caps, err = NewCapabilities(info, settings)
+	if err != nil {
+		return nil, errors.Errorf("Capabilities failed: %w", err)
+	}
+
+	c.mu.Lock()
+	c.caps = caps
+	c.mu.Unlock()
+
+	return caps, nil
+}
+
+// supports returns ErrUnsupported (wrapped with the feature) unless
+// the speaker supports the feature.
+func (c *Client) supports(ctx context.Context, feature string, ok func(*Capabilities) bool) error {
+	caps, err := c.Capabilities(ctx)
+	if err != nil {
+		return err
+	}
+	if !ok(caps) {
+		return errors.Errorf("%s: %w", feature, ErrUnsupported)
+	}
+	return nil
+}
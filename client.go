@@ -26,14 +26,21 @@ var clientID = makeClientID("gsm", "goodspeaker/musicflow v0.0.0")
 
 // Client represents a Music Flow Player client.
 type Client struct {
-	conn io.ReadWriteCloser
-	o    dialOptions
+	o dialOptions
 
-	waitC chan *waitFor // Concurrency guard, not buffered.
-	recvC chan Response
+	waitC     chan *waitFor // Concurrency guard, not buffered.
+	recvC     chan Response
+	done      chan struct{} // Closed by Close, stops recv.
+	closeOnce sync.Once
 
 	mu        sync.RWMutex
+	conn      io.ReadWriteCloser
+	connLost  chan struct{} // Closed when conn is lost.
 	broadcast func(string, []byte)
+	subs      []chan Response
+	caps      *Capabilities // Cached, see Capabilities.
+
+	drift driftReport
 }
 
 // NewClient returns a new Music Flow Player client that uses the
@@ -47,31 +54,111 @@ func newClient(conn io.ReadWriteCloser, o dialOptions) *Client {
 		o.logger = noopLogger{}
 	}
 	c := &Client{
-		conn:  conn,
-		o:     o,
-		waitC: make(chan *waitFor),
-		recvC: make(chan Response, 1),
+		conn:     conn,
+		connLost: make(chan struct{}),
+		o:        o,
+		waitC:    make(chan *waitFor),
+		recvC:    make(chan Response, 1),
+		done:     make(chan struct{}),
 	}
 	go c.recv()
-	go c.read()
+	go c.read(conn, c.connLost)
 
 	return c
 }
 
+// reconnect replaces the current connection with a new one. Only
+// possible when the client was created via Dial.
+func (c *Client) reconnect(ctx context.Context) error {
+	if c.o.addr == "" {
+		return errors.New("reconnect: address unknown, client was not created via Dial")
+	}
+
+	conn, err := dial(ctx, c.o)
+	if err != nil {
+		return errors.Errorf("reconnect: %w", err)
+	}
+	c.o.metrics.connected(true)
+	lost := make(chan struct{})
+
+	c.mu.Lock()
+	old := c.conn
+	c.conn = conn
+	c.connLost = lost
+	c.caps = nil // May have changed, e.g. after an update.
+	c.mu.Unlock()
+
+	_ = old.Close()
+	go c.read(conn, lost)
+
+	return nil
+}
+
+// lost returns a channel that is closed when the current connection
+// is lost.
+func (c *Client) lost() <-chan struct{} {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.connLost
+}
+
+// subscribe returns a channel that receives all broadcasts until
+// unsubscribe is called. Broadcasts are dropped when the channel is
+// full so that a slow subscriber never blocks the receiver.
+func (c *Client) subscribe() (broadcasts <-chan Response, unsubscribe func()) {
+	ch := make(chan Response, 32)
+
+	c.mu.Lock()
+	c.subs = append(c.subs, ch)
+	c.mu.Unlock()
+
+	return ch, func() {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		for i, sub := range c.subs {
+			if sub == ch {
+				c.subs = append(c.subs[:i], c.subs[i+1:]...)
+				break
+			}
+		}
+	}
+}
+
 func (c *Client) log() Logger {
 	return c.o.logger
 }
 
+// waitBroadcast waits for message to be received on broadcasts (see
+// subscribe) or for the timeout to expire.
+func waitBroadcast(ctx context.Context, broadcasts <-chan Response, message string, timeout time.Duration) (Response, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	for {
+		select {
+		case resp := <-broadcasts:
+			if resp.Message == message {
+				return resp, nil
+			}
+		case <-ctx.Done():
+			return Response{}, errors.Errorf("waiting for %s: %w", message, ctx.Err())
+		}
+	}
+}
+
 type waitFor struct {
 	ctx     context.Context
 	message string
 	result  string
 	respC   chan Response
+	sentC   chan struct{} // Closed when the request has been written.
+	writeC  chan struct{} // Closed when the write is done or failed.
 }
 
 func (w *waitFor) init(ctx context.Context, message string) {
 	w.ctx = ctx
 	w.respC = make(chan Response, 1)
+	w.sentC = make(chan struct{})
+	w.writeC = make(chan struct{})
 	if w.message == "" {
 		// By default, we expect the response to be same as request.
 		w.message = message
@@ -104,8 +191,14 @@ func WaitFor(message string, result string) SendOption {
 	}
 }
 
-// Send a request to the Music Flow device.
-func (c *Client) Send(ctx context.Context, req Request, reply interface{}, opts ...SendOption) error {
+// Send a request to the Music Flow device. The reply data is
+// unmarshaled into reply, unless it is a *Response in which case the
+// response is stored as is.
+func (c *Client) Send(ctx context.Context, req Request, reply interface{}, opts ...SendOption) (err error) {
+	defer func(start time.Time) {
+		c.o.metrics.request(req.Message, start, err)
+	}(time.Now())
+
 	// Clean up the sent JSON, ignore "data" key when request has no
 	// additional parameters.
 	if z, ok := req.Data.(interface{ IsZero() bool }); ok && z.IsZero() {
@@ -135,26 +228,35 @@ func (c *Client) Send(ctx context.Context, req Request, reply interface{}, opts
 
 	select {
 	case c.waitC <- &o.wait: // Concurrency guard.
+	case <-c.done:
+		return errors.New("Send: client closed")
 	case <-ctx.Done():
 		return ctx.Err()
 	}
 
+	c.mu.RLock()
+	conn := c.conn
+	c.mu.RUnlock()
+
 	go func() {
+		defer close(o.wait.writeC)
+
 		// Avoid blocking for a long time if the connection disappeared.
-		if conn, ok := c.conn.(interface{ Conn() net.Conn }); ok {
+		if conn, ok := conn.(interface{ Conn() net.Conn }); ok {
 			_ = conn.Conn().SetWriteDeadline(time.Now().Add(10 * time.Second))
 		}
 
 		c.log().Printf("<= %s", b)
 
-		_, err = c.conn.Write(b)
+		_, err := conn.Write(b)
 		if err != nil {
 			errC <- errors.Errorf("Send: write failed: %w", err)
 			return
 		}
+		close(o.wait.sentC)
 
 		// Disable timeout.
-		if conn, ok := c.conn.(interface{ Conn() net.Conn }); ok {
+		if conn, ok := conn.(interface{ Conn() net.Conn }); ok {
 			_ = conn.Conn().SetWriteDeadline(time.Time{})
 		}
 	}()
@@ -164,40 +266,71 @@ func (c *Client) Send(ctx context.Context, req Request, reply interface{}, opts
 	case resp = <-o.wait.respC:
 	case err = <-errC:
 		return err
+	case <-c.done:
+		return errors.New("Send: client closed")
 	case <-ctx.Done():
 		return ctx.Err()
 	}
 
 	switch {
 	case resp.Message == api.MessageParsingError:
-		return errors.New("Send: player could not parse the request")
+		c.o.metrics.parseError()
+		return errors.Errorf("Send: player could not parse the request: %w", ErrProtocol)
 	case resp.Result != o.wait.result:
-		return errors.Errorf("Send: player returned unexpected result: %q != %q", o.wait.result, resp.Result)
+		return errors.Errorf("Send: player returned unexpected result: %q != %q: %w", o.wait.result, resp.Result, ErrProtocol)
 	}
 
+	if r, ok := reply.(*Response); ok {
+		*r = resp
+		return nil
+	}
 	if reply == nil {
 		return nil
 	}
 	err = json.Unmarshal([]byte(resp.Data), reply)
 	if err != nil {
-		return errors.Errorf("unmarshal %s reply into %T failed: %w", req.Message, reply, err)
+		c.o.metrics.parseError()
+		return &protocolError{msg: fmt.Sprintf("unmarshal %s reply into %T failed", req.Message, reply), err: err}
+	}
+	if c.o.strict {
+		c.checkDrift(resp.Message, resp.Data, reply)
 	}
 
 	return nil
 }
 
-func (c *Client) read() {
-	dec := json.NewDecoder(c.conn)
+// protocolError is an ErrProtocol caused by err, e.g. a reply that
+// could not be unmarshaled. Both ErrProtocol and err are matched by
+// errors.Is and errors.As.
+type protocolError struct {
+	msg string
+	err error
+}
+
+func (e *protocolError) Error() string {
+	return fmt.Sprintf("%s: %v: %v", e.msg, e.err, ErrProtocol)
+}
+func (e *protocolError) Is(target error) bool { return target == ErrProtocol }
+func (e *protocolError) Unwrap() error        { return e.err }
+
+func (c *Client) read(conn io.ReadWriteCloser, lost chan struct{}) {
+	defer close(lost)
+
+	dec := json.NewDecoder(conn)
 	for {
 		var r Response
 		err := dec.Decode(&r)
 		if err != nil {
-			defer c.close(err)
+			defer conn.Close()
 
 			if errors.Is(err, io.EOF) {
 				c.log().Printf("Connection lost")
 				return
 			}
+			switch err.(type) {
+			case *json.SyntaxError, *json.UnmarshalTypeError:
+				c.o.metrics.parseError()
+			}
 			c.log().Printf("%+v", err)
 			return
 		}
@@ -205,12 +338,30 @@ func (c *Client) read() {
 			b, _ := json.Marshal(r)
 			c.log().Printf("=> %s", string(b))
 		}
-		c.recvC <- r
+		select {
+		case c.recvC <- r:
+		case <-c.done:
+			return
+		}
 	}
 }
 
+// staleReplyTimeout is how long a reply to a canceled request is
+// expected, see recv.
+var staleReplyTimeout = 10 * time.Second
+
 func (c *Client) recv() {
 	var wait *waitFor
+	// Replies to canceled requests that were sent, by message. The
+	// replies carry no request ID so a pending request for the same
+	// message always takes the reply, the speaker answers in order.
+	// Only a reply nobody waits for is dropped instead of being
+	// forwarded as a broadcast.
+	type staleReplies struct {
+		n     int
+		until time.Time
+	}
+	stale := make(map[string]staleReplies)
 recvLoop:
 	for {
 		var resp Response
@@ -220,41 +371,95 @@ recvLoop:
 				goto recvLoop
 			case resp = <-c.recvC:
 				// Broadcast.
+			case <-c.done:
+				return
 			}
 		} else {
 			select {
 			case <-wait.ctx.Done():
+				// The request might have reached the speaker even
+				// though the write is not done, wait for it (bounded
+				// by the write deadline) before the next request.
+				<-wait.writeC
+				select {
+				case <-wait.sentC:
+					if wait.result != "" {
+						st := stale[wait.message]
+						st.n++
+						st.until = time.Now().Add(staleReplyTimeout)
+						stale[wait.message] = st
+					}
+				default:
+				}
 				wait = nil
 				goto recvLoop
 			case resp = <-c.recvC:
 				// Send response or broadcast.
+			case <-c.done:
+				return
 			}
+		}
 
+		if wait != nil {
 			if wait.respond(resp) {
+				// The reply can precede the end of the write (e.g. the
+				// late reply to a canceled request), writes must not
+				// overlap.
+				<-wait.writeC
 				wait = nil
 				goto recvLoop
 			}
 		}
 
+		if st, ok := stale[resp.Message]; ok && resp.Result != "" {
+			if st.n--; st.n == 0 {
+				delete(stale, resp.Message)
+			} else {
+				stale[resp.Message] = st
+			}
+			if time.Now().Before(st.until) {
+				c.log().Printf("Dropped reply to canceled request: %s", resp.Message)
+				goto recvLoop
+			}
+		}
+
 		// No wait pending, forward response broadcast.
+		c.o.metrics.broadcast(resp.Message)
+		if c.o.strict {
+			c.checkEventDrift(resp)
+		}
 		c.mu.RLock()
 		if c.broadcast != nil {
 			c.broadcast(resp.Message, resp.Data)
 		}
+		for _, sub := range c.subs {
+			select {
+			case sub <- resp:
+			default:
+				c.log().Printf("Subscriber full, dropped broadcast: %s", resp.Message)
+			}
+		}
 		c.mu.RUnlock()
 	}
 }
 
+// Close closes the connection and stops the client, pending and
+// future requests fail.
 func (c *Client) Close() error {
 	return c.close(nil)
 }
 
 func (c *Client) close(err error) error {
-	// TODO: Close recv goroutine.
 	// TODO: Save the error that closed the connection.
 	_ = err
 
-	err = c.conn.Close()
+	c.closeOnce.Do(func() { close(c.done) })
+
+	c.mu.RLock()
+	conn := c.conn
+	c.mu.RUnlock()
+
+	err = conn.Close()
 	if err != nil {
 		return err
 	}
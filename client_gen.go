@@ -0,0 +1,370 @@
+// Code generated by protogen from api/protocol.json. DO NOT EDIT.
+
+package musicflow
+
+import (
+	"context"
+
+	errors "golang.org/x/xerrors"
+
+	"github.com/mafredri/musicflow/api"
+)
+
+// Alarms lists all alarms.
+func (c *Client) Alarms(ctx context.Context) ([]api.Alarm, error) {
+	req := api.AlarmListRequest{}
+	reply := req.Reply()
+	err := c.Send(ctx, newRequest(req), reply)
+	if err != nil {
+		return nil, errors.Errorf("Alarms failed: %w", err)
+	}
+	return reply.Info, nil
+}
+
+// AlarmState returns true if an alarm is active right now.
+func (c *Client) AlarmState(ctx context.Context) (bool, error) {
+	req := api.AlarmStateRequest{}
+	reply := req.Reply()
+	err := c.Send(ctx, newRequest(req), reply, WaitFor(api.MessageAlarmStateNotification, ""))
+	if err != nil {
+		return false, errors.Errorf("AlarmState failed: %w", err)
+	}
+	return reply.On, nil
+}
+
+// SleepAfter sets the sleep timer in minutes, -1 disables it.
+func (c *Client) SleepAfter(ctx context.Context, minutes int) error {
+	req := api.SleepSetRequest{Time: minutes}
+	err := c.Send(ctx, newRequest(req), nil)
+	if err != nil {
+		return errors.Errorf("SleepAfter failed: %w", err)
+	}
+	return nil
+}
+
+// NightMode sets the nightmode on or off.
+func (c *Client) NightMode(ctx context.Context, on bool) error {
+	req := api.NightModeSetRequest{NightMode: on}
+	reply := req.Reply()
+	err := c.Send(ctx, newRequest(req), reply)
+	if err != nil {
+		return errors.Errorf("NightMode failed: %w", err)
+	}
+	if reply.NightMode != on {
+		return errors.Errorf("NightMode: wrong return value: %w", ErrProtocol)
+	}
+	return nil
+}
+
+// Mute mutes or unmutes the speaker.
+func (c *Client) Mute(ctx context.Context, on bool) error {
+	req := api.MuteSetRequest{Mute: on}
+	err := c.Send(ctx, newRequest(req), nil)
+	if err != nil {
+		return errors.Errorf("Mute failed: %w", err)
+	}
+	return nil
+}
+
+// SystemVersion returns the system version information.
+func (c *Client) SystemVersion(ctx context.Context) (*api.SystemVersion, error) {
+	req := api.SystemVersionRequest{}
+	reply := req.Reply()
+	err := c.Send(ctx, newRequest(req), reply)
+	if err != nil {
+		return nil, errors.Errorf("SystemVersion failed: %w", err)
+	}
+	return reply, nil
+}
+
+// Settings returns the speaker settings.
+func (c *Client) Settings(ctx context.Context) (*api.Settings, error) {
+	req := api.SettingInfoRequest{}
+	reply := req.Reply()
+	err := c.Send(ctx, newRequest(req), reply)
+	if err != nil {
+		return nil, errors.Errorf("Settings failed: %w", err)
+	}
+	return reply, nil
+}
+
+// NetworkInfo returns the network information.
+//
+// WARNING: Returns WiFi password in plain text.
+func (c *Client) NetworkInfo(ctx context.Context) (*api.NetworkInfo, error) {
+	req := api.NetworkInfoRequest{}
+	reply := req.Reply()
+	err := c.Send(ctx, newRequest(req), reply)
+	if err != nil {
+		return nil, errors.Errorf("NetworkInfo failed: %w", err)
+	}
+	return reply, nil
+}
+
+// PlayInfo returns information on what's playing.
+func (c *Client) PlayInfo(ctx context.Context) (*api.PlayInfo, error) {
+	req := api.PlayInfoRequest{}
+	reply := req.Reply()
+	err := c.Send(ctx, newRequest(req), reply)
+	if err != nil {
+		return nil, errors.Errorf("PlayInfo failed: %w", err)
+	}
+	return reply, nil
+}
+
+// WooferLevel sets the woofer level.
+func (c *Client) WooferLevel(ctx context.Context, level int) error {
+	err := c.supports(ctx, "woofer", func(caps *Capabilities) bool {
+		return caps.Woofer
+	})
+	if err != nil {
+		return errors.Errorf("WooferLevel failed: %w", err)
+	}
+	req := api.WooferLevelSetRequest{Level: level}
+	reply := req.Reply()
+	err = c.Send(ctx, newRequest(req), reply)
+	if err != nil {
+		return errors.Errorf("WooferLevel failed: %w", err)
+	}
+	if reply.Level != level {
+		return errors.Errorf("WooferLevel: wrong return value: %w", ErrProtocol)
+	}
+	return nil
+}
+
+// RearBoxLevel sets the rear box (rear speakers) level.
+func (c *Client) RearBoxLevel(ctx context.Context, level int) error {
+	err := c.supports(ctx, "rear box", func(caps *Capabilities) bool {
+		return caps.RearBox
+	})
+	if err != nil {
+		return errors.Errorf("RearBoxLevel failed: %w", err)
+	}
+	req := api.RearBoxLevelSetRequest{Level: level}
+	reply := req.Reply()
+	err = c.Send(ctx, newRequest(req), reply)
+	if err != nil {
+		return errors.Errorf("RearBoxLevel failed: %w", err)
+	}
+	if reply.Level != level {
+		return errors.Errorf("RearBoxLevel: wrong return value: %w", ErrProtocol)
+	}
+	return nil
+}
+
+// EqualizerInfo returns the equalizer settings.
+func (c *Client) EqualizerInfo(ctx context.Context) (*api.EqualizerInfo, error) {
+	req := api.EqualizerInfoRequest{}
+	reply := req.Reply()
+	err := c.Send(ctx, newRequest(req), reply)
+	if err != nil {
+		return nil, errors.Errorf("EqualizerInfo failed: %w", err)
+	}
+	return reply, nil
+}
+
+// FunctionInfo returns the active function (input).
+func (c *Client) FunctionInfo(ctx context.Context) (*api.FunctionInfo, error) {
+	req := api.FunctionInfoRequest{}
+	reply := req.Reply()
+	err := c.Send(ctx, newRequest(req), reply)
+	if err != nil {
+		return nil, errors.Errorf("FunctionInfo failed: %w", err)
+	}
+	return reply, nil
+}
+
+// DRC sets dynamic range compression on or off.
+func (c *Client) DRC(ctx context.Context, drc bool) error {
+	req := api.DRCSetRequest{DRC: drc}
+	reply := req.Reply()
+	err := c.Send(ctx, newRequest(req), reply)
+	if err != nil {
+		return errors.Errorf("DRC failed: %w", err)
+	}
+	if reply.DRC != drc {
+		return errors.Errorf("DRC: wrong return value: %w", ErrProtocol)
+	}
+	return nil
+}
+
+// AutoVolume sets auto volume on or off.
+func (c *Client) AutoVolume(ctx context.Context, autoVolume bool) error {
+	req := api.AutoVolumeSetRequest{AutoVolume: autoVolume}
+	reply := req.Reply()
+	err := c.Send(ctx, newRequest(req), reply)
+	if err != nil {
+		return errors.Errorf("AutoVolume failed: %w", err)
+	}
+	if reply.AutoVolume != autoVolume {
+		return errors.Errorf("AutoVolume: wrong return value: %w", ErrProtocol)
+	}
+	return nil
+}
+
+// AutoPower sets auto power (on and off) on or off.
+func (c *Client) AutoPower(ctx context.Context, autoPower bool) error {
+	req := api.AutoPowerSetRequest{AutoPower: autoPower}
+	reply := req.Reply()
+	err := c.Send(ctx, newRequest(req), reply)
+	if err != nil {
+		return errors.Errorf("AutoPower failed: %w", err)
+	}
+	if reply.AutoPower != autoPower {
+		return errors.Errorf("AutoPower: wrong return value: %w", ErrProtocol)
+	}
+	return nil
+}
+
+// AVSync sets the audio delay (AV sync).
+func (c *Client) AVSync(ctx context.Context, avSync int) error {
+	req := api.AVSyncSetRequest{AVSync: avSync}
+	reply := req.Reply()
+	err := c.Send(ctx, newRequest(req), reply)
+	if err != nil {
+		return errors.Errorf("AVSync failed: %w", err)
+	}
+	if reply.AVSync != avSync {
+		return errors.Errorf("AVSync: wrong return value: %w", ErrProtocol)
+	}
+	return nil
+}
+
+// Led sets the status LED on or off.
+func (c *Client) Led(ctx context.Context, on bool) error {
+	req := api.LedSetRequest{On: on}
+	reply := req.Reply()
+	err := c.Send(ctx, newRequest(req), reply)
+	if err != nil {
+		return errors.Errorf("Led failed: %w", err)
+	}
+	if reply.On != on {
+		return errors.Errorf("Led: wrong return value: %w", ErrProtocol)
+	}
+	return nil
+}
+
+// BluetoothStandby sets Bluetooth standby on or off.
+func (c *Client) BluetoothStandby(ctx context.Context, on bool) error {
+	req := api.BluetoothStandbySetRequest{On: on}
+	reply := &api.BluetoothStandbyStateEvent{}
+	err := c.Send(ctx, newRequest(req), reply, WaitFor(api.MessageBluetoothStandbyStateNotification, ""))
+	if err != nil {
+		return errors.Errorf("BluetoothStandby failed: %w", err)
+	}
+	if reply.On != on {
+		return errors.Errorf("BluetoothStandby: wrong return value: %w", ErrProtocol)
+	}
+	return nil
+}
+
+// LimitBluetoothConnection limits Bluetooth connections (to one
+// device).
+func (c *Client) LimitBluetoothConnection(ctx context.Context, limit bool) error {
+	req := api.BluetoothLimitSetRequest{Limit: limit}
+	reply := &api.BluetoothLimitSetEvent{}
+	err := c.Send(ctx, newRequest(req), reply, WaitFor(api.MessageBluetoothLimitSetNotification, ""))
+	if err != nil {
+		return errors.Errorf("LimitBluetoothConnection failed: %w", err)
+	}
+	if reply.Limit != limit {
+		return errors.Errorf("LimitBluetoothConnection: wrong return value: %w", ErrProtocol)
+	}
+	return nil
+}
+
+// UsageShare returns true if usage data is shared with LG.
+func (c *Client) UsageShare(ctx context.Context) (bool, error) {
+	req := api.UsageShareGetRequest{}
+	reply := req.Reply()
+	err := c.Send(ctx, newRequest(req), reply)
+	if err != nil {
+		return false, errors.Errorf("UsageShare failed: %w", err)
+	}
+	return reply.Sharing, nil
+}
+
+// SetUsageShare sets sharing of usage data with LG on or off.
+func (c *Client) SetUsageShare(ctx context.Context, sharing bool) error {
+	req := api.UsageShareSetRequest{Sharing: sharing}
+	err := c.Send(ctx, newRequest(req), nil)
+	if err != nil {
+		return errors.Errorf("SetUsageShare failed: %w", err)
+	}
+	return nil
+}
+
+// ContentProviders lists the music services supported by the speaker.
+func (c *Client) ContentProviders(ctx context.Context) ([]api.ContentProvider, error) {
+	req := api.ContentProviderListRequest{}
+	reply := req.Reply()
+	err := c.Send(ctx, newRequest(req), reply)
+	if err != nil {
+		return nil, errors.Errorf("ContentProviders failed: %w", err)
+	}
+	return reply.ContentProviders, nil
+}
+
+// AutoDisplay sets auto display on or off.
+func (c *Client) AutoDisplay(ctx context.Context, autoDisplay bool) error {
+	err := c.supports(ctx, "auto display", func(caps *Capabilities) bool {
+		return caps.AutoDisplay
+	})
+	if err != nil {
+		return errors.Errorf("AutoDisplay failed: %w", err)
+	}
+	req := api.AutoDisplaySetRequest{AutoDisplay: autoDisplay}
+	err = c.Send(ctx, newRequest(req), nil)
+	if err != nil {
+		return errors.Errorf("AutoDisplay failed: %w", err)
+	}
+	return nil
+}
+
+// SoundEffect sets the sound effect on or off.
+func (c *Client) SoundEffect(ctx context.Context, soundEffect bool) error {
+	err := c.supports(ctx, "sound effect", func(caps *Capabilities) bool {
+		return caps.SoundEffect
+	})
+	if err != nil {
+		return errors.Errorf("SoundEffect failed: %w", err)
+	}
+	req := api.SoundEffectSetRequest{SoundEffect: soundEffect}
+	err = c.Send(ctx, newRequest(req), nil)
+	if err != nil {
+		return errors.Errorf("SoundEffect failed: %w", err)
+	}
+	return nil
+}
+
+// StartupSound sets the startup sound on or off.
+func (c *Client) StartupSound(ctx context.Context, on bool) error {
+	err := c.supports(ctx, "startup sound", func(caps *Capabilities) bool {
+		return caps.StartupSound
+	})
+	if err != nil {
+		return errors.Errorf("StartupSound failed: %w", err)
+	}
+	req := api.StartupSoundSetRequest{On: on}
+	err = c.Send(ctx, newRequest(req), nil)
+	if err != nil {
+		return errors.Errorf("StartupSound failed: %w", err)
+	}
+	return nil
+}
+
+// TVRemote sets control via the TV remote on or off.
+func (c *Client) TVRemote(ctx context.Context, tvRemote bool) error {
+	err := c.supports(ctx, "TV remote", func(caps *Capabilities) bool {
+		return caps.TVRemote
+	})
+	if err != nil {
+		return errors.Errorf("TVRemote failed: %w", err)
+	}
+	req := api.TVRemoteSetRequest{TVRemote: tvRemote}
+	err = c.Send(ctx, newRequest(req), nil)
+	if err != nil {
+		return errors.Errorf("TVRemote failed: %w", err)
+	}
+	return nil
+}
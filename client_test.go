@@ -0,0 +1,394 @@
+package musicflow
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"net"
+	"testing"
+	"testing/quick"
+	"time"
+
+	errors "golang.org/x/xerrors"
+
+	"github.com/mafredri/musicflow/api"
+)
+
+// pipeSpeaker is the speaker side of an in-memory connection to a
+// Client, messages are plain JSON (see NewClient).
+type pipeSpeaker struct {
+	conn net.Conn
+	dec  *json.Decoder
+}
+
+func newPipeClient(t *testing.T) (*Client, *pipeSpeaker) {
+	t.Helper()
+	cc, sc := net.Pipe()
+	c := NewClient(cc)
+	t.Cleanup(func() {
+		c.Close()
+		sc.Close()
+	})
+	return c, &pipeSpeaker{conn: sc, dec: json.NewDecoder(sc)}
+}
+
+func (s *pipeSpeaker) request() (Response, error) {
+	var req Response
+	if err := s.dec.Decode(&req); err != nil {
+		return req, fmt.Errorf("read request: %w", err)
+	}
+	return req, nil
+}
+
+func (s *pipeSpeaker) send(resp Response) error {
+	b, _ := json.Marshal(resp)
+	if _, err := s.conn.Write(b); err != nil {
+		return fmt.Errorf("write %s: %w", resp.Message, err)
+	}
+	return nil
+}
+
+func TestSendUnmarshalError(t *testing.T) {
+	c, s := newPipeClient(t)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	go func() {
+		req, err := s.request()
+		if err == nil {
+			err = s.send(Response{Message: req.Message, Result: "OK", Data: json.RawMessage(`{"reg": "yes"}`)})
+		}
+		if err != nil {
+			t.Error(err)
+		}
+	}()
+
+	req := api.ProductInfoRequest{}
+	err := c.Send(ctx, newRequest(req), req.Reply())
+	if !errors.Is(err, ErrProtocol) {
+		t.Errorf("Send() error = %v, want ErrProtocol", err)
+	}
+	var typeErr *json.UnmarshalTypeError
+	if !errors.As(err, &typeErr) {
+		t.Errorf("Send() error = %v, want *json.UnmarshalTypeError in the chain", err)
+	}
+}
+
+func TestWaitForRespond(t *testing.T) {
+	messages := []string{"A", "B", api.MessageParsingError, ""}
+	f := func(wi, ri uint8, result string) bool {
+		var w waitFor
+		w.init(context.Background(), messages[int(wi)%len(messages)])
+		resp := Response{Message: messages[int(ri)%len(messages)], Result: result}
+
+		want := resp.Message == w.message || resp.Message == api.MessageParsingError
+		if got := w.respond(resp); got != want {
+			t.Logf("respond(%+v) to %q = %v, want %v", resp, w.message, got, want)
+			return false
+		}
+		if want && len(w.respC) != 1 {
+			t.Logf("respond(%+v) to %q did not deliver the response", resp, w.message)
+			return false
+		}
+		return true
+	}
+	if err := quick.Check(f, nil); err != nil {
+		t.Error(err)
+	}
+}
+
+// interleaveOp is a request in TestRecvInterleaving.
+type interleaveOp struct {
+	seq        int
+	message    string
+	broadcasts int  // Sent before the reply.
+	cancel     bool // The request is canceled before the reply is sent.
+}
+
+type seqData struct {
+	Seq int `json:"seq"`
+}
+
+// TestRecvInterleaving sends requests while the speaker interleaves
+// broadcasts with the replies at random, some requests are canceled
+// and answered late. Replies must never be forwarded as broadcasts,
+// broadcasts are all received in order and a request only receives a
+// reply to itself or to an earlier canceled request for the same
+// message (replies carry no request ID).
+func TestRecvInterleaving(t *testing.T) {
+	for i := 0; i < 200; i++ {
+		seed := time.Now().UnixNano()
+		t.Run(fmt.Sprint(i), func(t *testing.T) {
+			testRecvInterleaving(t, rand.New(rand.NewSource(seed)))
+			if t.Failed() {
+				t.Logf("seed %d", seed)
+			}
+		})
+		if t.Failed() {
+			return
+		}
+	}
+}
+
+func testRecvInterleaving(t *testing.T, rnd *rand.Rand) {
+	messages := []string{"TEST_A", "TEST_B", "TEST_C"}
+	var ops []interleaveOp
+	for i := 0; i < 1+rnd.Intn(20); i++ {
+		ops = append(ops, interleaveOp{
+			seq:        i,
+			message:    messages[rnd.Intn(len(messages))],
+			broadcasts: rnd.Intn(4),
+			cancel:     rnd.Intn(4) == 0,
+		})
+	}
+
+	c, s := newPipeClient(t)
+	broadcasts, unsubscribe := c.subscribe()
+	defer unsubscribe()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	// Received by the subscriber until the end marker.
+	received := make(chan []Response, 1)
+	go func() {
+		var got []Response
+		for {
+			select {
+			case resp := <-broadcasts:
+				if resp.Message == "TEST_END" {
+					received <- got
+					return
+				}
+				got = append(got, resp)
+			case <-ctx.Done():
+				received <- got
+				return
+			}
+		}
+	}()
+
+	canceled := make(chan struct{}) // The speaker waits for the cancel.
+	ack := make(chan struct{})
+	bseq := 0
+	speakerDone := make(chan error, 1)
+	go func() {
+		speakerDone <- func() error {
+			for _, op := range ops {
+				req, err := s.request()
+				if err != nil {
+					return err
+				}
+				if req.Message != op.message {
+					return fmt.Errorf("request %d: got %s, want %s", op.seq, req.Message, op.message)
+				}
+				for i := 0; i < op.broadcasts; i++ {
+					data, _ := json.Marshal(seqData{Seq: bseq})
+					bseq++
+					if err = s.send(Response{Message: "TEST_EVENT", Data: data}); err != nil {
+						return err
+					}
+				}
+				if op.cancel {
+					canceled <- struct{}{}
+					<-ack
+				}
+				data, _ := json.Marshal(seqData{Seq: op.seq})
+				if err = s.send(Response{Message: op.message, Result: "OK", Data: data}); err != nil {
+					return err
+				}
+			}
+			return s.send(Response{Message: "TEST_END"})
+		}()
+	}()
+
+	// Seqs of the outstanding replies to canceled requests, by message.
+	outstanding := make(map[string][]int)
+	checkReply := func(op interleaveOp, reply Response) {
+		t.Helper()
+		var got seqData
+		if err := json.Unmarshal(reply.Data, &got); err != nil {
+			t.Fatal(err)
+		}
+		if reply.Message != op.message {
+			t.Errorf("request %d: reply %s, want %s", op.seq, reply.Message, op.message)
+		}
+		valid := got.Seq == op.seq
+		var rest []int
+		for _, seq := range outstanding[op.message] {
+			if seq == got.Seq {
+				valid = true
+			}
+			// Answered in order, earlier replies were dropped.
+			if seq > got.Seq {
+				rest = append(rest, seq)
+			}
+		}
+		if !valid {
+			t.Errorf("request %d (%s): got reply to request %d", op.seq, op.message, got.Seq)
+		}
+		if got.Seq != op.seq {
+			rest = append(rest, op.seq)
+		}
+		outstanding[op.message] = rest
+	}
+
+	for _, op := range ops {
+		rctx, rcancel := context.WithCancel(ctx)
+		errC := make(chan error, 1)
+		var reply Response
+		go func() {
+			errC <- c.Send(rctx, Request{Message: op.message, Data: seqData{Seq: op.seq}}, &reply)
+		}()
+
+		if op.cancel {
+			select {
+			case <-canceled:
+			case err := <-speakerDone:
+				t.Fatalf("speaker: %v", err)
+			}
+			rcancel()
+			err := <-errC
+			switch {
+			case err == nil:
+				// Took the late reply to an earlier request.
+				checkReply(op, reply)
+			case errors.Is(err, context.Canceled):
+				outstanding[op.message] = append(outstanding[op.message], op.seq)
+			default:
+				t.Fatalf("request %d: Send() error = %v, want context.Canceled", op.seq, err)
+			}
+			ack <- struct{}{}
+			continue
+		}
+
+		var err error
+		select {
+		case err = <-errC:
+		case err = <-speakerDone:
+			if err != nil {
+				t.Fatalf("speaker: %v", err)
+			}
+			speakerDone <- nil // Done with the last reply.
+			err = <-errC
+		}
+		rcancel()
+		if err != nil {
+			t.Fatalf("request %d: Send() error = %v", op.seq, err)
+		}
+		checkReply(op, reply)
+	}
+
+	if err := <-speakerDone; err != nil {
+		t.Fatalf("speaker: %v", err)
+	}
+	got := <-received
+	for i, resp := range got {
+		if resp.Result != "" {
+			t.Errorf("reply %s %s forwarded as broadcast", resp.Message, resp.Data)
+			continue
+		}
+		var d seqData
+		_ = json.Unmarshal(resp.Data, &d)
+		if d.Seq != i {
+			t.Errorf("broadcast %d: got %d, out of order or dropped", i, d.Seq)
+		}
+	}
+	if len(got) != bseq {
+		t.Errorf("received %d broadcasts, want %d", len(got), bseq)
+	}
+}
+
+// responseSeeds are the seeds of FuzzResponse: a valid reply, partial
+// JSON, a parsing error and a broadcast before the reply.
+var responseSeeds = []string{
+	`{"msg": "PRODUCT_INFO", "result": "OK", "data": {"modelname": "LAS750M", "info": {"vol": 7}}}`,
+	`{"msg": "PRODUCT_INFO", "result": "OK", "data": {"model`,
+	`{"msg": "MSG_PARSING_ERROR", "result": "NG"}`,
+	`{"msg": "VOLUME_CHANGE", "data": {"vol": 12}}{"msg": "PRODUCT_INFO", "result": "OK", "data": {}}`,
+}
+
+// FuzzResponse feeds data to a client as the messages sent by the
+// speaker while a request is pending, Send must neither panic nor
+// block. Every message is also decoded like a broadcast (see
+// DecodeEvent).
+func FuzzResponse(f *testing.F) {
+	for _, seed := range responseSeeds {
+		f.Add([]byte(seed))
+	}
+	f.Fuzz(func(t *testing.T, data []byte) {
+		dec := json.NewDecoder(bytes.NewReader(data))
+		for {
+			var resp Response
+			if err := dec.Decode(&resp); err != nil {
+				break
+			}
+			_, _ = DecodeEvent(resp.Message, resp.Data)
+		}
+
+		c, s := newPipeClient(t)
+		go func() {
+			defer s.conn.Close()
+			// Read the request before replying, like the speaker.
+			if _, err := s.request(); err == nil {
+				_, _ = s.conn.Write(data)
+			}
+		}()
+
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+		defer cancel()
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			req := api.ProductInfoRequest{}
+			_ = c.Send(ctx, newRequest(req), req.Reply())
+		}()
+		select {
+		case <-done:
+		case <-time.After(5 * time.Second):
+			t.Fatal("Send blocked after the context deadline")
+		}
+	})
+}
+
+// FuzzWaitFor checks that a pending request only takes its own reply
+// or PARSING_ERROR. The data is a JSON object with the expected
+// message and result and the received response.
+func FuzzWaitFor(f *testing.F) {
+	for _, seed := range []string{
+		`{"message": "PRODUCT_INFO", "resp": {"msg": "PRODUCT_INFO", "result": "OK", "data": {}}}`,
+		`{"message": "PRODUCT_INFO", "resp": {"msg": "PRODUCT_IN`,
+		`{"message": "PRODUCT_INFO", "resp": {"msg": "MSG_PARSING_ERROR", "result": "NG"}}`,
+		`{"message": "PRODUCT_INFO", "resp": {"msg": "VOLUME_CHANGE", "data": {"vol": 12}}}`,
+		`{"message": "EQ_SETTING", "result": "OK", "resp": {"msg": "EQ_SETTING", "result": "NG"}}`,
+	} {
+		f.Add([]byte(seed))
+	}
+	f.Fuzz(func(t *testing.T, data []byte) {
+		var in struct {
+			Message string   `json:"message"`
+			Result  string   `json:"result"`
+			Resp    Response `json:"resp"`
+		}
+		if err := json.Unmarshal(data, &in); err != nil {
+			t.Skip("invalid JSON")
+		}
+
+		var w waitFor
+		if in.Result != "" {
+			w.message, w.result = in.Message, in.Result
+		}
+		w.init(context.Background(), in.Message)
+
+		want := in.Resp.Message == in.Message || in.Resp.Message == api.MessageParsingError
+		if got := w.respond(in.Resp); got != want {
+			t.Fatalf("respond(%+v) to %q = %v, want %v", in.Resp, in.Message, got, want)
+		}
+		if want && len(w.respC) != 1 {
+			t.Fatalf("respond(%+v) to %q did not deliver the response", in.Resp, in.Message)
+		}
+	})
+}
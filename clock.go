@@ -0,0 +1,143 @@
+package musicflow
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	errors "golang.org/x/xerrors"
+
+	"github.com/mafredri/musicflow/api"
+)
+
+// SetTimezone sets the timezone of the speaker, name must be an IANA
+// timezone name, e.g. "Europe/Helsinki".
+func (c *Client) SetTimezone(ctx context.Context, name string) error {
+	req := api.TimezoneSetRequest{Timezone: name}
+	err := c.Send(ctx, newRequest(req), nil)
+	if err != nil {
+		return errors.Errorf("SetTimezone failed: %w", err)
+	}
+	return nil
+}
+
+// SyncClock sets both the timezone and the time of the speaker from
+// now, the location of now must have an IANA name (the local timezone
+// is resolved via TZ or /etc/localtime).
+func (c *Client) SyncClock(ctx context.Context, now time.Time) error {
+	name, err := timezoneName(now.Location())
+	if err != nil {
+		return errors.Errorf("SyncClock failed: %w", err)
+	}
+	err = c.SetTimezone(ctx, name)
+	if err != nil {
+		return errors.Errorf("SyncClock failed: %w", err)
+	}
+	_, err = c.ProductInfo(ctx, now, true)
+	if err != nil {
+		return errors.Errorf("SyncClock failed: %w", err)
+	}
+	return nil
+}
+
+// Retry delays of ClockSync after a failed synchronization, doubled on
+// each consecutive failure.
+var (
+	clockSyncRetry    = 10 * time.Second
+	clockSyncMaxRetry = 10 * time.Minute
+)
+
+// ClockSync synchronizes the clock of the speaker with loc immediately,
+// then every interval and right after daylight saving time transitions
+// so that alarms trigger at the right wall-clock time. Failures are
+// logged and retried with backoff, lost connections are re-established
+// (requires Dial). ClockSync blocks until the context is done.
+func (c *Client) ClockSync(ctx context.Context, loc *time.Location, interval time.Duration) error {
+	if interval <= 0 {
+		return errors.New("ClockSync: interval must be positive")
+	}
+	retry := clockSyncRetry
+	for {
+		wait := interval
+		err := c.SyncClock(ctx, time.Now().In(loc))
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			c.log().Printf("ClockSync: %v, retrying in %s", err, retry)
+
+			select {
+			case <-c.lost():
+				if err = c.waitReconnect(ctx); err != nil {
+					return errors.Errorf("ClockSync failed: %w", err)
+				}
+				continue
+			default:
+			}
+
+			if retry < wait {
+				wait = retry
+			}
+			if retry *= 2; retry > clockSyncMaxRetry {
+				retry = clockSyncMaxRetry
+			}
+		} else {
+			retry = clockSyncRetry
+		}
+
+		now := time.Now().In(loc)
+		next := now.Add(wait)
+		if t, ok := nextZoneTransition(now, wait); ok {
+			// Give the speaker a moment past the transition.
+			next = t.Add(time.Minute)
+		}
+
+		timer := time.NewTimer(time.Until(next))
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		}
+	}
+}
+
+// nextZoneTransition returns the time of the next zone offset change
+// (e.g. DST) within d from t, with second precision.
+func nextZoneTransition(t time.Time, d time.Duration) (time.Time, bool) {
+	_, offset := t.Zone()
+	end := t.Add(d)
+	if _, o := end.Zone(); o == offset {
+		return time.Time{}, false
+	}
+	for end.Sub(t) > time.Second {
+		mid := t.Add(end.Sub(t) / 2)
+		if _, o := mid.Zone(); o == offset {
+			t = mid
+		} else {
+			end = mid
+		}
+	}
+	return end, true
+}
+
+// timezoneName returns the IANA name of loc.
+func timezoneName(loc *time.Location) (string, error) {
+	name := loc.String()
+	if name != "Local" {
+		return name, nil
+	}
+	if tz := strings.TrimPrefix(os.Getenv("TZ"), ":"); tz != "" && !filepath.IsAbs(tz) {
+		return tz, nil
+	}
+	// E.g. /etc/localtime -> /usr/share/zoneinfo/Europe/Helsinki.
+	p, err := filepath.EvalSymlinks("/etc/localtime")
+	if err == nil {
+		if i := strings.Index(p, "zoneinfo/"); i >= 0 {
+			return p[i+len("zoneinfo/"):], nil
+		}
+	}
+	return "", errors.New("could not determine the name of the local timezone, use time.LoadLocation")
+}
@@ -0,0 +1,172 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/mafredri/musicflow"
+	"github.com/mafredri/musicflow/api"
+)
+
+func alarmCmd(ctx context.Context, o options, args []string) error {
+	if len(args) == 0 {
+		return errors.New("alarm: missing subcommand (list, add, rm, enable or disable)")
+	}
+	switch args[0] {
+	case "list", "add", "rm", "enable", "disable":
+	default:
+		return fmt.Errorf("alarm: unknown subcommand %q", args[0])
+	}
+
+	c, err := o.dial(ctx)
+	if err != nil {
+		return err
+	}
+	defer o.close(c)
+
+	switch sub, args := args[0], args[1:]; sub {
+	case "list":
+		return alarmList(ctx, o, c)
+	case "add":
+		return alarmAdd(ctx, o, c, args)
+	default:
+		if len(args) != 1 {
+			return fmt.Errorf("alarm %s: expected alarm ID", sub)
+		}
+		a, err := findAlarm(ctx, c, args[0])
+		if err != nil {
+			return err
+		}
+		switch sub {
+		case "rm":
+			return c.AlarmDelete(ctx, a)
+		case "enable":
+			return c.AlarmEnable(ctx, a)
+		default:
+			return c.AlarmDisable(ctx, a)
+		}
+	}
+}
+
+func alarmsCmd(ctx context.Context, o options, args []string) error {
+	return alarmCmd(ctx, o, append([]string{"list"}, args...))
+}
+
+// alarmInfo is the output of alarm list.
+type alarmInfo struct {
+	ID       int        `json:"id"`
+	Time     string     `json:"time"` // HH:MM.
+	Days     []string   `json:"days"` // Empty when the alarm only goes off once.
+	Enabled  bool       `json:"enabled"`
+	Volume   int        `json:"volume"`
+	Duration int        `json:"duration"` // Minutes.
+	Shuffle  bool       `json:"shuffle"`
+	Title    string     `json:"title"`
+	Next     *time.Time `json:"next"` // Null when the alarm is disabled.
+}
+
+func alarmList(ctx context.Context, o options, c *musicflow.Client) error {
+	alarms, err := c.Alarms(ctx)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	out := []alarmInfo{}
+	for _, a := range alarms {
+		ai := alarmInfo{
+			ID:       a.ID,
+			Time:     fmt.Sprintf("%02d:%02d", a.Hour, a.Minute),
+			Days:     []string{},
+			Enabled:  a.Enable,
+			Volume:   a.Volume,
+			Duration: a.Duration,
+			Shuffle:  a.Shuffle,
+			Title:    a.Title,
+		}
+		for _, d := range a.Day.Days() {
+			ai.Days = append(ai.Days, d.String())
+		}
+		if t := a.Next(now); !t.IsZero() {
+			ai.Next = &t
+		}
+		out = append(out, ai)
+	}
+	return o.print(out, func(w io.Writer) {
+		fmt.Fprintln(w, "ID\tTIME\tDAYS\tENABLED\tVOLUME\tDURATION\tNEXT")
+		for i, a := range out {
+			next := "-"
+			if a.Next != nil {
+				next = a.Next.Format("Mon Jan 2 15:04")
+			}
+			fmt.Fprintf(w, "%d\t%s\t%s\t%t\t%d\t%dm\t%s\n",
+				a.ID, a.Time, alarms[i].Day, a.Enabled, a.Volume, a.Duration, next)
+		}
+	})
+}
+
+func alarmAdd(ctx context.Context, o options, c *musicflow.Client, args []string) error {
+	fs := flag.NewFlagSet("alarm add", flag.ExitOnError)
+	days := fs.String("days", "", "Comma separated days when the alarm is active, e.g. mon,tue (default once)")
+	volume := fs.Int("volume", 10, "Alarm volume")
+	duration := fs.Int("duration", 15, "Alarm duration in minutes")
+	shuffle := fs.Bool("shuffle", false, "Shuffle the alarm playlist")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: alarm add [options] HH:MM\n\n")
+		fs.PrintDefaults()
+	}
+	_ = fs.Parse(args)
+
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return errors.New("alarm add: expected alarm time")
+	}
+	t, err := time.Parse("15:04", fs.Arg(0))
+	if err != nil {
+		return fmt.Errorf("alarm add: invalid time: %w", err)
+	}
+	ds, err := api.ParseDaySet(*days)
+	if err != nil {
+		return err
+	}
+
+	a := api.Alarm{
+		Day:      ds,
+		Hour:     t.Hour(),
+		Minute:   t.Minute(),
+		Duration: *duration,
+		Volume:   *volume,
+		Enable:   true,
+		Shuffle:  *shuffle,
+		Title:    api.AlarmDefaultSound,
+	}
+
+	id, err := c.AlarmCreate(ctx, a)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(o.out, "Created alarm %d\n", id)
+	return nil
+}
+
+func findAlarm(ctx context.Context, c *musicflow.Client, arg string) (api.Alarm, error) {
+	id, err := strconv.Atoi(arg)
+	if err != nil {
+		return api.Alarm{}, fmt.Errorf("invalid alarm ID: %q", arg)
+	}
+	alarms, err := c.Alarms(ctx)
+	if err != nil {
+		return api.Alarm{}, err
+	}
+	for _, a := range alarms {
+		if a.ID == id {
+			return a, nil
+		}
+	}
+	return api.Alarm{}, fmt.Errorf("alarm %d not found", id)
+}
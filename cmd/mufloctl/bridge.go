@@ -0,0 +1,573 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mafredri/musicflow"
+	"github.com/mafredri/musicflow/api"
+)
+
+// envMQTTPassword is the MQTT password, same as mqtt -password (keeps
+// it out of the process list).
+const envMQTTPassword = "MUFLOCTL_MQTT_PASSWORD"
+
+// maxVolume is the volume range exposed to Home Assistant, the
+// speakers do not report their maximum.
+const maxVolume = 40
+
+func mqttCmd(ctx context.Context, o options, args []string) error {
+	fs := flag.NewFlagSet("mqtt", flag.ExitOnError)
+	broker := fs.String("broker", "localhost:1883", "MQTT broker `address`, mqtts://host:port for TLS")
+	username := fs.String("username", "", "MQTT user `name`")
+	password := fs.String("password", os.Getenv(envMQTTPassword), "MQTT password")
+	clientID := fs.String("client-id", "mufloctl", "MQTT client `ID`")
+	prefix := fs.String("prefix", "musicflow", "Topic `prefix` of the state and command topics")
+	discovery := fs.String("discovery-prefix", "homeassistant", "Home Assistant discovery topic `prefix`, empty disables discovery")
+	timeout := fs.Duration("timeout", 10*time.Second, "Timeout of the speaker requests")
+	_ = fs.Parse(args)
+
+	targets, err := o.speakerTargets(ctx)
+	if err != nil {
+		return err
+	}
+	b := &bridge{
+		prefix:    strings.Trim(*prefix, "/"),
+		discovery: strings.Trim(*discovery, "/"),
+		timeout:   *timeout,
+		speakers:  make(map[string]*bridgeSpeaker),
+	}
+	for _, t := range targets {
+		s, err := newSpeakerConn(t)
+		if err != nil {
+			return err
+		}
+		id := topicID(t.name)
+		if _, ok := b.speakers[id]; ok {
+			return fmt.Errorf("speakers %q and %q have the same topic %q", b.speakers[id].name, t.name, id)
+		}
+		b.speakers[id] = &bridgeSpeaker{speakerConn: s, id: id, state: make(map[string]string), changed: make(map[string]uint64)}
+	}
+
+	mo := mqttOptions{
+		clientID:    *clientID,
+		username:    *username,
+		password:    *password,
+		keepAlive:   30 * time.Second,
+		willTopic:   b.statusTopic(),
+		willPayload: "offline",
+	}
+	dialCtx, cancel := context.WithTimeout(ctx, *timeout)
+	m, err := dialMQTT(dialCtx, *broker, mo, b.command(ctx))
+	cancel()
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(o.out, "Bridging %d speaker(s) to %s/ via %s (Ctrl+C to exit)...\n", len(targets), b.prefix, *broker)
+
+	for _, s := range b.speakers {
+		s := s
+		go s.pump(ctx, b.timeout, func(c *musicflow.Client, events <-chan musicflow.Event) {
+			b.handle(ctx, s, c, events)
+		})
+	}
+
+	const maxBackoff = 30 * time.Second
+	backoff := time.Second
+	for {
+		b.connected(m)
+		select {
+		case <-m.Done():
+			fmt.Fprintf(os.Stderr, "error: %v, reconnecting...\n", m.Err())
+		case <-ctx.Done():
+			_ = m.publish(b.statusTopic(), []byte("offline"), true)
+			m.Close()
+			return ctx.Err()
+		}
+
+		for {
+			select {
+			case <-time.After(backoff):
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+			dialCtx, cancel := context.WithTimeout(ctx, *timeout)
+			m, err = dialMQTT(dialCtx, *broker, mo, b.command(ctx))
+			cancel()
+			if err == nil {
+				backoff = time.Second
+				break
+			}
+			fmt.Fprintf(os.Stderr, "error: %v\n", err)
+			if backoff *= 2; backoff > maxBackoff {
+				backoff = maxBackoff
+			}
+		}
+	}
+}
+
+// bridge publishes the speaker state to MQTT and applies the commands
+// received on the command topics (see mqtt):
+//
+//	PREFIX/bridge/status             online or offline
+//	PREFIX/SPEAKER/availability      online or offline
+//	PREFIX/SPEAKER/ENTITY            state, e.g. PREFIX/kitchen/volume
+//	PREFIX/SPEAKER/ENTITY/set        command
+//
+// The Home Assistant discovery config is published for each entity
+// (see entities).
+type bridge struct {
+	prefix    string
+	discovery string // Empty when disabled.
+	timeout   time.Duration
+	speakers  map[string]*bridgeSpeaker // By topic ID.
+
+	mu sync.Mutex
+	m  *mqttClient // Current broker connection.
+}
+
+// bridgeSpeaker is a speaker and its last known state.
+type bridgeSpeaker struct {
+	*speakerConn
+	id string // Topic ID, see topicID.
+
+	mu        sync.Mutex
+	available bool
+	state     map[string]string // By entity.
+	config    map[string][]byte // Discovery config by topic.
+	events    uint64            // Number of events handled.
+	changed   map[string]uint64 // Events when the entity was last set by one.
+}
+
+// entity is a Home Assistant entity of a speaker.
+type entity struct {
+	name      string // Entity of the state and command topics.
+	component string // Home Assistant component.
+	title     string
+	icon      string
+	readOnly  bool // No command topic.
+
+	// set applies the command payload.
+	set func(ctx context.Context, c *musicflow.Client, caps *musicflow.Capabilities, payload string) error
+}
+
+// Home Assistant has no MQTT media_player, the player is represented by
+// the volume, mute and title entities.
+var entities = []entity{
+	{
+		name: "volume", component: "number", title: "Volume", icon: "mdi:volume-high",
+		set: func(ctx context.Context, c *musicflow.Client, _ *musicflow.Capabilities, payload string) error {
+			f, err := strconv.ParseFloat(payload, 64)
+			if err != nil {
+				return fmt.Errorf("volume: invalid value %q", payload)
+			}
+			_, err = c.VolumeTo(ctx, int(f), 0, musicflow.CurveLinear)
+			return err
+		},
+	},
+	{
+		name: "mute", component: "switch", title: "Mute", icon: "mdi:volume-off",
+		set: switchSetter((*musicflow.Client).Mute),
+	},
+	{
+		name: "title", component: "sensor", title: "Now playing", icon: "mdi:music",
+		readOnly: true,
+	},
+	{
+		name: "input", component: "select", title: "Input", icon: "mdi:import",
+		set: func(ctx context.Context, c *musicflow.Client, caps *musicflow.Capabilities, payload string) error {
+			f, err := api.ParseFunction(payload)
+			if err != nil {
+				return err
+			}
+			return c.Function(ctx, resolveFunction(caps, f))
+		},
+	},
+	{
+		name: "equalizer", component: "select", title: "Equalizer", icon: "mdi:equalizer",
+		set: func(ctx context.Context, c *musicflow.Client, _ *musicflow.Capabilities, payload string) error {
+			eq, err := api.ParseEqualizer(payload)
+			if err != nil {
+				return err
+			}
+			return c.Equalizer(ctx, musicflow.SetEqualizer(eq))
+		},
+	},
+	{
+		name: "nightmode", component: "switch", title: "Night mode", icon: "mdi:weather-night",
+		set: switchSetter((*musicflow.Client).NightMode),
+	},
+	{
+		name: "drc", component: "switch", title: "Dynamic range control", icon: "mdi:tune-vertical",
+		set: switchSetter((*musicflow.Client).DRC),
+	},
+	{
+		name: "autovolume", component: "switch", title: "Auto volume", icon: "mdi:volume-equal",
+		set: switchSetter((*musicflow.Client).AutoVolume),
+	},
+}
+
+func switchSetter(fn func(c *musicflow.Client, ctx context.Context, on bool) error) func(context.Context, *musicflow.Client, *musicflow.Capabilities, string) error {
+	return func(ctx context.Context, c *musicflow.Client, _ *musicflow.Capabilities, payload string) error {
+		on, err := parseOnOff(payload)
+		if err != nil {
+			return err
+		}
+		return fn(c, ctx, on)
+	}
+}
+
+func switchState(on bool) string {
+	if on {
+		return "ON"
+	}
+	return "OFF"
+}
+
+// topicID returns name as a topic level, e.g. "Living room" becomes
+// "living_room".
+func topicID(name string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(name) {
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9', r == '-':
+			b.WriteRune(r)
+		default:
+			b.WriteByte('_')
+		}
+	}
+	return b.String()
+}
+
+func (b *bridge) statusTopic() string {
+	return b.prefix + "/bridge/status"
+}
+
+func (b *bridge) topic(s *bridgeSpeaker, level ...string) string {
+	return strings.Join(append([]string{b.prefix, s.id}, level...), "/")
+}
+
+// publish publishes a retained message, it is dropped when the broker
+// is not connected (the state is republished on connect).
+func (b *bridge) publish(topic string, payload []byte) {
+	b.mu.Lock()
+	m := b.m
+	b.mu.Unlock()
+	if m != nil {
+		_ = m.publish(topic, payload, true)
+	}
+}
+
+// connected announces the bridge and publishes the known state after
+// connecting to the broker.
+func (b *bridge) connected(m *mqttClient) {
+	b.mu.Lock()
+	b.m = m
+	b.mu.Unlock()
+
+	_ = m.subscribe(b.prefix + "/+/+/set")
+	_ = m.publish(b.statusTopic(), []byte("online"), true)
+	for _, s := range b.speakers {
+		s.mu.Lock()
+		for topic, config := range s.config {
+			_ = m.publish(topic, config, true)
+		}
+		_ = m.publish(b.topic(s, "availability"), []byte(availability(s.available)), true)
+		for name, v := range s.state {
+			_ = m.publish(b.topic(s, name), []byte(v), true)
+		}
+		s.mu.Unlock()
+	}
+}
+
+func availability(online bool) string {
+	if online {
+		return "online"
+	}
+	return "offline"
+}
+
+// set publishes the entity state changed by an event.
+func (b *bridge) set(s *bridgeSpeaker, name, v string) {
+	s.mu.Lock()
+	s.state[name] = v
+	s.changed[name] = s.events
+	s.mu.Unlock()
+	b.publish(b.topic(s, name), []byte(v))
+}
+
+// poll publishes the entity state requested by refresh, since is the
+// number of events handled before the request. The state is dropped
+// when an event has set the entity since, the event is newer.
+func (b *bridge) poll(s *bridgeSpeaker, since uint64, name, v string) {
+	s.mu.Lock()
+	if s.changed[name] > since {
+		s.mu.Unlock()
+		return
+	}
+	s.state[name] = v
+	s.mu.Unlock()
+	b.publish(b.topic(s, name), []byte(v))
+}
+
+func (b *bridge) setAvailable(s *bridgeSpeaker, online bool) {
+	s.mu.Lock()
+	s.available = online
+	s.mu.Unlock()
+	b.publish(b.topic(s, "availability"), []byte(availability(online)))
+}
+
+// handle announces the speaker and tracks its state while connected.
+func (b *bridge) handle(ctx context.Context, s *bridgeSpeaker, c *musicflow.Client, events <-chan musicflow.Event) {
+	reqCtx, cancel := context.WithTimeout(ctx, b.timeout)
+	err := b.announce(reqCtx, s, c)
+	if err == nil {
+		err = b.refresh(reqCtx, s, c)
+	}
+	cancel()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %s: %v\n", s.name, err)
+		if exitCode(err) == exitConnection {
+			s.close(c) // Ends the events.
+		}
+	} else {
+		b.setAvailable(s, true)
+	}
+
+	for ev := range events {
+		s.mu.Lock()
+		s.events++
+		s.mu.Unlock()
+		if err := b.update(ctx, s, c, ev); err != nil {
+			fmt.Fprintf(os.Stderr, "error: %s: %v\n", s.name, err)
+		}
+	}
+	b.setAvailable(s, false)
+}
+
+// announce publishes the Home Assistant discovery config of the
+// speaker entities.
+func (b *bridge) announce(ctx context.Context, s *bridgeSpeaker, c *musicflow.Client) error {
+	if b.discovery == "" {
+		return nil
+	}
+	info, err := c.ProductInfo(ctx, time.Now(), false)
+	if err != nil {
+		return err
+	}
+	caps, err := c.Capabilities(ctx)
+	if err != nil {
+		return err
+	}
+
+	node := "musicflow_" + s.id
+	if mac := strings.ToLower(strings.Replace(info.Info.WirelessMAC, ":", "", -1)); mac != "" {
+		node = "musicflow_" + mac
+	}
+	device := object{
+		"identifiers":  []string{node},
+		"name":         info.Info.Name,
+		"model":        info.ModelName,
+		"manufacturer": "LG",
+		"sw_version":   info.Info.BeVer,
+	}
+	if info.Info.WirelessMAC != "" {
+		device["connections"] = [][]string{{"mac", strings.ToLower(info.Info.WirelessMAC)}}
+	}
+
+	config := make(map[string][]byte)
+	for _, e := range entities {
+		cfg := object{
+			"name":      e.title,
+			"unique_id": node + "_" + e.name,
+			"object_id": s.id + "_" + e.name,
+			"icon":      e.icon,
+			"device":    device,
+			"availability": []object{
+				{"topic": b.statusTopic()},
+				{"topic": b.topic(s, "availability")},
+			},
+			"availability_mode": "all",
+			"state_topic":       b.topic(s, e.name),
+		}
+		if !e.readOnly {
+			cfg["command_topic"] = b.topic(s, e.name, "set")
+		}
+		switch e.name {
+		case "volume":
+			cfg["min"], cfg["max"], cfg["step"] = 0, maxVolume, 1
+		case "input":
+			cfg["options"] = names(caps.Functions)
+		case "equalizer":
+			if len(caps.Equalizers) == 0 {
+				continue
+			}
+			cfg["options"] = names(caps.Equalizers)
+		}
+		payload, err := json.Marshal(cfg)
+		if err != nil {
+			return err
+		}
+		config[fmt.Sprintf("%s/%s/%s/%s/config", b.discovery, e.component, node, e.name)] = payload
+	}
+
+	s.mu.Lock()
+	s.config = config
+	s.mu.Unlock()
+	for topic, payload := range config {
+		b.publish(topic, payload)
+	}
+	return nil
+}
+
+// refresh requests and publishes the state of the speaker. It runs
+// concurrently with the events (after a command), the entities set by
+// an event during the refresh are not overwritten (see poll).
+func (b *bridge) refresh(ctx context.Context, s *bridgeSpeaker, c *musicflow.Client) error {
+	s.mu.Lock()
+	since := s.events
+	s.mu.Unlock()
+	set := func(name, v string) { b.poll(s, since, name, v) }
+
+	info, err := c.ProductInfo(ctx, time.Now(), false)
+	if err != nil {
+		return err
+	}
+	set("volume", strconv.Itoa(info.Info.Volume))
+	set("mute", switchState(info.Info.Mute))
+	set("input", info.Info.Function.String())
+
+	settings, err := c.Settings(ctx)
+	if err != nil {
+		return err
+	}
+	updateSettings(set, settings)
+
+	if len(info.Info.Equalizers) > 0 {
+		eq, err := c.EqualizerInfo(ctx)
+		if err != nil {
+			return err
+		}
+		set("equalizer", eq.CurrentEqualizer.String())
+	}
+
+	play, err := c.PlayInfo(ctx)
+	if err != nil {
+		return err
+	}
+	set("title", play.Title)
+	return nil
+}
+
+func updateSettings(set func(name, v string), settings *api.Settings) {
+	set("nightmode", switchState(settings.NightMode))
+	set("drc", switchState(settings.DRC))
+	set("autovolume", switchState(settings.AutoVolume))
+}
+
+// update publishes the state changed by the speaker event.
+func (b *bridge) update(ctx context.Context, s *bridgeSpeaker, c *musicflow.Client, ev musicflow.Event) error {
+	set := func(name, v string) { b.set(s, name, v) }
+	switch v := ev.Value.(type) {
+	case *api.VolumeChangeEvent:
+		b.set(s, "volume", strconv.Itoa(v.Volume))
+	case *api.MuteChangeEvent:
+		b.set(s, "mute", switchState(v.Mute))
+	case *api.FunctionInfoEvent:
+		b.set(s, "input", v.Type.String())
+	case *api.FunctionInfo:
+		b.set(s, "input", v.Type.String())
+	case *api.NightModeSetReply:
+		b.set(s, "nightmode", switchState(v.NightMode))
+	case *api.DRCSetReply:
+		b.set(s, "drc", switchState(v.DRC))
+	case *api.AutoVolumeSetReply:
+		b.set(s, "autovolume", switchState(v.AutoVolume))
+	case *api.Settings:
+		updateSettings(set, v)
+	case *api.EqualizerInfo:
+		b.set(s, "equalizer", v.CurrentEqualizer.String())
+	case *api.PlayInfo:
+		b.set(s, "title", v.Title)
+	default:
+		ctx, cancel := context.WithTimeout(ctx, b.timeout)
+		defer cancel()
+		switch ev.Message {
+		case api.MessageSettingInfoNotification:
+			settings, err := c.Settings(ctx)
+			if err != nil {
+				return err
+			}
+			updateSettings(set, settings)
+		case api.MessageEqualizerChangeNotification:
+			eq, err := c.EqualizerInfo(ctx)
+			if err != nil {
+				return err
+			}
+			b.set(s, "equalizer", eq.CurrentEqualizer.String())
+		}
+	}
+	return nil
+}
+
+// command returns the handler of the command topics.
+func (b *bridge) command(ctx context.Context) func(topic string, payload []byte) {
+	return func(topic string, payload []byte) {
+		level := strings.Split(strings.TrimPrefix(topic, b.prefix+"/"), "/")
+		if len(level) != 3 || level[2] != "set" {
+			return
+		}
+		s, ok := b.speakers[level[0]]
+		if !ok {
+			return
+		}
+		var e *entity
+		for i := range entities {
+			if entities[i].name == level[1] && !entities[i].readOnly {
+				e = &entities[i]
+			}
+		}
+		if e == nil {
+			return
+		}
+		// Not blocking the broker connection.
+		go func() {
+			if err := b.apply(ctx, s, e, strings.TrimSpace(string(payload))); err != nil {
+				fmt.Fprintf(os.Stderr, "error: %s: %s: %v\n", s.name, e.name, err)
+			}
+		}()
+	}
+}
+
+func (b *bridge) apply(ctx context.Context, s *bridgeSpeaker, e *entity, payload string) error {
+	ctx, cancel := context.WithTimeout(ctx, b.timeout)
+	defer cancel()
+
+	c, err := s.client(ctx)
+	if err != nil {
+		return err
+	}
+	caps, err := c.Capabilities(ctx)
+	if err == nil {
+		err = e.set(ctx, c, caps, payload)
+	}
+	if err == nil {
+		// Not all changes are broadcasted by the speaker.
+		err = b.refresh(ctx, s, c)
+	}
+	if err != nil && exitCode(err) == exitConnection {
+		s.close(c)
+	}
+	if errors.Is(err, context.Canceled) {
+		return nil
+	}
+	return err
+}
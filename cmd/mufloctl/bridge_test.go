@@ -0,0 +1,266 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mafredri/goodspeaker"
+
+	"github.com/mafredri/musicflow"
+	"github.com/mafredri/musicflow/api"
+)
+
+// fakeMusicFlow is a speaker on 127.0.0.1 with plain text messages, it
+// keeps the volume and mute state and broadcasts the changes.
+type fakeMusicFlow struct {
+	l net.Listener
+
+	mu       sync.Mutex
+	changed  chan struct{} // Closed and replaced on every request.
+	volume   int
+	mute     bool
+	requests map[string]int // Count by message.
+	conns    []*goodspeaker.Writer
+	closed   []net.Conn
+}
+
+func newFakeMusicFlow(t *testing.T, volume int) *fakeMusicFlow {
+	t.Helper()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &fakeMusicFlow{l: l, changed: make(chan struct{}), volume: volume, requests: make(map[string]int)}
+	go s.serve()
+	t.Cleanup(func() {
+		l.Close()
+		s.mu.Lock()
+		for _, conn := range s.closed {
+			conn.Close()
+		}
+		s.mu.Unlock()
+	})
+	return s
+}
+
+func (s *fakeMusicFlow) target(name string) target {
+	host, port, _ := net.SplitHostPort(s.l.Addr().String())
+	t := target{name: name}
+	t.Addr = host
+	t.Port, _ = strconv.Atoi(port)
+	return t
+}
+
+func (s *fakeMusicFlow) serve() {
+	for {
+		conn, err := s.l.Accept()
+		if err != nil {
+			return
+		}
+		w := goodspeaker.NewWriter(conn)
+		s.mu.Lock()
+		s.conns = append(s.conns, w)
+		s.closed = append(s.closed, conn)
+		s.mu.Unlock()
+		go s.handle(conn, w)
+	}
+}
+
+// send writes the message to w, or to all connections when w is nil.
+// The caller holds s.mu.
+func (s *fakeMusicFlow) send(w *goodspeaker.Writer, msg, result string, data interface{}) {
+	b, _ := json.Marshal(data)
+	p, _ := json.Marshal(musicflow.Response{Message: msg, Result: result, Data: b})
+	ws := s.conns
+	if w != nil {
+		ws = []*goodspeaker.Writer{w}
+	}
+	for _, w := range ws {
+		_, _ = w.Write(p)
+	}
+}
+
+// setMute changes the mute state on the speaker, like the remote.
+func (s *fakeMusicFlow) setMute(mute bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.mute = mute
+	s.send(nil, api.MessageMuteChange, "", api.MuteChangeEvent{Mute: mute})
+}
+
+func (s *fakeMusicFlow) handle(conn net.Conn, w *goodspeaker.Writer) {
+	defer conn.Close()
+	dec := json.NewDecoder(goodspeaker.NewReader(conn))
+	for {
+		var req musicflow.Response
+		if err := dec.Decode(&req); err != nil {
+			return
+		}
+		s.mu.Lock()
+		s.requests[req.Message]++
+		switch req.Message {
+		case api.MessageProductInfo:
+			s.send(w, req.Message, "OK", api.ProductInfo{
+				ModelName: "LAS750M",
+				Info: api.ProductInfoInfo{
+					Name:        "Living room",
+					Volume:      s.volume,
+					Mute:        s.mute,
+					WirelessMAC: "00:11:22:33:44:55",
+				},
+			})
+		case api.MessageSettingInfoRequest:
+			s.send(w, req.Message, "OK", api.Settings{NightMode: true})
+		case api.MessagePlayInfoRequest:
+			s.send(w, req.Message, "OK", api.PlayInfo{Title: "Song"})
+		case api.MessageVolumeSetting:
+			var v api.VolumeSettingRequest
+			_ = json.Unmarshal(req.Data, &v)
+			s.volume = v.Volume
+			// Like the speaker, the change is broadcast before the reply.
+			s.send(nil, api.MessageVolumeChange, "", api.VolumeChangeEvent{Volume: v.Volume})
+			s.send(w, req.Message, "OK", struct{}{})
+		case api.MessageMuteSet:
+			var v api.MuteSetRequest
+			_ = json.Unmarshal(req.Data, &v)
+			s.mute = v.Mute
+			s.send(nil, api.MessageMuteChange, "", api.MuteChangeEvent{Mute: v.Mute})
+			s.send(w, req.Message, "OK", struct{}{})
+		default:
+			s.send(w, req.Message, "OK", struct{}{})
+		}
+		close(s.changed)
+		s.changed = make(chan struct{})
+		s.mu.Unlock()
+	}
+}
+
+// waitRequests waits until the speaker has received n requests of the
+// message.
+func (s *fakeMusicFlow) waitRequests(t *testing.T, msg string, n int) {
+	t.Helper()
+	timeout := time.After(5 * time.Second)
+	for {
+		s.mu.Lock()
+		got, changed := s.requests[msg], s.changed
+		s.mu.Unlock()
+		if got >= n {
+			return
+		}
+		select {
+		case <-changed:
+		case <-timeout:
+			t.Fatalf("timeout waiting for %d %s requests, got %d", n, msg, got)
+		}
+	}
+}
+
+func (s *fakeMusicFlow) getVolume() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.volume
+}
+
+func TestBridge(t *testing.T) {
+	b := newFakeBroker(t, 0)
+	s := newFakeMusicFlow(t, 7)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	o := options{out: ioutil.Discard, targets: []target{s.target("Living room")}}
+	errC := make(chan error, 1)
+	go func() {
+		errC <- mqttCmd(ctx, o, []string{"-broker", b.addr(), "-timeout", "2s"})
+	}()
+
+	b.wait(t, "subscription", func() bool {
+		return len(b.filters) == 1 && b.filters[0] == "musicflow/+/+/set"
+	})
+	b.waitRetained(t, "musicflow/bridge/status", "online")
+	b.waitRetained(t, "musicflow/living_room/availability", "online")
+	for topic, want := range map[string]string{
+		"musicflow/living_room/volume":    "7",
+		"musicflow/living_room/mute":      "OFF",
+		"musicflow/living_room/nightmode": "ON",
+		"musicflow/living_room/title":     "Song",
+	} {
+		b.waitRetained(t, topic, want)
+	}
+
+	const configTopic = "homeassistant/number/musicflow_001122334455/volume/config"
+	var config struct {
+		StateTopic   string `json:"state_topic"`
+		CommandTopic string `json:"command_topic"`
+		Max          int    `json:"max"`
+	}
+	b.wait(t, configTopic, func() bool { return b.retained[configTopic] != "" })
+	b.mu.Lock()
+	err := json.Unmarshal([]byte(b.retained[configTopic]), &config)
+	_, eqConfig := b.retained["homeassistant/select/musicflow_001122334455/equalizer/config"]
+	b.mu.Unlock()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if config.StateTopic != "musicflow/living_room/volume" || config.CommandTopic != "musicflow/living_room/volume/set" || config.Max != maxVolume {
+		t.Errorf("volume config = %+v, want the volume topics and max %d", config, maxVolume)
+	}
+	if eqConfig {
+		t.Error("equalizer announced, the speaker has no equalizers")
+	}
+
+	// Commands are sent to the speaker, the new state is published.
+	// The state is refreshed after a command (the last request is
+	// PLAY_INFO_REQ).
+	b.send(t, "musicflow/living_room/volume/set", "12", 0)
+	b.waitRetained(t, "musicflow/living_room/volume", "12")
+	s.waitRequests(t, api.MessagePlayInfoRequest, 2)
+	if v := s.getVolume(); v != 12 {
+		t.Errorf("speaker volume = %d, want 12", v)
+	}
+	b.send(t, "musicflow/living_room/mute/set", "ON", 0)
+	b.waitRetained(t, "musicflow/living_room/mute", "ON")
+	s.waitRequests(t, api.MessagePlayInfoRequest, 3)
+
+	// Events from the speaker update the state.
+	s.setMute(false)
+	b.waitRetained(t, "musicflow/living_room/mute", "OFF")
+
+	cancel()
+	select {
+	case err := <-errC:
+		if err != context.Canceled {
+			t.Errorf("mqttCmd() error = %v, want context.Canceled", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("mqttCmd() did not return after cancel")
+	}
+	b.waitRetained(t, "musicflow/bridge/status", "offline")
+}
+
+func TestBridgePollAfterEvent(t *testing.T) {
+	b := &bridge{prefix: "musicflow"}
+	s := &bridgeSpeaker{id: "s", state: make(map[string]string), changed: make(map[string]uint64)}
+
+	// A refresh is started, an event arrives before its reply.
+	since := s.events
+	s.events++
+	b.set(s, "mute", "ON")
+	b.poll(s, since, "mute", "OFF")
+	b.poll(s, since, "volume", "7")
+	if s.state["mute"] != "ON" || s.state["volume"] != "7" {
+		t.Errorf("state = %v, want the mute event and the polled volume", s.state)
+	}
+
+	// A refresh started after the event is newer.
+	b.poll(s, s.events, "mute", "OFF")
+	if s.state["mute"] != "OFF" {
+		t.Errorf("mute = %s, want OFF", s.state["mute"])
+	}
+}
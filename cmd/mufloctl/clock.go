@@ -0,0 +1,46 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"time"
+)
+
+func clockCmd(ctx context.Context, o options, args []string) error {
+	fs := flag.NewFlagSet("clock", flag.ExitOnError)
+	tz := fs.String("tz", "Local", "IANA timezone `name`, e.g. Europe/Helsinki")
+	daemon := fs.Bool("daemon", false, "Keep running and resynchronize periodically and after DST transitions")
+	interval := fs.Duration("interval", 6*time.Hour, "Resynchronization interval in daemon mode")
+	_ = fs.Parse(args)
+
+	if *daemon && o.client != nil {
+		// Fan-out waits for all speakers to be done.
+		return errors.New("clock: -daemon cannot be used with -all or -group")
+	}
+
+	loc, err := time.LoadLocation(*tz)
+	if err != nil {
+		return err
+	}
+
+	c, err := o.dial(ctx)
+	if err != nil {
+		return err
+	}
+	defer o.close(c)
+
+	if *daemon {
+		fmt.Fprintf(o.out, "Synchronizing clock every %s (Ctrl+C to exit)...\n", *interval)
+		return c.ClockSync(ctx, loc, *interval)
+	}
+
+	now := time.Now().In(loc)
+	err = c.SyncClock(ctx, now)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(o.out, "Clock set to %s\n", now.Format("Mon 15:04 MST"))
+	return nil
+}
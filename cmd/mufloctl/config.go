@@ -0,0 +1,258 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/mafredri/musicflow"
+)
+
+// Environment variables that override the config file.
+const (
+	envConfig  = "MUFLOCTL_CONFIG"  // Config file path.
+	envSpeaker = "MUFLOCTL_SPEAKER" // Speaker name, same as -speaker.
+	envKey     = "MUFLOCTL_KEY"     // AES key, same as -key.
+	envIV      = "MUFLOCTL_IV"      // AES IV, same as -iv.
+)
+
+// config is the mufloctl config file, e.g.:
+//
+//	{
+//		"default": "livingroom",
+//		"defaults": {"output": "json"},
+//		"speakers": {
+//			"livingroom": {"addr": "soundbar.local"},
+//			"kitchen": {"addr": "192.168.1.20", "port": 9741},
+//			"bedroom": {}
+//		},
+//		"groups": {
+//			"downstairs": ["livingroom", "kitchen"]
+//		}
+//	}
+//
+// Speakers without addr are looked up by name in the registry (see
+// registry add), e.g. bedroom.
+type config struct {
+	Default  string                   `json:"default,omitempty"`  // Speaker used when none is selected.
+	Defaults speakerConfig            `json:"defaults,omitempty"` // Options for all speakers, addr is ignored.
+	Speakers map[string]speakerConfig `json:"speakers,omitempty"`
+	Groups   map[string][]string      `json:"groups,omitempty"` // Speaker names by group, see -group.
+
+	path string
+}
+
+// speakerConfig is a named speaker profile. Empty values use the
+// defaults, flags take precedence.
+type speakerConfig struct {
+	Addr    string `json:"addr,omitempty"` // Host address or IP, empty for the registry.
+	Port    int    `json:"port,omitempty"`
+	Key     string `json:"key,omitempty"` // AES key for encryption.
+	IV      string `json:"iv,omitempty"`  // IV for encryption.
+	Output  string `json:"output,omitempty"`
+	Verbose *bool  `json:"verbose,omitempty"` // Nil when not set, see verbose.
+}
+
+// verbose reports whether verbose output is enabled.
+func (sc speakerConfig) verbose() bool {
+	return sc.Verbose != nil && *sc.Verbose
+}
+
+func defaultConfigPath() string {
+	if path := os.Getenv(envConfig); path != "" {
+		return path
+	}
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "mufloctl.json"
+	}
+	return filepath.Join(dir, "mufloctl", "config.json")
+}
+
+// loadConfig loads the config file, a missing file is only an error
+// when required.
+func loadConfig(path string, required bool) (*config, error) {
+	cfg := &config{path: path}
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) && !required {
+			return cfg, nil
+		}
+		return nil, fmt.Errorf("config: %w", err)
+	}
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.DisallowUnknownFields()
+	if err = dec.Decode(cfg); err != nil {
+		return nil, fmt.Errorf("config: %s: %w", path, err)
+	}
+	if o := cfg.Defaults.Output; o != "" && !validOutput(o) {
+		return nil, fmt.Errorf("config: %s: defaults: unknown output format %q", path, o)
+	}
+	for name, sc := range cfg.Speakers {
+		if sc.Output != "" && !validOutput(sc.Output) {
+			return nil, fmt.Errorf("config: %s: speaker %q: unknown output format %q", path, name, sc.Output)
+		}
+	}
+	if cfg.Default != "" {
+		if _, ok := cfg.Speakers[cfg.Default]; !ok {
+			return nil, fmt.Errorf("config: %s: default speaker %q not found", path, cfg.Default)
+		}
+	}
+	for group, names := range cfg.Groups {
+		for _, name := range names {
+			if _, ok := cfg.Speakers[name]; !ok {
+				return nil, fmt.Errorf("config: %s: group %q: speaker %q not found", path, group, name)
+			}
+		}
+	}
+	return cfg, nil
+}
+
+// with returns sc overridden by the non-empty (non-nil) values in o.
+func (sc speakerConfig) with(o speakerConfig) speakerConfig {
+	if o.Addr != "" {
+		sc.Addr = o.Addr
+	}
+	if o.Port != 0 {
+		sc.Port = o.Port
+	}
+	if o.Key != "" {
+		sc.Key = o.Key
+	}
+	if o.IV != "" {
+		sc.IV = o.IV
+	}
+	if o.Output != "" {
+		sc.Output = o.Output
+	}
+	if o.Verbose != nil {
+		sc.Verbose = o.Verbose
+	}
+	return sc
+}
+
+// profile returns the options for the named speaker, or only the
+// defaults when name is empty. The config file overrides base (the
+// built-in defaults) and is overridden by over (flags and
+// environment).
+func (cfg *config) profile(name string, base, over speakerConfig) (speakerConfig, error) {
+	d := cfg.Defaults
+	d.Addr = ""
+	sc := base.with(d)
+	if name != "" {
+		s, ok := cfg.Speakers[name]
+		if !ok {
+			return sc, fmt.Errorf("unknown speaker %q (config %s)", name, cfg.path)
+		}
+		sc = sc.with(s)
+	}
+	return sc.with(over), nil
+}
+
+// resolve returns sc with the last known address of the named speaker
+// in the registry when sc has no address. The speaker is located (and
+// the registry updated) if it has moved, see Registry.DialByName.
+func resolve(ctx context.Context, name string, sc speakerConfig) (speakerConfig, error) {
+	if sc.Addr != "" {
+		return sc, nil
+	}
+	reg, err := musicflow.LoadRegistry(defaultRegistryPath())
+	if err != nil {
+		return sc, err
+	}
+	if _, ok := reg.Lookup(name); !ok {
+		return sc, fmt.Errorf("speaker %q has no addr and is not in the registry %s", name, defaultRegistryPath())
+	}
+	opt, err := dialOptions(sc.Key, sc.IV, sc.verbose())
+	if err != nil {
+		return sc, err
+	}
+	c, err := reg.DialByName(ctx, name, opt...)
+	if err != nil {
+		return sc, connError{err}
+	}
+	c.Close()
+
+	e, _ := reg.Lookup(name)
+	host, port, err := net.SplitHostPort(e.Addr)
+	if err != nil {
+		return sc, fmt.Errorf("registry: speaker %q: %w", name, err)
+	}
+	sc.Addr = host
+	sc.Port, _ = strconv.Atoi(port)
+	return sc, nil
+}
+
+// group returns the speaker names in group, or all speakers when
+// group is empty.
+func (cfg *config) group(group string) ([]string, error) {
+	if group == "" {
+		if len(cfg.Speakers) == 0 {
+			return nil, fmt.Errorf("no speakers in config %s", cfg.path)
+		}
+		return cfg.names(), nil
+	}
+	names, ok := cfg.Groups[group]
+	if !ok {
+		return nil, fmt.Errorf("unknown group %q (config %s)", group, cfg.path)
+	}
+	return names, nil
+}
+
+// names returns the speaker names, sorted.
+func (cfg *config) names() []string {
+	var names []string
+	for name := range cfg.Speakers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func configCmd(ctx context.Context, o options, args []string) error {
+	if len(args) != 1 || (args[0] != "list" && args[0] != "path") {
+		return errors.New("config: expected list or path")
+	}
+	if args[0] == "path" {
+		fmt.Fprintln(o.out, o.config.path)
+		return nil
+	}
+
+	groups := make(map[string][]string)
+	for group, names := range o.config.Groups {
+		for _, name := range names {
+			groups[name] = append(groups[name], group)
+		}
+	}
+
+	w := tabwriter.NewWriter(o.out, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "NAME\tADDRESS\tPORT\tGROUPS\tDEFAULT")
+	for _, name := range o.config.names() {
+		sc, _ := o.config.profile(name, speakerConfig{}, speakerConfig{})
+		port := "-"
+		if sc.Port != 0 {
+			port = fmt.Sprint(sc.Port)
+		}
+		addr := sc.Addr
+		if addr == "" {
+			addr = "(registry)"
+		}
+		sort.Strings(groups[name])
+		def := ""
+		if name == o.config.Default {
+			def = "*"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", name, addr, port, strings.Join(groups[name], ","), def)
+	}
+	return w.Flush()
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeConfig(t *testing.T, data string) string {
+	t.Helper()
+	dir, err := ioutil.TempDir("", "mufloctl")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	path := filepath.Join(dir, "config.json")
+	if err = ioutil.WriteFile(path, []byte(data), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadConfigOutput(t *testing.T) {
+	tests := []struct {
+		data string
+		want string
+	}{
+		{`{"defaults": {"output": "xml"}}`, `defaults: unknown output format "xml"`},
+		{`{"speakers": {"a": {"output": "xml"}}}`, `speaker "a": unknown output format "xml"`},
+		{`{"defaults": {"output": "json"}, "speakers": {"a": {"output": "yaml"}}}`, ""},
+	}
+	for _, tt := range tests {
+		_, err := loadConfig(writeConfig(t, tt.data), true)
+		if (err == nil) != (tt.want == "") || (err != nil && !strings.Contains(err.Error(), tt.want)) {
+			t.Errorf("loadConfig(%s) error = %v, want %q", tt.data, err, tt.want)
+		}
+	}
+}
+
+func TestProfileVerbose(t *testing.T) {
+	on, off := true, false
+	cfg, err := loadConfig(writeConfig(t, `{"defaults": {"verbose": true}, "speakers": {"a": {}}}`), true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		over *bool
+		want bool
+	}{
+		{nil, true},
+		{&on, true},
+		{&off, false}, // -v=false
+	}
+	for _, tt := range tests {
+		sc, err := cfg.profile("a", speakerConfig{}, speakerConfig{Verbose: tt.over})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if sc.verbose() != tt.want {
+			t.Errorf("profile() with -v %v: verbose = %v, want %v", tt.over != nil && *tt.over, sc.verbose(), tt.want)
+		}
+	}
+}
@@ -0,0 +1,500 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mafredri/musicflow"
+	"github.com/mafredri/musicflow/api"
+)
+
+func infoCmd(ctx context.Context, o options, args []string) error {
+	c, err := o.dial(ctx)
+	if err != nil {
+		return err
+	}
+	defer o.close(c)
+
+	info, err := c.ProductInfo(ctx, time.Now(), false)
+	if err != nil {
+		return err
+	}
+	fn, err := c.FunctionInfo(ctx)
+	if err != nil {
+		return err
+	}
+	eq, err := c.EqualizerInfo(ctx)
+	if err != nil {
+		return err
+	}
+	settings, err := c.Settings(ctx)
+	if err != nil {
+		return err
+	}
+
+	out := speakerInfo{
+		Name:           info.Info.Name,
+		Model:          info.ModelName,
+		ModelType:      info.ModelType.String(),
+		Firmware:       info.Info.BeVer,
+		Network:        info.Network.String(),
+		Volume:         info.Info.Volume,
+		Mute:           info.Info.Mute,
+		Input:          fn.Type.String(),
+		InputConnected: fn.Connect != 0,
+		Equalizer:      eq.CurrentEqualizer.String(),
+		Bass:           eq.Bass,
+		Treble:         eq.Treble,
+		NightMode:      settings.NightMode,
+		WooferLevel:    settings.WooferLevel,
+		WooferMax:      settings.WooferMax,
+		Playing:        info.Info.Playing,
+	}
+	return o.print(out, func(w io.Writer) {
+		fmt.Fprintf(w, "Name:\t%s\n", out.Name)
+		fmt.Fprintf(w, "Model:\t%s (%s)\n", out.Model, out.ModelType)
+		fmt.Fprintf(w, "Firmware:\t%s\n", out.Firmware)
+		fmt.Fprintf(w, "Network:\t%s\n", out.Network)
+		fmt.Fprintf(w, "Volume:\t%d\n", out.Volume)
+		fmt.Fprintf(w, "Mute:\t%s\n", onOff(out.Mute))
+		fmt.Fprintf(w, "Input:\t%s%s\n", out.Input, connected(fn))
+		fmt.Fprintf(w, "Equalizer:\t%s (bass %d, treble %d)\n", out.Equalizer, out.Bass, out.Treble)
+		fmt.Fprintf(w, "Night mode:\t%s\n", onOff(out.NightMode))
+		fmt.Fprintf(w, "Woofer:\t%d/%d\n", out.WooferLevel, out.WooferMax)
+		fmt.Fprintf(w, "Playing:\t%t\n", out.Playing)
+	})
+}
+
+// speakerInfo is the output of the info command, the enums are
+// represented by their names.
+type speakerInfo struct {
+	Name           string `json:"name"`
+	Model          string `json:"model"`
+	ModelType      string `json:"modelType"`
+	Firmware       string `json:"firmware"`
+	Network        string `json:"network"`
+	Volume         int    `json:"volume"`
+	Mute           bool   `json:"mute"`
+	Input          string `json:"input"`
+	InputConnected bool   `json:"inputConnected"`
+	Equalizer      string `json:"equalizer"`
+	Bass           int    `json:"bass"`
+	Treble         int    `json:"treble"`
+	NightMode      bool   `json:"nightMode"`
+	WooferLevel    int    `json:"wooferLevel"`
+	WooferMax      int    `json:"wooferMax"`
+	Playing        bool   `json:"playing"`
+}
+
+func settingsCmd(ctx context.Context, o options, args []string) error {
+	c, err := o.dial(ctx)
+	if err != nil {
+		return err
+	}
+	defer o.close(c)
+
+	settings, err := c.Settings(ctx)
+	if err != nil {
+		return err
+	}
+	return o.print(settings, nil)
+}
+
+func playInfoCmd(ctx context.Context, o options, args []string) error {
+	c, err := o.dial(ctx)
+	if err != nil {
+		return err
+	}
+	defer o.close(c)
+
+	info, err := c.PlayInfo(ctx)
+	if err != nil {
+		return err
+	}
+	return o.print(info, nil)
+}
+
+func versionCmd(ctx context.Context, o options, args []string) error {
+	c, err := o.dial(ctx)
+	if err != nil {
+		return err
+	}
+	defer o.close(c)
+
+	version, err := c.SystemVersion(ctx)
+	if err != nil {
+		return err
+	}
+	return o.print(version, nil)
+}
+
+func volumeCmd(ctx context.Context, o options, args []string) error {
+	if len(args) > 1 {
+		return errors.New("volume: expected [+|-]N")
+	}
+
+	c, err := o.dial(ctx)
+	if err != nil {
+		return err
+	}
+	defer o.close(c)
+
+	var volume int
+	switch {
+	case len(args) == 0:
+		info, err := c.ProductInfo(ctx, time.Now(), false)
+		if err != nil {
+			return err
+		}
+		volume = info.Info.Volume
+	case strings.HasPrefix(args[0], "+"), strings.HasPrefix(args[0], "-"):
+		step, err := strconv.Atoi(args[0][1:])
+		if err != nil || step < 0 {
+			return fmt.Errorf("volume: invalid step %q", args[0])
+		}
+		if args[0][0] == '+' {
+			volume, err = c.VolumeUp(ctx, step)
+		} else {
+			volume, err = c.VolumeDown(ctx, step)
+		}
+		if err != nil {
+			return err
+		}
+	default:
+		v, err := strconv.Atoi(args[0])
+		if err != nil {
+			return fmt.Errorf("volume: invalid volume %q", args[0])
+		}
+		volume, err = c.VolumeTo(ctx, v, 0, musicflow.CurveLinear)
+		if err != nil {
+			return err
+		}
+	}
+	fmt.Fprintf(o.out, "Volume: %d\n", volume)
+	return nil
+}
+
+func muteCmd(ctx context.Context, o options, args []string) error {
+	if len(args) > 1 {
+		return errors.New("mute: expected on, off or toggle")
+	}
+
+	c, err := o.dial(ctx)
+	if err != nil {
+		return err
+	}
+	defer o.close(c)
+
+	info, err := c.ProductInfo(ctx, time.Now(), false)
+	if err != nil {
+		return err
+	}
+	mute := info.Info.Mute
+	if len(args) == 1 {
+		if args[0] == "toggle" {
+			mute = !mute
+		} else if mute, err = parseOnOff(args[0]); err != nil {
+			return fmt.Errorf("mute: %v", err)
+		}
+		if err = c.Mute(ctx, mute); err != nil {
+			return err
+		}
+	}
+	fmt.Fprintf(o.out, "Mute: %s\n", onOff(mute))
+	return nil
+}
+
+func eqCmd(ctx context.Context, o options, args []string) error {
+	fs := flag.NewFlagSet("eq", flag.ExitOnError)
+	bass := fs.Int("bass", -1, "Bass level")
+	treble := fs.Int("treble", -1, "Treble level")
+	balance := fs.Int("balance", -1, "Left right balance")
+	save := fs.Bool("save", false, "Save the settings as default")
+	_ = fs.Parse(args)
+	// Allow flags after the preset, e.g. eq cinema -bass 3.
+	var preset string
+	if fs.NArg() > 0 {
+		preset = fs.Arg(0)
+		_ = fs.Parse(fs.Args()[1:])
+		if fs.NArg() > 0 {
+			return fmt.Errorf("eq: unexpected argument(s): %s", strings.Join(fs.Args(), " "))
+		}
+	}
+
+	c, err := o.dial(ctx)
+	if err != nil {
+		return err
+	}
+	defer o.close(c)
+
+	var eq []musicflow.EqualizerSetting
+	if preset != "" {
+		e, err := api.ParseEqualizer(preset)
+		if err != nil {
+			return fmt.Errorf("eq: %v", err)
+		}
+		eq = append(eq, musicflow.SetEqualizer(e))
+	}
+	if *bass >= 0 {
+		eq = append(eq, musicflow.SetBass(*bass))
+	}
+	if *treble >= 0 {
+		eq = append(eq, musicflow.SetTreble(*treble))
+	}
+	if *balance >= 0 {
+		eq = append(eq, musicflow.SetLeftRightBalance(*balance))
+	}
+	if *save {
+		eq = append(eq, musicflow.SaveEqualizerSettings())
+	}
+	if err = c.Equalizer(ctx, eq...); err != nil {
+		return err
+	}
+
+	info, err := c.EqualizerInfo(ctx)
+	if err != nil {
+		return err
+	}
+	caps, err := c.Capabilities(ctx)
+	if err != nil {
+		return err
+	}
+	out := equalizerInfo{
+		Equalizer: info.CurrentEqualizer.String(),
+		Bass:      info.Bass,
+		Treble:    info.Treble,
+		Balance:   info.LeftRightBalance,
+		Available: []string{},
+	}
+	for _, e := range caps.Equalizers {
+		out.Available = append(out.Available, e.String())
+	}
+	return o.print(out, func(w io.Writer) {
+		fmt.Fprintf(w, "Equalizer:\t%s\n", out.Equalizer)
+		fmt.Fprintf(w, "Bass:\t%d\n", out.Bass)
+		fmt.Fprintf(w, "Treble:\t%d\n", out.Treble)
+		fmt.Fprintf(w, "Balance:\t%d\n", out.Balance)
+		fmt.Fprintf(w, "Available:\t%s\n", strings.Join(out.Available, ", "))
+	})
+}
+
+// equalizerInfo is the output of the eq command.
+type equalizerInfo struct {
+	Equalizer string   `json:"equalizer"`
+	Bass      int      `json:"bass"`
+	Treble    int      `json:"treble"`
+	Balance   int      `json:"balance"`
+	Available []string `json:"available"`
+}
+
+func inputCmd(ctx context.Context, o options, args []string) error {
+	if len(args) > 1 {
+		return errors.New("input: expected input name, e.g. arc, bt or optical")
+	}
+
+	c, err := o.dial(ctx)
+	if err != nil {
+		return err
+	}
+	defer o.close(c)
+
+	caps, err := c.Capabilities(ctx)
+	if err != nil {
+		return err
+	}
+	if len(args) == 1 {
+		f, err := api.ParseFunction(args[0])
+		if err != nil {
+			return fmt.Errorf("input: %v", err)
+		}
+		if err = c.Function(ctx, resolveFunction(caps, f)); err != nil {
+			return err
+		}
+	}
+
+	fn, err := c.FunctionInfo(ctx)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(o.out, "Input: %s%s\n", fn.Type, connected(fn))
+	if len(args) == 0 {
+		fmt.Fprintf(o.out, "Available: %s\n", joinFunctions(caps.Functions))
+	}
+	return nil
+}
+
+// resolveFunction maps ARC and Optical to the combined Optical / HDMI
+// ARC input on speakers that only have the latter.
+func resolveFunction(caps *musicflow.Capabilities, f api.Function) api.Function {
+	if caps.HasFunction(f) {
+		return f
+	}
+	switch f {
+	case api.FunctionARC, api.FunctionOptical:
+		if caps.HasFunction(api.FunctionOpticalARC) {
+			return api.FunctionOpticalARC
+		}
+	}
+	return f
+}
+
+func nightModeCmd(ctx context.Context, o options, args []string) error {
+	if len(args) > 1 {
+		return errors.New("nightmode: expected on or off")
+	}
+
+	c, err := o.dial(ctx)
+	if err != nil {
+		return err
+	}
+	defer o.close(c)
+
+	var on bool
+	if len(args) == 1 {
+		if on, err = parseOnOff(args[0]); err != nil {
+			return fmt.Errorf("nightmode: %v", err)
+		}
+		if err = c.NightMode(ctx, on); err != nil {
+			return err
+		}
+	} else {
+		settings, err := c.Settings(ctx)
+		if err != nil {
+			return err
+		}
+		on = settings.NightMode
+	}
+	fmt.Fprintf(o.out, "Night mode: %s\n", onOff(on))
+	return nil
+}
+
+func wooferCmd(ctx context.Context, o options, args []string) error {
+	if len(args) > 1 {
+		return errors.New("woofer: expected level")
+	}
+
+	c, err := o.dial(ctx)
+	if err != nil {
+		return err
+	}
+	defer o.close(c)
+
+	if len(args) == 1 {
+		level, err := strconv.Atoi(args[0])
+		if err != nil {
+			return fmt.Errorf("woofer: invalid level %q", args[0])
+		}
+		if err = c.WooferLevel(ctx, level); err != nil {
+			return err
+		}
+	}
+	settings, err := c.Settings(ctx)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(o.out, "Woofer: %d/%d\n", settings.WooferLevel, settings.WooferMax)
+	return nil
+}
+
+func sleepCmd(ctx context.Context, o options, args []string) error {
+	if len(args) > 1 {
+		return errors.New("sleep: expected minutes, duration (e.g. 1h30m) or off")
+	}
+
+	c, err := o.dial(ctx)
+	if err != nil {
+		return err
+	}
+	defer o.close(c)
+
+	if len(args) == 1 {
+		minutes := -1
+		if args[0] != "off" {
+			minutes, err = parseMinutes(args[0])
+			if err != nil {
+				return fmt.Errorf("sleep: %v", err)
+			}
+		}
+		if err = c.SleepAfter(ctx, minutes); err != nil {
+			return err
+		}
+	}
+	timer, err := c.SleepInfo(ctx)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(o.out, "Sleep timer: %s\n", timer)
+	return nil
+}
+
+// parseMinutes parses minutes or a duration, rounded up to minutes.
+func parseMinutes(s string) (int, error) {
+	if n, err := strconv.Atoi(s); err == nil && n > 0 {
+		return n, nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil || d <= 0 {
+		return 0, fmt.Errorf("invalid time %q", s)
+	}
+	return int((d + time.Minute - 1) / time.Minute), nil
+}
+
+func nameCmd(ctx context.Context, o options, args []string) error {
+	c, err := o.dial(ctx)
+	if err != nil {
+		return err
+	}
+	defer o.close(c)
+
+	if len(args) > 0 {
+		if err = c.SetName(ctx, strings.Join(args, " ")); err != nil {
+			return err
+		}
+	}
+	info, err := c.ProductInfo(ctx, time.Now(), false)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(o.out, "Name: %s\n", info.Info.Name)
+	return nil
+}
+
+func parseOnOff(s string) (bool, error) {
+	switch strings.ToLower(s) {
+	case "on", "true", "1", "yes":
+		return true, nil
+	case "off", "false", "0", "no":
+		return false, nil
+	default:
+		return false, fmt.Errorf("expected on or off, got %q", s)
+	}
+}
+
+func onOff(on bool) string {
+	if on {
+		return "on"
+	}
+	return "off"
+}
+
+func connected(fn *api.FunctionInfo) string {
+	if fn.Connect != 0 {
+		return " (connected)"
+	}
+	return ""
+}
+
+// joinFunctions joins the names of the functions.
+func joinFunctions(functions []api.Function) string {
+	var names []string
+	for _, f := range functions {
+		names = append(names, f.String())
+	}
+	return strings.Join(names, ", ")
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"text/tabwriter"
+	"time"
+
+	"github.com/mafredri/musicflow"
+)
+
+func discoverCmd(ctx context.Context, o options, args []string) error {
+	fs := flag.NewFlagSet("discover", flag.ExitOnError)
+	timeout := fs.Duration("timeout", 2*time.Second, "Time spent on each host")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: discover [options] [host ...]\n\n")
+		fs.PrintDefaults()
+	}
+	_ = fs.Parse(args)
+
+	dopt, err := dialOptions(key, iv, o.verbose)
+	if err != nil {
+		return err
+	}
+	opts := []musicflow.DiscoverOption{
+		musicflow.WithDiscoverPort(o.port),
+		musicflow.WithDiscoverTimeout(*timeout),
+		musicflow.WithDiscoverDialOption(dopt...),
+	}
+	if fs.NArg() > 0 {
+		opts = append(opts, musicflow.WithDiscoverHosts(fs.Args()...))
+	}
+
+	speakers, err := musicflow.Discover(ctx, opts...)
+	if err != nil {
+		return err
+	}
+	if len(speakers) == 0 {
+		fmt.Fprintln(o.out, "No speakers found.")
+		return nil
+	}
+
+	w := tabwriter.NewWriter(o.out, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "ADDRESS\tNAME\tMODEL\tPET NAME\tMAC")
+	for _, s := range speakers {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", s.Addr, s.Name, s.ModelName, s.PetName, s.WirelessMAC)
+	}
+	return w.Flush()
+}
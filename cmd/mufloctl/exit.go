@@ -0,0 +1,43 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+
+	"github.com/mafredri/musicflow"
+)
+
+// Exit codes, documented in usage.
+const (
+	exitError       = 1 // Other errors.
+	exitUsage       = 2 // Invalid command line, same as package flag.
+	exitConnection  = 3 // The speaker could not be reached or the connection was lost.
+	exitUnsupported = 4 // The speaker does not support the feature.
+	exitProtocol    = 5 // The speaker rejected the request or sent an unexpected reply.
+)
+
+// connError marks errors that occurred while connecting to the speaker.
+type connError struct{ err error }
+
+func (e connError) Error() string { return e.err.Error() }
+func (e connError) Unwrap() error { return e.err }
+
+// exitCode returns the exit code for the error.
+func exitCode(err error) int {
+	var ce connError
+	var ne net.Error
+	switch {
+	case errors.Is(err, musicflow.ErrUnsupported):
+		return exitUnsupported
+	case errors.Is(err, musicflow.ErrProtocol):
+		return exitProtocol
+	case errors.As(err, &ce), errors.As(err, &ne),
+		errors.Is(err, io.EOF), errors.Is(err, io.ErrUnexpectedEOF),
+		errors.Is(err, context.DeadlineExceeded):
+		return exitConnection
+	default:
+		return exitError
+	}
+}
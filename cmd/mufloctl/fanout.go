@@ -0,0 +1,151 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"strconv"
+	"time"
+
+	"github.com/mafredri/musicflow"
+)
+
+// fanOutCommands can be run on several speakers (-all or -group),
+// commands that power off or reset the speaker are left out on purpose.
+var fanOutCommands = map[string]bool{
+	"alarms":    true,
+	"clock":     true,
+	"eq":        true,
+	"info":      true,
+	"input":     true,
+	"mute":      true,
+	"nightmode": true,
+	"playinfo":  true,
+	"settings":  true,
+	"sleep":     true,
+	"version":   true,
+	"volume":    true,
+	"woofer":    true,
+}
+
+// targetCommands handle -all and -group themselves, see
+// options.targets.
+var targetCommands = map[string]bool{
+	"mqtt":  true,
+	"serve": true,
+}
+
+// target is a speaker from the config file.
+type target struct {
+	name string
+	speakerConfig
+}
+
+// resolveTargets returns the targets with the addresses of the speakers
+// without address in the config, see resolve.
+func resolveTargets(ctx context.Context, targets []target) ([]target, error) {
+	resolved := make([]target, 0, len(targets))
+	for _, t := range targets {
+		sc, err := resolve(ctx, t.name, t.speakerConfig)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", t.name, err)
+		}
+		resolved = append(resolved, target{name: t.name, speakerConfig: sc})
+	}
+	return resolved, nil
+}
+
+// fanOutResult is the output of a command run on several speakers
+// with -output json or yaml.
+type fanOutResult struct {
+	Speaker  string      `json:"speaker"`
+	Addr     string      `json:"addr"`
+	OK       bool        `json:"ok"`
+	Error    string      `json:"error,omitempty"`
+	Duration string      `json:"duration"`
+	Output   interface{} `json:"output,omitempty"` // JSON output of the command as is, otherwise the text.
+}
+
+// fanOutRun runs the command on the targets concurrently and reports
+// the output of each speaker, in order, when all are done. Returns a
+// *musicflow.FanOutError when the command failed on any speaker.
+func fanOutRun(ctx context.Context, o options, targets []target, concurrency int, run func(ctx context.Context, o options, args []string) error, args []string) error {
+	var mtargets []musicflow.Target
+	outputs := make(map[string]*bytes.Buffer)
+	width := 0
+	targets, err := resolveTargets(ctx, targets)
+	if err != nil {
+		return err
+	}
+	for _, t := range targets {
+		opt, err := dialOptions(t.Key, t.IV, t.verbose())
+		if err != nil {
+			return fmt.Errorf("%s: %w", t.name, err)
+		}
+		mtargets = append(mtargets, musicflow.Target{
+			Name:    t.name,
+			Addr:    net.JoinHostPort(t.Addr, strconv.Itoa(t.Port)),
+			Options: opt,
+		})
+		outputs[t.name] = new(bytes.Buffer)
+		if len(t.name) > width {
+			width = len(t.name)
+		}
+	}
+
+	report := musicflow.FanOut(ctx, mtargets, func(ctx context.Context, t musicflow.Target, c *musicflow.Client) error {
+		if _, err := c.ProductInfo(ctx, time.Now(), true); err != nil {
+			return connError{err}
+		}
+		to := o
+		to.addr, to.client, to.out = t.Addr, c, outputs[t.Name]
+		if o.output != "table" {
+			to.output = "json" // Embedded in the report.
+		}
+		return run(ctx, to, args)
+	}, musicflow.WithFanOutConcurrency(concurrency))
+
+	if o.output == "table" {
+		for _, res := range report {
+			name := res.Target.Name
+			if res.Err != nil {
+				fmt.Fprintf(o.out, "%-*s  error: %v\n", width+1, name+":", res.Err)
+				continue
+			}
+			s := bufio.NewScanner(outputs[name])
+			for s.Scan() {
+				fmt.Fprintf(o.out, "%-*s  %s\n", width+1, name+":", s.Text())
+			}
+		}
+		return report.Err()
+	}
+
+	var results []fanOutResult
+	for _, res := range report {
+		r := fanOutResult{
+			Speaker:  res.Target.Name,
+			Addr:     res.Target.Addr,
+			OK:       res.Err == nil,
+			Duration: res.Duration.Round(time.Millisecond).String(),
+		}
+		if res.Err != nil {
+			r.Error = res.Err.Error()
+		}
+		b := bytes.TrimSpace(outputs[res.Target.Name].Bytes())
+		switch {
+		case len(b) == 0:
+		case json.Valid(b):
+			r.Output = json.RawMessage(b)
+		default:
+			r.Output = string(b)
+		}
+		results = append(results, r)
+	}
+	if err := o.print(results, nil); err != nil {
+		return err
+	}
+	return report.Err()
+}
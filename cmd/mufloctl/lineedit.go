@@ -0,0 +1,297 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// lineEditor reads lines from a terminal in raw mode with basic
+// emacs-style editing, history and tab completion. Without a
+// terminal it reads plain lines.
+type lineEditor struct {
+	in       *bufio.Reader
+	out      io.Writer
+	prompt   string
+	complete func(line string) (start int, candidates []string)
+	raw      bool // Terminal is in raw mode.
+
+	mu      sync.Mutex // Protects the fields below and the output.
+	buf     []rune
+	pos     int
+	reading bool // Prompt is displayed.
+
+	history []string
+	hpos    int    // Position in history while browsing.
+	saved   []rune // Line being edited before browsing history.
+}
+
+// newLineEditor puts stdin in raw mode when it is a terminal, restore
+// must be called before exiting.
+func newLineEditor(prompt string, complete func(string) (int, []string)) (ed *lineEditor, restore func()) {
+	ed = &lineEditor{
+		in:       bufio.NewReader(os.Stdin),
+		out:      os.Stdout,
+		prompt:   prompt,
+		complete: complete,
+	}
+	restore, err := makeRaw(int(os.Stdin.Fd()))
+	if err != nil {
+		return ed, func() {}
+	}
+	ed.raw = true
+	return ed, restore
+}
+
+// printf writes the output above the line being edited.
+func (ed *lineEditor) printf(format string, a ...interface{}) {
+	ed.mu.Lock()
+	defer ed.mu.Unlock()
+
+	s := strings.TrimSuffix(fmt.Sprintf(format, a...), "\n")
+	if ed.raw {
+		// Terminal output processing is kept, but the line
+		// being edited must be cleared first.
+		s = strings.Replace(s, "\n", "\r\n", -1)
+		if ed.reading {
+			fmt.Fprint(ed.out, "\r\x1b[K")
+		}
+	}
+	fmt.Fprint(ed.out, s+"\n")
+	if ed.raw && ed.reading {
+		ed.refresh()
+	}
+}
+
+// readLine reads a line, io.EOF is returned on Ctrl+D (empty line)
+// or when the input is closed.
+func (ed *lineEditor) readLine() (string, error) {
+	if !ed.raw {
+		line, err := ed.in.ReadString('\n')
+		if err != nil && (err != io.EOF || line == "") {
+			return "", err
+		}
+		return strings.TrimRight(line, "\r\n"), nil
+	}
+
+	ed.mu.Lock()
+	ed.buf, ed.pos, ed.reading = nil, 0, true
+	ed.hpos, ed.saved = len(ed.history), nil
+	ed.refresh()
+	ed.mu.Unlock()
+
+	for {
+		r, _, err := ed.in.ReadRune()
+		if err != nil {
+			ed.done("\r\n")
+			return "", err
+		}
+
+		ed.mu.Lock()
+		switch r {
+		case '\r', '\n':
+			line := string(ed.buf)
+			ed.addHistory(line)
+			ed.mu.Unlock()
+			ed.done("\r\n")
+			return line, nil
+		case 3: // Ctrl+C, discard the line.
+			ed.buf, ed.pos = nil, 0
+			fmt.Fprint(ed.out, "^C\r\n")
+		case 4: // Ctrl+D.
+			if len(ed.buf) == 0 {
+				ed.mu.Unlock()
+				ed.done("\r\n")
+				return "", io.EOF
+			}
+			ed.delete(ed.pos, ed.pos+1)
+		case 1: // Ctrl+A.
+			ed.pos = 0
+		case 5: // Ctrl+E.
+			ed.pos = len(ed.buf)
+		case 2: // Ctrl+B.
+			ed.move(-1)
+		case 6: // Ctrl+F.
+			ed.move(1)
+		case 8, 127: // Backspace.
+			if ed.pos > 0 {
+				ed.delete(ed.pos-1, ed.pos)
+			}
+		case 11: // Ctrl+K.
+			ed.delete(ed.pos, len(ed.buf))
+		case 21: // Ctrl+U.
+			ed.delete(0, ed.pos)
+		case 23: // Ctrl+W, delete the previous word.
+			i := ed.pos
+			for i > 0 && ed.buf[i-1] == ' ' {
+				i--
+			}
+			for i > 0 && ed.buf[i-1] != ' ' {
+				i--
+			}
+			ed.delete(i, ed.pos)
+		case 12: // Ctrl+L.
+			fmt.Fprint(ed.out, "\x1b[H\x1b[2J")
+		case 16: // Ctrl+P.
+			ed.browse(-1)
+		case 14: // Ctrl+N.
+			ed.browse(1)
+		case '\t':
+			ed.completeLine()
+		case 27: // Escape sequence.
+			ed.mu.Unlock()
+			seq := ed.readEscape()
+			ed.mu.Lock()
+			switch seq {
+			case "[A", "OA":
+				ed.browse(-1)
+			case "[B", "OB":
+				ed.browse(1)
+			case "[C", "OC":
+				ed.move(1)
+			case "[D", "OD":
+				ed.move(-1)
+			case "[H", "OH", "[1~", "[7~":
+				ed.pos = 0
+			case "[F", "OF", "[4~", "[8~":
+				ed.pos = len(ed.buf)
+			case "[3~":
+				ed.delete(ed.pos, ed.pos+1)
+			}
+		default:
+			if r >= ' ' {
+				ed.insert(string(r))
+			}
+		}
+		ed.refresh()
+		ed.mu.Unlock()
+	}
+}
+
+// readEscape reads the rest of an escape sequence, e.g. "[A".
+func (ed *lineEditor) readEscape() string {
+	var seq []rune
+	for {
+		r, _, err := ed.in.ReadRune()
+		if err != nil {
+			return string(seq)
+		}
+		seq = append(seq, r)
+		switch {
+		case len(seq) == 1 && r != '[' && r != 'O':
+			return string(seq)
+		case len(seq) > 1 && (r == '~' || ('A' <= r && r <= 'Z') || ('a' <= r && r <= 'z')):
+			return string(seq)
+		case len(seq) > 8:
+			return string(seq)
+		}
+	}
+}
+
+func (ed *lineEditor) done(s string) {
+	ed.mu.Lock()
+	defer ed.mu.Unlock()
+	ed.reading = false
+	fmt.Fprint(ed.out, s)
+}
+
+// refresh redraws the prompt and line, must be called with mu held.
+func (ed *lineEditor) refresh() {
+	fmt.Fprintf(ed.out, "\r%s%s\x1b[K", ed.prompt, string(ed.buf))
+	if n := len(ed.buf) - ed.pos; n > 0 {
+		fmt.Fprintf(ed.out, "\x1b[%dD", n)
+	}
+}
+
+func (ed *lineEditor) move(n int) {
+	ed.pos += n
+	if ed.pos < 0 {
+		ed.pos = 0
+	}
+	if ed.pos > len(ed.buf) {
+		ed.pos = len(ed.buf)
+	}
+}
+
+func (ed *lineEditor) insert(s string) {
+	r := []rune(s)
+	buf := make([]rune, 0, len(ed.buf)+len(r))
+	buf = append(buf, ed.buf[:ed.pos]...)
+	buf = append(buf, r...)
+	ed.buf = append(buf, ed.buf[ed.pos:]...)
+	ed.pos += len(r)
+}
+
+func (ed *lineEditor) delete(from, to int) {
+	if to > len(ed.buf) {
+		to = len(ed.buf)
+	}
+	if from >= to {
+		return
+	}
+	ed.buf = append(ed.buf[:from:from], ed.buf[to:]...)
+	ed.pos = from
+}
+
+func (ed *lineEditor) addHistory(line string) {
+	if strings.TrimSpace(line) == "" {
+		return
+	}
+	if n := len(ed.history); n > 0 && ed.history[n-1] == line {
+		return
+	}
+	ed.history = append(ed.history, line)
+}
+
+// browse moves through the history, dir is -1 (older) or 1 (newer).
+func (ed *lineEditor) browse(dir int) {
+	i := ed.hpos + dir
+	if i < 0 || i > len(ed.history) {
+		return
+	}
+	if ed.hpos == len(ed.history) {
+		ed.saved = ed.buf
+	}
+	ed.hpos = i
+	if i == len(ed.history) {
+		ed.buf = ed.saved
+	} else {
+		ed.buf = []rune(ed.history[i])
+	}
+	ed.pos = len(ed.buf)
+}
+
+// completeLine completes the word before the cursor. A single
+// candidate is inserted, otherwise the common prefix is inserted or
+// the candidates are listed.
+func (ed *lineEditor) completeLine() {
+	if ed.complete == nil {
+		return
+	}
+	start, candidates := ed.complete(string(ed.buf[:ed.pos]))
+	if len(candidates) == 0 {
+		return
+	}
+	word := string(ed.buf[start:ed.pos])
+	prefix := candidates[0]
+	for _, c := range candidates[1:] {
+		for !strings.HasPrefix(c, prefix) {
+			prefix = prefix[:len(prefix)-1]
+		}
+	}
+	if len(candidates) == 1 && !strings.HasSuffix(prefix, "=") {
+		prefix += " "
+	}
+	if len(prefix) > len(word) || len(candidates) == 1 {
+		ed.delete(start, ed.pos)
+		ed.insert(prefix)
+		return
+	}
+
+	sort.Strings(candidates)
+	fmt.Fprint(ed.out, "\r\n"+strings.Join(candidates, "  ")+"\r\n")
+}
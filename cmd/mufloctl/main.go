@@ -1,21 +1,24 @@
 package main
 
 import (
-	"bufio"
 	"context"
-	"encoding/json"
 	"errors"
 	"flag"
 	"fmt"
 	"io"
 	"log"
+	"net"
 	"os"
 	"os/signal"
+	"sort"
+	"strconv"
 	"syscall"
+	"text/tabwriter"
 	"time"
 
 	"github.com/mafredri/goodspeaker"
 	"github.com/mafredri/musicflow"
+	"github.com/mafredri/musicflow/api"
 )
 
 var (
@@ -23,19 +26,183 @@ var (
 	iv  = "54eRty@hkL,;/y9U"
 )
 
+// command is a mufloctl subcommand.
+type command struct {
+	args string // Usage of arguments.
+	help string // Short description.
+	run  func(ctx context.Context, o options, args []string) error
+}
+
+var commands = map[string]command{
+	"alarm":     {"list|add|rm|enable|disable", "Manage alarms", alarmCmd},
+	"alarms":    {"", "List alarms, same as alarm list", alarmsCmd},
+	"config":    {"list|path", "Show the speakers in the config file", configCmd},
+	"clock":     {"[-tz name] [-daemon]", "Synchronize the speaker clock and timezone", clockCmd},
+	"discover":  {"[host ...]", "Find speakers on the local network", discoverCmd},
+	"eq":        {"[preset] [-bass n] [-treble n] [-balance n] [-save]", "Show or change the equalizer", eqCmd},
+	"info":      {"", "Show the speaker status", infoCmd},
+	"input":     {"[arc|bt|optical|...]", "Show or change the input", inputCmd},
+	"mqtt":      {"[-broker addr] [-prefix topic] [-discovery-prefix topic]", "Bridge the speaker(s) to MQTT and Home Assistant", mqttCmd},
+	"mute":      {"[on|off|toggle]", "Show or change mute", muteCmd},
+	"name":      {"[name]", "Show or change the speaker name", nameCmd},
+	"nightmode": {"[on|off]", "Show or change night mode", nightModeCmd},
+	"playinfo":  {"", "Show what is playing", playInfoCmd},
+	"poweroff":  {"[-snapshot file]", "Power off the speaker", poweroffCmd},
+	"serve":     {"[-listen addr] [-timeout d] [-history n]", "Serve a REST gateway to the speaker(s)", serveCmd},
+	"registry":  {"list|add|rm|resolve [name]", "Manage known speakers by MAC address", registryCmd},
+	"reset":     {"-yes-really [-snapshot file]", "Reset the speaker to factory settings", resetCmd},
+	"settings":  {"", "Show the speaker settings", settingsCmd},
+	"setup":     {"-name name [-ssid ssid] [-tz name]", "Set up a freshly reset speaker", setupCmd},
+	"sleep":     {"[minutes|duration|off]", "Show or set the sleep timer", sleepCmd},
+	"update":    {"[-check]", "Update the speaker firmware", updateCmd},
+	"version":   {"", "Show the firmware versions", versionCmd},
+	"volume":    {"[[+|-]N]", "Show or change the volume", volumeCmd},
+	"watch":     {"[-filter messages] [-json]", "Show a timeline of the speaker events", watchCmd},
+	"woofer":    {"[level]", "Show or change the woofer level", wooferCmd},
+}
+
+// options are the global options shared by all commands.
+type options struct {
+	addr    string // Empty when not provided.
+	port    int
+	verbose bool
+	output  string // Output format of read commands, see outputFormats.
+	speaker string // Name of the selected speaker, if any.
+	config  *config
+	out     io.Writer // Stdout of the command.
+
+	client  *musicflow.Client // Connected by fan-out, see dial.
+	targets []target          // Selected by -all or -group, see targetCommands.
+}
+
+func usage() {
+	fmt.Fprintf(flag.CommandLine.Output(), "Usage: %s [options] [command [args]]\n\n", os.Args[0])
+	fmt.Fprintf(flag.CommandLine.Output(), "Without command, an interactive shell is started (requests are read\nfrom stdin), type help for usage.\n\nCommands:\n")
+	var names []string
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	w := tabwriter.NewWriter(flag.CommandLine.Output(), 0, 4, 2, ' ', 0)
+	for _, name := range names {
+		fmt.Fprintf(w, "  %s %s\t%s\n", name, commands[name].args, commands[name].help)
+	}
+	w.Flush()
+	fmt.Fprintf(flag.CommandLine.Output(), "\nOptions:\n")
+	flag.PrintDefaults()
+	fmt.Fprintf(flag.CommandLine.Output(), `
+Exit codes:
+  1  error
+  2  invalid usage
+  3  connection to the speaker failed
+  4  feature not supported by the speaker
+  5  protocol error (request rejected or unexpected reply)
+
+Environment:
+  %-22s  config file, same as -config
+  %-22s  speaker name, same as -speaker
+  %-22s  AES key, same as -key
+  %-22s  IV, same as -iv
+  %-22s  MQTT password, same as mqtt -password
+`, envConfig, envSpeaker, envKey, envIV, envMQTTPassword)
+}
+
 func main() {
 	host := flag.String("addr", "", "Host address or IP of the speaker")
 	port := flag.Int("port", 9741, "Port of the speaker")
 	flag.StringVar(&key, "key", key, "AES key for encryption")
 	flag.StringVar(&iv, "iv", iv, "IV for encryption")
 	doTest := flag.Bool("test", false, "Perform a communication test with the speaker")
+	verbose := flag.Bool("v", false, "Log the communication with the speaker")
+	output := flag.String("output", "table", "Output `format` of read commands (table, json or yaml)")
+	configPath := flag.String("config", defaultConfigPath(), "Config `file` with named speakers")
+	speaker := flag.String("speaker", os.Getenv(envSpeaker), "Use the speaker `name` from the config file")
+	all := flag.Bool("all", false, "Run the command on all speakers in the config file")
+	group := flag.String("group", "", "Run the command on the speakers in the config file `group`")
+	concurrency := flag.Int("concurrency", 8, "Maximum `number` of speakers operated on at the same time (-all or -group)")
 
+	flag.Usage = usage
 	flag.Parse()
 
-	if *host == "" {
-		fmt.Print("error: speaker address must be provided\n\n")
+	// Flags take precedence over the environment, the environment
+	// over the config file.
+	over := speakerConfig{Key: os.Getenv(envKey), IV: os.Getenv(envIV)}
+	requireConfig := os.Getenv(envConfig) != ""
+	speakerFlag := false // The environment default is ignored by -all and -group.
+	flag.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "config":
+			requireConfig = true
+		case "speaker":
+			speakerFlag = true
+		case "addr":
+			over.Addr = *host
+		case "port":
+			over.Port = *port
+		case "key":
+			over.Key = key
+		case "iv":
+			over.IV = iv
+		case "output":
+			over.Output = *output
+		case "v":
+			v := *verbose
+			over.Verbose = &v
+		}
+	})
+	base := speakerConfig{Port: *port, Key: key, IV: iv, Output: *output}
+
+	cfg, err := loadConfig(*configPath, requireConfig)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		os.Exit(exitUsage)
+	}
+	fanOut := *all || *group != ""
+	if fanOut && !speakerFlag {
+		*speaker = ""
+	}
+	if fanOut && (*speaker != "" || over.Addr != "") {
+		fmt.Fprint(os.Stderr, "error: -all and -group cannot be combined with -addr or -speaker\n\n")
+		flag.Usage()
+		os.Exit(exitUsage)
+	}
+	if *speaker == "" && over.Addr == "" && !fanOut {
+		*speaker = cfg.Default
+	}
+	sc, err := cfg.profile(*speaker, base, over)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		os.Exit(exitUsage)
+	}
+	*host, *port, key, iv, *output, *verbose = sc.Addr, sc.Port, sc.Key, sc.IV, sc.Output, sc.verbose()
+
+	var cmd command
+	if flag.NArg() > 0 {
+		var ok bool
+		cmd, ok = commands[flag.Arg(0)]
+		if !ok {
+			fmt.Fprintf(os.Stderr, "error: unknown command %q\n\n", flag.Arg(0))
+			flag.Usage()
+			os.Exit(exitUsage)
+		}
+	}
+
+	if !validOutput(*output) {
+		fmt.Fprintf(os.Stderr, "error: unknown output format %q\n\n", *output)
 		flag.Usage()
-		os.Exit(1)
+		os.Exit(exitUsage)
+	}
+
+	if fanOut && !fanOutCommands[flag.Arg(0)] && !targetCommands[flag.Arg(0)] {
+		fmt.Fprintf(os.Stderr, "error: command %q cannot be used with -all or -group\n\n", flag.Arg(0))
+		flag.Usage()
+		os.Exit(exitUsage)
+	}
+
+	if cmd.run == nil && *host == "" && *speaker == "" {
+		fmt.Fprint(os.Stderr, "error: speaker address must be provided (-addr or -speaker)\n\n")
+		flag.Usage()
+		os.Exit(exitUsage)
 	}
 
 	ctx, cancel := context.WithCancel(context.Background())
@@ -43,12 +210,59 @@ func main() {
 	signal.Notify(ch, os.Interrupt, syscall.SIGTERM)
 	go func() {
 		for sig := range ch {
-			fmt.Printf("Received %s, exiting...\n", sig.String())
+			fmt.Fprintf(os.Stderr, "Received %s, exiting...\n", sig.String())
 			cancel()
 		}
 	}()
 
+	if cmd.run == nil && *host == "" {
+		sc, err = resolve(ctx, *speaker, sc)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error: %v\n", err)
+			os.Exit(exitCode(err))
+		}
+		*host, *port = sc.Addr, sc.Port
+	}
+
 	addr := fmt.Sprintf("%s:%d", *host, *port)
+	if cmd.run != nil {
+		o := options{
+			port:    *port,
+			verbose: *verbose,
+			output:  *output,
+			speaker: *speaker,
+			config:  cfg,
+			out:     os.Stdout,
+		}
+		if *host != "" {
+			o.addr = addr
+		}
+		run := cmd.run
+		if fanOut {
+			names, err := cfg.group(*group)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "error: %v\n", err)
+				os.Exit(exitUsage)
+			}
+			var targets []target
+			for _, name := range names {
+				sc, _ := cfg.profile(name, base, over)
+				targets = append(targets, target{name: name, speakerConfig: sc})
+			}
+			if targetCommands[flag.Arg(0)] {
+				o.targets = targets
+			} else {
+				run = func(ctx context.Context, o options, args []string) error {
+					return fanOutRun(ctx, o, targets, *concurrency, cmd.run, args)
+				}
+			}
+		}
+		if err := run(ctx, o, flag.Args()[1:]); err != nil && !errors.Is(err, context.Canceled) {
+			fmt.Fprintf(os.Stderr, "error: %v\n", err)
+			os.Exit(exitCode(err))
+		}
+		return
+	}
 	if *doTest {
 		if err := testRun(ctx, addr, key, iv); err != nil && !errors.Is(err, context.Canceled) {
 			panic(err)
@@ -56,76 +270,94 @@ func main() {
 		return
 	}
 
-	if err := run(ctx, addr, key, iv); err != nil && !errors.Is(err, context.Canceled) {
-		panic(err)
+	if err := repl(ctx, addr, key, iv, *verbose); err != nil && !errors.Is(err, context.Canceled) {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		os.Exit(exitCode(err))
 	}
 }
 
-func run(ctx context.Context, addr, key, iv string) error {
-	ctx, cancel := context.WithCancel(ctx)
-	defer cancel()
-
-	log.Printf("Connecting to %s...", addr)
+// dial connects to the speaker at addr, verbose enables logging of
+// the communication.
+func dial(ctx context.Context, addr, key, iv string, verbose bool) (*musicflow.Client, error) {
+	if addr == "" {
+		return nil, errors.New("speaker address must be provided (-addr or -speaker)")
+	}
+	opt, err := dialOptions(key, iv, verbose)
+	if err != nil {
+		return nil, err
+	}
+	c, err := musicflow.Dial(ctx, addr, opt...)
+	if err != nil {
+		return nil, connError{err}
+	}
+	return c, nil
+}
 
+// dialOptions returns the options for connecting to a speaker.
+func dialOptions(key, iv string, verbose bool) ([]musicflow.DialOption, error) {
 	var gsOpt []goodspeaker.Option
 	if key != "" && iv != "" {
 		aes, err := goodspeaker.WithAES([]byte(key), []byte(iv))
 		if err != nil {
-			return err
+			return nil, err
 		}
 		gsOpt = append(gsOpt, aes)
 	}
 	opt := []musicflow.DialOption{
 		musicflow.WithGoodspeakerOption(gsOpt...),
-		musicflow.WithLogger(log.New(os.Stderr, "[musicflow] ", log.Flags())),
 	}
+	if verbose {
+		opt = append(opt, musicflow.WithLogger(log.New(os.Stderr, "[musicflow] ", log.Flags())))
+	}
+	return opt, nil
+}
 
-	c, err := musicflow.Dial(ctx, addr, opt...)
-	if err != nil {
-		return err
+// dial connects to the speaker and performs the initial handshake
+// (product info request) expected by the speaker.
+func (o options) dial(ctx context.Context) (*musicflow.Client, error) {
+	if o.client != nil {
+		return o.client, nil
 	}
-	defer c.Close()
+	c, _, err := o.dialInfo(ctx)
+	return c, err
+}
 
-	c.OnBroadcast(func(message string, data []byte) {
-		log.Printf("Broadcast: %s %s", message, data)
-	})
+// close closes the client returned by dial, unless it is o.client
+// which is closed by its owner (fan-out or the shell).
+func (o options) close(c *musicflow.Client) {
+	if c != o.client {
+		c.Close()
+	}
+}
 
-	_, err = c.ProductInfo(ctx, time.Now(), true)
+// dialInfo is like dial but also returns the product info of the
+// handshake, the speaker is always dialed.
+func (o options) dialInfo(ctx context.Context) (*musicflow.Client, *api.ProductInfo, error) {
+	addr, err := o.speakerAddr(ctx)
 	if err != nil {
-		return err
+		return nil, nil, err
 	}
+	c, err := dial(ctx, addr, key, iv, o.verbose)
+	if err != nil {
+		return nil, nil, err
+	}
+	info, err := c.ProductInfo(ctx, time.Now(), true)
+	if err != nil {
+		c.Close()
+		return nil, nil, connError{err}
+	}
+	return c, info, nil
+}
 
-	fmt.Printf("Reading stdin for JSON input (Ctrl+C to exit)...\nExample(s):\n\t%s\n\t%s\n\n",
-		`{"data":{"fadetime":0,"vol":8},"msg":"VOLUME_SETTING"}`,
-		`{"data":{"nightmode":false},"msg":"NIGHT_MODE_SET"}`,
-	)
-	go func() {
-		defer cancel()
-
-		s := bufio.NewScanner(os.Stdin)
-		for s.Scan() {
-			req := musicflow.Request{}
-			err = json.Unmarshal(s.Bytes(), &req)
-			if err != nil {
-				log.Println(err)
-				continue
-			}
-
-			err = c.Send(ctx, req, nil)
-			if err != nil {
-				if err == io.EOF {
-					log.Println("Connection lost")
-					break
-				}
-				log.Println(err)
-				return
-			}
-		}
-		if err = s.Err(); err != nil {
-			log.Println(err)
-		}
-	}()
-
-	<-ctx.Done()
-	return ctx.Err()
+// speakerAddr returns the address (host:port) of the speaker, a
+// speaker without address in the config is looked up in the registry.
+func (o options) speakerAddr(ctx context.Context) (string, error) {
+	if o.addr != "" || o.speaker == "" {
+		return o.addr, nil
+	}
+	sc, err := resolve(ctx, o.speaker, speakerConfig{Port: o.port, Key: key, IV: iv, Verbose: &o.verbose})
+	if err != nil {
+		return "", err
+	}
+	return net.JoinHostPort(sc.Addr, strconv.Itoa(sc.Port)), nil
 }
@@ -0,0 +1,201 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mafredri/musicflow"
+	"github.com/mafredri/musicflow/api"
+)
+
+// speakerMetrics is the state of a speaker at the time of a scrape.
+type speakerMetrics struct {
+	connected bool
+	info      *api.ProductInfo // Nil when not connected or on error.
+	woofer    *int             // Nil when not supported.
+	eq        *api.EqualizerInfo
+	client    musicflow.MetricsSnapshot
+}
+
+// scrape requests the speaker state, the speaker is not dialed (see
+// pump).
+func (s *speakerConn) scrape(ctx context.Context) speakerMetrics {
+	sm := speakerMetrics{client: s.metrics.Snapshot()}
+
+	s.mu.Lock()
+	c := s.c
+	s.mu.Unlock()
+	if c == nil {
+		return sm
+	}
+	sm.connected = true
+
+	info, err := c.ProductInfo(ctx, time.Now(), false)
+	if err != nil {
+		return sm
+	}
+	sm.info = info
+	if caps, err := c.Capabilities(ctx); err == nil && caps.Woofer {
+		if settings, err := c.Settings(ctx); err == nil {
+			sm.woofer = &settings.WooferLevel
+		}
+	}
+	if len(info.Info.Equalizers) > 0 {
+		sm.eq, _ = c.EqualizerInfo(ctx)
+	}
+	// Include the requests of this scrape.
+	sm.client = s.metrics.Snapshot()
+	return sm
+}
+
+// serveMetrics serves the speaker state and the client metrics in the
+// Prometheus text format.
+func (g *gateway) serveMetrics(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), g.timeout)
+	defer cancel()
+
+	scraped := make([]speakerMetrics, len(g.names))
+	var wg sync.WaitGroup
+	for i, name := range g.names {
+		wg.Add(1)
+		go func(i int, s *speakerConn) {
+			defer wg.Done()
+			scraped[i] = s.scrape(ctx)
+		}(i, g.speakers[name])
+	}
+	wg.Wait()
+
+	var p promWriter
+	connected := p.family("musicflow_speaker_connected", "gauge", "Whether the gateway is connected to the speaker.")
+	info := p.family("musicflow_speaker_info", "gauge", "Speaker name, model and firmware version.")
+	volume := p.family("musicflow_volume", "gauge", "Volume level.")
+	muted := p.family("musicflow_muted", "gauge", "Whether the speaker is muted.")
+	playing := p.family("musicflow_playing", "gauge", "Whether the speaker is playing.")
+	woofer := p.family("musicflow_woofer_level", "gauge", "Woofer level.")
+	function := p.family("musicflow_function", "gauge", "Current function (input), the value is always 1.")
+	equalizer := p.family("musicflow_equalizer", "gauge", "Current equalizer preset, the value is always 1.")
+	requests := p.family("musicflow_requests_total", "counter", "Requests sent by message and result.")
+	latency := p.family("musicflow_request_duration_seconds", "histogram", "Time until the reply was received, by message.")
+	parseErrors := p.family("musicflow_parse_errors_total", "counter", "Requests, replies and messages that could not be parsed.")
+	connects := p.family("musicflow_connects_total", "counter", "Connections established to the speaker.")
+	reconnects := p.family("musicflow_reconnects_total", "counter", "Connections re-established after the first one.")
+	broadcasts := p.family("musicflow_broadcasts_total", "counter", "Broadcasts received by message.")
+
+	for i, name := range g.names {
+		sm := scraped[i]
+		connected.sample("", boolValue(sm.connected), "speaker", name)
+		if sm.info != nil {
+			info.sample("", 1, "speaker", name, "name", sm.info.Info.Name, "model", sm.info.ModelName, "version", sm.info.Info.BeVer)
+			volume.sample("", float64(sm.info.Info.Volume), "speaker", name)
+			muted.sample("", boolValue(sm.info.Info.Mute), "speaker", name)
+			playing.sample("", boolValue(sm.info.Info.Playing), "speaker", name)
+			function.sample("", 1, "speaker", name, "function", sm.info.Info.Function.String())
+		}
+		if sm.woofer != nil {
+			woofer.sample("", float64(*sm.woofer), "speaker", name)
+		}
+		if sm.eq != nil {
+			equalizer.sample("", 1, "speaker", name, "equalizer", sm.eq.CurrentEqualizer.String())
+		}
+
+		for _, rc := range sm.client.Requests {
+			requests.sample("", float64(rc.Count), "speaker", name, "message", rc.Message, "result", rc.Result)
+		}
+		for _, h := range sm.client.Latency {
+			for j, le := range musicflow.LatencyBuckets {
+				latency.sample("_bucket", float64(h.Buckets[j]), "speaker", name, "message", h.Message, "le", strconv.FormatFloat(le, 'g', -1, 64))
+			}
+			latency.sample("_bucket", float64(h.Count), "speaker", name, "message", h.Message, "le", "+Inf")
+			latency.sample("_sum", h.Sum.Seconds(), "speaker", name, "message", h.Message)
+			latency.sample("_count", float64(h.Count), "speaker", name, "message", h.Message)
+		}
+		parseErrors.sample("", float64(sm.client.ParseErrors), "speaker", name)
+		connects.sample("", float64(sm.client.Connects), "speaker", name)
+		reconnects.sample("", float64(sm.client.Reconnects), "speaker", name)
+		for _, msg := range sortedKeys(sm.client.Broadcasts) {
+			broadcasts.sample("", float64(sm.client.Broadcasts[msg]), "speaker", name, "message", msg)
+		}
+	}
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	_ = p.write(w)
+}
+
+func boolValue(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+func sortedKeys(m map[string]uint64) []string {
+	var keys []string
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// promWriter writes metrics in the Prometheus text exposition format,
+// the samples are grouped by family in the order the families were
+// added. Families without samples are omitted.
+type promWriter struct {
+	families []*promFamily
+}
+
+type promFamily struct {
+	name    string
+	typ     string
+	help    string
+	samples []string
+}
+
+func (p *promWriter) family(name, typ, help string) *promFamily {
+	f := &promFamily{name: name, typ: typ, help: help}
+	p.families = append(p.families, f)
+	return f
+}
+
+var promLabelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// sample adds a sample, suffix is appended to the name (e.g. _bucket)
+// and labels are name value pairs.
+func (f *promFamily) sample(suffix string, value float64, labels ...string) {
+	var b strings.Builder
+	b.WriteString(f.name + suffix)
+	for i := 0; i+1 < len(labels); i += 2 {
+		if i == 0 {
+			b.WriteByte('{')
+		} else {
+			b.WriteByte(',')
+		}
+		fmt.Fprintf(&b, `%s="%s"`, labels[i], promLabelEscaper.Replace(labels[i+1]))
+	}
+	if len(labels) > 0 {
+		b.WriteByte('}')
+	}
+	b.WriteByte(' ')
+	b.WriteString(strconv.FormatFloat(value, 'g', -1, 64))
+	f.samples = append(f.samples, b.String())
+}
+
+func (p *promWriter) write(w io.Writer) error {
+	for _, f := range p.families {
+		if len(f.samples) == 0 {
+			continue
+		}
+		_, err := fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n%s\n", f.name, f.help, f.name, f.typ, strings.Join(f.samples, "\n"))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,335 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"sync"
+	"time"
+)
+
+// MQTT 3.1.1 packet types.
+const (
+	mqttConnect     = 1
+	mqttConnack     = 2
+	mqttPublish     = 3
+	mqttPuback      = 4
+	mqttSubscribe   = 8
+	mqttSuback      = 9
+	mqttPingreq     = 12
+	mqttPingresp    = 13
+	mqttDisconnect  = 14
+	mqttMaxPacket   = 1 << 20
+	mqttProtocolLvl = 4
+)
+
+// mqttOptions are the connection options of mqttClient.
+type mqttOptions struct {
+	clientID  string
+	username  string
+	password  string
+	keepAlive time.Duration
+
+	// Will is published retained by the broker when the connection
+	// is lost.
+	willTopic   string
+	willPayload string
+}
+
+// mqttClient is a minimal MQTT 3.1.1 client. Messages are published
+// and received with QoS 0, which is all the bridge needs.
+type mqttClient struct {
+	conn    net.Conn
+	r       *bufio.Reader
+	handler func(topic string, payload []byte)
+
+	mu     sync.Mutex // Protects writes.
+	nextID uint16
+
+	done      chan struct{} // Closed when the connection is lost.
+	closeOnce sync.Once
+	err       error
+}
+
+// dialMQTT connects to the broker, addr is host:port optionally
+// prefixed by tcp://, mqtt://, ssl://, tls:// or mqtts://. The handler
+// is called for the received messages, from a single goroutine.
+func dialMQTT(ctx context.Context, addr string, o mqttOptions, handler func(topic string, payload []byte)) (*mqttClient, error) {
+	useTLS := false
+	if i := strings.Index(addr, "://"); i >= 0 {
+		switch addr[:i] {
+		case "tcp", "mqtt":
+		case "ssl", "tls", "mqtts":
+			useTLS = true
+		default:
+			return nil, fmt.Errorf("mqtt: unsupported scheme: %q", addr[:i])
+		}
+		addr = addr[i+3:]
+	}
+	if _, _, err := net.SplitHostPort(addr); err != nil {
+		port := "1883"
+		if useTLS {
+			port = "8883"
+		}
+		addr = net.JoinHostPort(addr, port)
+	}
+
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("mqtt: %w", err)
+	}
+	if useTLS {
+		host, _, _ := net.SplitHostPort(addr)
+		tc := tls.Client(conn, &tls.Config{ServerName: host})
+		if err = tc.Handshake(); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("mqtt: %w", err)
+		}
+		conn = tc
+	}
+
+	c := &mqttClient{
+		conn:    conn,
+		r:       bufio.NewReader(conn),
+		handler: handler,
+		done:    make(chan struct{}),
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	}
+	if err = c.connect(o); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	_ = conn.SetDeadline(time.Time{})
+
+	go c.readLoop(o.keepAlive)
+	go c.keepAlive(o.keepAlive)
+	return c, nil
+}
+
+func (c *mqttClient) connect(o mqttOptions) error {
+	var flags byte = 0x02 // Clean session.
+	var payload []byte
+	payload = appendString(payload, o.clientID)
+	if o.willTopic != "" {
+		flags |= 0x04 | 0x20 // Will, retained, QoS 0.
+		payload = appendString(payload, o.willTopic)
+		payload = appendString(payload, o.willPayload)
+	}
+	if o.username != "" {
+		flags |= 0x80
+		payload = appendString(payload, o.username)
+	}
+	if o.password != "" {
+		flags |= 0x40
+		payload = appendString(payload, o.password)
+	}
+
+	var b []byte
+	b = appendString(b, "MQTT")
+	b = append(b, mqttProtocolLvl, flags)
+	b = append(b, byte(o.keepAlive/time.Second>>8), byte(o.keepAlive/time.Second))
+	b = append(b, payload...)
+	if err := c.write(mqttConnect<<4, b); err != nil {
+		return fmt.Errorf("mqtt: connect failed: %w", err)
+	}
+
+	typ, body, err := c.readPacket()
+	if err != nil {
+		return fmt.Errorf("mqtt: connect failed: %w", err)
+	}
+	if typ>>4 != mqttConnack || len(body) != 2 {
+		return errors.New("mqtt: connect failed: unexpected reply")
+	}
+	switch body[1] {
+	case 0:
+		return nil
+	case 4, 5:
+		return errors.New("mqtt: connect failed: not authorized")
+	default:
+		return fmt.Errorf("mqtt: connect failed: refused (code %d)", body[1])
+	}
+}
+
+// Done returns a channel that is closed when the connection is lost.
+func (c *mqttClient) Done() <-chan struct{} {
+	return c.done
+}
+
+// Err returns the reason the connection was lost.
+func (c *mqttClient) Err() error {
+	<-c.done
+	return c.err
+}
+
+func (c *mqttClient) close(err error) {
+	c.closeOnce.Do(func() {
+		c.err = err
+		c.conn.Close()
+		close(c.done)
+	})
+}
+
+// Close disconnects from the broker, the will is not published.
+func (c *mqttClient) Close() error {
+	_ = c.write(mqttDisconnect<<4, nil)
+	c.close(errors.New("mqtt: closed"))
+	return nil
+}
+
+// publish publishes the message with QoS 0.
+func (c *mqttClient) publish(topic string, payload []byte, retain bool) error {
+	var header byte = mqttPublish << 4
+	if retain {
+		header |= 0x01
+	}
+	b := appendString(nil, topic)
+	b = append(b, payload...)
+	return c.write(header, b)
+}
+
+// subscribe subscribes to the topic filters with QoS 0.
+func (c *mqttClient) subscribe(filters ...string) error {
+	c.mu.Lock()
+	c.nextID++
+	if c.nextID == 0 {
+		c.nextID = 1
+	}
+	id := c.nextID
+	c.mu.Unlock()
+
+	b := []byte{byte(id >> 8), byte(id)}
+	for _, f := range filters {
+		b = appendString(b, f)
+		b = append(b, 0) // QoS 0.
+	}
+	return c.write(mqttSubscribe<<4|0x02, b)
+}
+
+func (c *mqttClient) write(header byte, body []byte) error {
+	if len(body) > mqttMaxPacket {
+		return errors.New("mqtt: packet too large")
+	}
+	b := []byte{header}
+	n := len(body)
+	for {
+		digit := byte(n % 128)
+		n /= 128
+		if n > 0 {
+			digit |= 0x80
+		}
+		b = append(b, digit)
+		if n == 0 {
+			break
+		}
+	}
+	b = append(b, body...)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	_ = c.conn.SetWriteDeadline(time.Now().Add(10 * time.Second))
+	_, err := c.conn.Write(b)
+	if err != nil {
+		c.close(err)
+	}
+	return err
+}
+
+func (c *mqttClient) readPacket() (header byte, body []byte, err error) {
+	if header, err = c.r.ReadByte(); err != nil {
+		return 0, nil, err
+	}
+	n, shift := 0, uint(0)
+	for i := 0; ; i++ {
+		if i == 4 {
+			return 0, nil, errors.New("mqtt: malformed remaining length")
+		}
+		b, err := c.r.ReadByte()
+		if err != nil {
+			return 0, nil, err
+		}
+		n |= int(b&0x7f) << shift
+		if b&0x80 == 0 {
+			break
+		}
+		shift += 7
+	}
+	if n > mqttMaxPacket {
+		return 0, nil, errors.New("mqtt: packet too large")
+	}
+	body = make([]byte, n)
+	if _, err = io.ReadFull(c.r, body); err != nil {
+		return 0, nil, err
+	}
+	return header, body, nil
+}
+
+func (c *mqttClient) readLoop(keepAlive time.Duration) {
+	for {
+		if keepAlive > 0 {
+			_ = c.conn.SetReadDeadline(time.Now().Add(keepAlive * 3 / 2))
+		}
+		header, body, err := c.readPacket()
+		if err != nil {
+			c.close(err)
+			return
+		}
+		switch header >> 4 {
+		case mqttPublish:
+			if len(body) < 2 {
+				c.close(errors.New("mqtt: malformed publish"))
+				return
+			}
+			n := int(binary.BigEndian.Uint16(body))
+			if len(body) < 2+n {
+				c.close(errors.New("mqtt: malformed publish"))
+				return
+			}
+			topic, payload := string(body[2:2+n]), body[2+n:]
+			if qos := header >> 1 & 0x03; qos > 0 {
+				// Should not happen, we subscribe with QoS 0.
+				if len(payload) < 2 {
+					c.close(errors.New("mqtt: malformed publish"))
+					return
+				}
+				_ = c.write(mqttPuback<<4, payload[:2])
+				payload = payload[2:]
+			}
+			if c.handler != nil {
+				c.handler(topic, payload)
+			}
+		case mqttSuback, mqttPingresp:
+		}
+	}
+}
+
+func (c *mqttClient) keepAlive(d time.Duration) {
+	if d <= 0 {
+		return
+	}
+	t := time.NewTicker(d / 2)
+	defer t.Stop()
+	for {
+		select {
+		case <-t.C:
+			if c.write(mqttPingreq<<4, nil) != nil {
+				return
+			}
+		case <-c.done:
+			return
+		}
+	}
+}
+
+func appendString(b []byte, s string) []byte {
+	b = append(b, byte(len(s)>>8), byte(len(s)))
+	return append(b, s...)
+}
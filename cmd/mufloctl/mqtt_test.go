@@ -0,0 +1,364 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// mqttMessage is a PUBLISH packet received by fakeBroker.
+type mqttMessage struct {
+	topic   string
+	payload string
+}
+
+// fakeBroker is an MQTT broker on 127.0.0.1 for one client at a time.
+// The packets are encoded and decoded independently of mqttClient.
+type fakeBroker struct {
+	l    net.Listener
+	code byte // CONNACK return code.
+
+	mu       sync.Mutex
+	changed  chan struct{} // Closed and replaced on every packet.
+	conn     net.Conn
+	connects [][]byte          // CONNECT bodies.
+	packets  [][2][]byte       // All packets after CONNECT, header and body.
+	retained map[string]string // Last payload by topic.
+	filters  []string          // Subscribed topic filters.
+}
+
+func newFakeBroker(t *testing.T, code byte) *fakeBroker {
+	t.Helper()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b := &fakeBroker{l: l, code: code, changed: make(chan struct{}), retained: make(map[string]string)}
+	go b.serve()
+	t.Cleanup(func() {
+		l.Close()
+		b.mu.Lock()
+		if b.conn != nil {
+			b.conn.Close()
+		}
+		b.mu.Unlock()
+	})
+	return b
+}
+
+func (b *fakeBroker) addr() string { return b.l.Addr().String() }
+
+func encodeMQTT(header byte, body []byte) []byte {
+	p := []byte{header}
+	n := len(body)
+	for {
+		d := byte(n % 128)
+		n /= 128
+		if n > 0 {
+			d |= 0x80
+		}
+		p = append(p, d)
+		if n == 0 {
+			break
+		}
+	}
+	return append(p, body...)
+}
+
+func decodeMQTT(r *bufio.Reader) (header byte, body []byte, err error) {
+	if header, err = r.ReadByte(); err != nil {
+		return 0, nil, err
+	}
+	n, mul := 0, 1
+	for {
+		d, err := r.ReadByte()
+		if err != nil {
+			return 0, nil, err
+		}
+		n += int(d&0x7f) * mul
+		mul *= 128
+		if d&0x80 == 0 {
+			break
+		}
+	}
+	body = make([]byte, n)
+	_, err = io.ReadFull(r, body)
+	return header, body, err
+}
+
+func mqttString(s string) []byte {
+	return append([]byte{byte(len(s) >> 8), byte(len(s))}, s...)
+}
+
+func (b *fakeBroker) serve() {
+	for {
+		conn, err := b.l.Accept()
+		if err != nil {
+			return
+		}
+		b.mu.Lock()
+		b.conn = conn
+		b.mu.Unlock()
+		b.handle(conn)
+	}
+}
+
+func (b *fakeBroker) handle(conn net.Conn) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	for {
+		header, body, err := decodeMQTT(r)
+		if err != nil {
+			return
+		}
+		b.mu.Lock()
+		switch header >> 4 {
+		case mqttConnect:
+			b.connects = append(b.connects, body)
+			_, _ = conn.Write(encodeMQTT(mqttConnack<<4, []byte{0, b.code}))
+		case mqttPublish:
+			n := int(body[0])<<8 | int(body[1])
+			b.retained[string(body[2:2+n])] = string(body[2+n:])
+		case mqttSubscribe:
+			for p := body[2:]; len(p) > 2; {
+				n := int(p[0])<<8 | int(p[1])
+				b.filters = append(b.filters, string(p[2:2+n]))
+				p = p[2+n+1:]
+			}
+			_, _ = conn.Write(encodeMQTT(mqttSuback<<4, append(body[:2:2], 0)))
+		case mqttPingreq:
+			_, _ = conn.Write(encodeMQTT(mqttPingresp<<4, nil))
+		}
+		if header>>4 != mqttConnect {
+			b.packets = append(b.packets, [2][]byte{{header}, body})
+		}
+		close(b.changed)
+		b.changed = make(chan struct{})
+		b.mu.Unlock()
+	}
+}
+
+// send publishes a message to the client, a packet ID is added for
+// QoS 1.
+func (b *fakeBroker) send(t *testing.T, topic, payload string, qos byte) {
+	t.Helper()
+	body := mqttString(topic)
+	if qos > 0 {
+		body = append(body, 0, 7)
+	}
+	body = append(body, payload...)
+	b.mu.Lock()
+	conn := b.conn
+	b.mu.Unlock()
+	if _, err := conn.Write(encodeMQTT(mqttPublish<<4|qos<<1, body)); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// wait waits until cond, called with the broker locked, is true.
+func (b *fakeBroker) wait(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	timeout := time.After(5 * time.Second)
+	for {
+		b.mu.Lock()
+		ok, changed := cond(), b.changed
+		b.mu.Unlock()
+		if ok {
+			return
+		}
+		select {
+		case <-changed:
+		case <-timeout:
+			t.Fatalf("timeout waiting for %s", what)
+		}
+	}
+}
+
+// waitRetained waits for the payload of the topic.
+func (b *fakeBroker) waitRetained(t *testing.T, topic, payload string) {
+	t.Helper()
+	b.wait(t, topic+" = "+payload, func() bool { return b.retained[topic] == payload })
+}
+
+// waitPacket waits for the nth packet (after CONNECT) and returns it.
+func (b *fakeBroker) waitPacket(t *testing.T, n int) (header byte, body []byte) {
+	t.Helper()
+	b.wait(t, "packet", func() bool { return len(b.packets) > n })
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.packets[n][0][0], b.packets[n][1]
+}
+
+func TestMQTTRemainingLength(t *testing.T) {
+	tests := []struct {
+		n    int
+		want []byte
+	}{
+		{0, []byte{0x00}},
+		{127, []byte{0x7f}},
+		{128, []byte{0x80, 0x01}},
+		{16383, []byte{0xff, 0x7f}},
+		{16384, []byte{0x80, 0x80, 0x01}},
+		{mqttMaxPacket, []byte{0x80, 0x80, 0x40}},
+	}
+	for _, tt := range tests {
+		cc, sc := net.Pipe()
+		c := &mqttClient{conn: cc, r: bufio.NewReader(cc), done: make(chan struct{})}
+
+		body := bytes.Repeat([]byte{'x'}, tt.n)
+		go func() { _ = c.write(mqttPublish<<4, body) }()
+		got := make([]byte, 1+len(tt.want)+tt.n)
+		if _, err := io.ReadFull(sc, got); err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(got[1:1+len(tt.want)], tt.want) {
+			t.Errorf("write(%d bytes) remaining length = % x, want % x", tt.n, got[1:1+len(tt.want)], tt.want)
+		}
+
+		go func() { _, _ = sc.Write(got) }()
+		header, gotBody, err := c.readPacket()
+		if err != nil || header != mqttPublish<<4 || len(gotBody) != tt.n {
+			t.Errorf("readPacket() = %#x, %d bytes, %v; want %#x, %d bytes", header, len(gotBody), err, mqttPublish<<4, tt.n)
+		}
+		cc.Close()
+		sc.Close()
+	}
+}
+
+func TestMQTTReadPacketMalformed(t *testing.T) {
+	tests := []struct {
+		name string
+		p    []byte
+		want string
+	}{
+		{"five length bytes", []byte{0x30, 0xff, 0xff, 0xff, 0xff, 0x7f}, "malformed remaining length"},
+		{"too large", []byte{0x30, 0x80, 0x80, 0x80, 0x01}, "packet too large"},
+		{"truncated", []byte{0x30, 0x05, 'a'}, "unexpected EOF"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &mqttClient{r: bufio.NewReader(bytes.NewReader(tt.p))}
+			_, _, err := c.readPacket()
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("readPacket() error = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestMQTTConnect(t *testing.T) {
+	b := newFakeBroker(t, 0)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	o := mqttOptions{
+		clientID:    "c",
+		username:    "u",
+		password:    "p",
+		keepAlive:   30 * time.Second,
+		willTopic:   "w",
+		willPayload: "offline",
+	}
+	m, err := dialMQTT(ctx, "tcp://"+b.addr(), o, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer m.Close()
+
+	var want []byte
+	want = append(want, mqttString("MQTT")...)
+	want = append(want, 4, 0x02|0x04|0x20|0x80|0x40, 0, 30)
+	for _, s := range []string{"c", "w", "offline", "u", "p"} {
+		want = append(want, mqttString(s)...)
+	}
+	b.mu.Lock()
+	got := b.connects[0]
+	b.mu.Unlock()
+	if !bytes.Equal(got, want) {
+		t.Errorf("CONNECT = % x, want % x", got, want)
+	}
+}
+
+func TestMQTTConnectRefused(t *testing.T) {
+	for code, want := range map[byte]string{2: "refused (code 2)", 5: "not authorized"} {
+		b := newFakeBroker(t, code)
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		_, err := dialMQTT(ctx, b.addr(), mqttOptions{clientID: "c"}, nil)
+		cancel()
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("dialMQTT() with code %d error = %v, want %q", code, err, want)
+		}
+	}
+}
+
+func TestMQTTPublishSubscribe(t *testing.T) {
+	b := newFakeBroker(t, 0)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	received := make(chan mqttMessage, 2)
+	m, err := dialMQTT(ctx, b.addr(), mqttOptions{clientID: "c"}, func(topic string, payload []byte) {
+		received <- mqttMessage{topic: topic, payload: string(payload)}
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err = m.publish("a/b", []byte("x"), true); err != nil {
+		t.Fatal(err)
+	}
+	header, body := b.waitPacket(t, 0)
+	if want := append(mqttString("a/b"), 'x'); header != mqttPublish<<4|0x01 || !bytes.Equal(body, want) {
+		t.Errorf("PUBLISH = %#x % x, want %#x % x", header, body, mqttPublish<<4|0x01, want)
+	}
+
+	if err = m.subscribe("f/#", "g/+"); err != nil {
+		t.Fatal(err)
+	}
+	header, body = b.waitPacket(t, 1)
+	want := []byte{0, 1}
+	want = append(append(want, mqttString("f/#")...), 0)
+	want = append(append(want, mqttString("g/+")...), 0)
+	if header != mqttSubscribe<<4|0x02 || !bytes.Equal(body, want) {
+		t.Errorf("SUBSCRIBE = %#x % x, want %#x % x", header, body, mqttSubscribe<<4|0x02, want)
+	}
+
+	b.send(t, "f/0", "zero", 0)
+	b.send(t, "f/1", "one", 1)
+	for _, want := range []mqttMessage{{topic: "f/0", payload: "zero"}, {topic: "f/1", payload: "one"}} {
+		select {
+		case got := <-received:
+			if got != want {
+				t.Errorf("received %+v, want %+v", got, want)
+			}
+		case <-ctx.Done():
+			t.Fatal("timeout waiting for", want.topic)
+		}
+	}
+	// The SUBACK is not recorded, the QoS 1 message is acknowledged.
+	header, body = b.waitPacket(t, 2)
+	if header != mqttPuback<<4 || !bytes.Equal(body, []byte{0, 7}) {
+		t.Errorf("PUBACK = %#x % x, want %#x 00 07", header, body, mqttPuback<<4)
+	}
+
+	m.Close()
+	header, _ = b.waitPacket(t, 3)
+	if header != mqttDisconnect<<4 {
+		t.Errorf("Close() sent %#x, want DISCONNECT", header)
+	}
+	select {
+	case <-m.Done():
+	case <-ctx.Done():
+		t.Fatal("Done() not closed after Close()")
+	}
+	if err := m.Err(); err == nil || errors.Is(err, io.EOF) {
+		t.Errorf("Err() = %v, want closed", err)
+	}
+}
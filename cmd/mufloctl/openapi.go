@@ -0,0 +1,274 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/mafredri/musicflow/api"
+)
+
+// object is a JSON object in the API description.
+type object = map[string]interface{}
+
+// openAPI returns the OpenAPI 3 description of the gateway (see
+// serve), the schemas are generated from the body and reply types of
+// the routes.
+func openAPI(speakers []string) object {
+	g := schemaGen{schemas: make(object)}
+	errorResponse := object{
+		"description": "Error",
+		"content":     object{"application/json": object{"schema": g.schema(reflect.TypeOf(errorReply{}))}},
+	}
+
+	paths := object{
+		"/speakers": object{
+			"get": object{
+				"summary": "Speakers served by the gateway",
+				"responses": object{
+					"200": object{
+						"description": "OK",
+						"content":     object{"application/json": object{"schema": g.schema(reflect.TypeOf([]speakerStatus{}))}},
+					},
+				},
+			},
+		},
+	}
+	speakerParam := object{
+		"name":     "speaker",
+		"in":       "path",
+		"required": true,
+		"schema":   object{"type": "string", "enum": speakers},
+	}
+	for _, rt := range routes {
+		path := "/speakers/{speaker}" + rt.path
+		params := []object{speakerParam}
+		for _, p := range strings.Split(rt.path, "/") {
+			if strings.HasPrefix(p, "{") {
+				params = append(params, object{
+					"name":     strings.Trim(p, "{}"),
+					"in":       "path",
+					"required": true,
+					"schema":   object{"type": "integer"},
+				})
+			}
+		}
+
+		op := object{
+			"summary":    rt.summary,
+			"parameters": params,
+			"responses": object{
+				"200": object{
+					"description": "OK",
+					"content":     object{"application/json": object{"schema": g.schema(reflect.TypeOf(rt.reply))}},
+				},
+				"default": errorResponse,
+			},
+		}
+		if rt.body != nil {
+			op["requestBody"] = object{
+				"required": true,
+				"content":  object{"application/json": object{"schema": g.schema(reflect.TypeOf(rt.body))}},
+			}
+		}
+
+		item, ok := paths[path].(object)
+		if !ok {
+			item = make(object)
+			paths[path] = item
+		}
+		item[strings.ToLower(rt.method)] = op
+	}
+
+	streamParams := []object{
+		speakerParam,
+		{
+			"name":        "filter",
+			"in":          "query",
+			"description": "Comma separated messages to send, e.g. VOLUME_CHANGE,MUTE_CHANGE (default all).",
+			"schema":      object{"type": "string"},
+		},
+		{
+			"name":        "lastEventId",
+			"in":          "query",
+			"description": "Resume after the event ID, same as the Last-Event-ID header. Events no longer in the history are skipped.",
+			"schema":      object{"type": "integer"},
+		},
+	}
+	event := g.schema(reflect.TypeOf(streamEvent{}))
+	paths["/speakers/{speaker}/events"] = object{
+		"get": object{
+			"summary":    "Event stream as server-sent events, the event type is the message",
+			"parameters": streamParams,
+			"responses": object{
+				"200": object{
+					"description": "Event stream",
+					"content":     object{"text/event-stream": object{"schema": event}},
+				},
+				"default": errorResponse,
+			},
+		},
+	}
+	paths["/speakers/{speaker}/events/ws"] = object{
+		"get": object{
+			"summary":    "Event stream over a WebSocket, one JSON text message per event",
+			"parameters": streamParams,
+			"responses": object{
+				"101": object{
+					"description": "Event stream",
+					"content":     object{"application/json": object{"schema": event}},
+				},
+				"default": errorResponse,
+			},
+		},
+	}
+
+	paths["/metrics"] = object{
+		"get": object{
+			"summary": "Speaker state and client metrics in the Prometheus text format",
+			"responses": object{
+				"200": object{
+					"description": "Metrics",
+					"content":     object{"text/plain": object{"schema": object{"type": "string"}}},
+				},
+			},
+		},
+	}
+
+	return object{
+		"openapi": "3.0.3",
+		"info": object{
+			"title":   "mufloctl REST gateway",
+			"version": "1",
+			"description": "Control Music Flow speakers. The bodies use the types of " +
+				"github.com/mafredri/musicflow/api. Errors are returned with 503 when " +
+				"the speaker could not be reached, 501 when the feature is not " +
+				"supported and 502 when the speaker rejected the request.",
+		},
+		"paths":      paths,
+		"components": object{"schemas": g.schemas},
+	}
+}
+
+// schemaGen generates the JSON schemas, named struct types are added
+// to schemas and referenced.
+type schemaGen struct {
+	schemas object
+}
+
+var (
+	timeType    = reflect.TypeOf(time.Time{})
+	rawJSONType = reflect.TypeOf(json.RawMessage{})
+	daySetType  = reflect.TypeOf(api.DaySet(0))
+)
+
+// enumNames are the names accepted, in addition to the numeric value,
+// by the api enum types.
+var enumNames = map[reflect.Type][]string{
+	reflect.TypeOf(api.Equalizer(0)):     names(api.EqualizerValues()),
+	reflect.TypeOf(api.EqualizerType(0)): names(api.EqualizerTypeValues()),
+	reflect.TypeOf(api.Function(0)):      names(api.FunctionValues()),
+	reflect.TypeOf(api.Model(0)):         names(api.ModelValues()),
+	reflect.TypeOf(api.Network(0)):       names(api.NetworkValues()),
+	reflect.TypeOf(api.Role(0)):          names(api.RoleValues()),
+	reflect.TypeOf(api.Day(0)):           names(api.DayValues()),
+}
+
+// names returns the names of the values, a slice of fmt.Stringer.
+func names(values interface{}) []string {
+	rv := reflect.ValueOf(values)
+	var s []string
+	for i := 0; i < rv.Len(); i++ {
+		s = append(s, rv.Index(i).Interface().(fmt.Stringer).String())
+	}
+	return s
+}
+
+func (g *schemaGen) schema(t reflect.Type) object {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if names, ok := enumNames[t]; ok {
+		return object{
+			"description": "Numeric value, or the name when sent.",
+			"oneOf": []object{
+				{"type": "integer"},
+				{"type": "string", "enum": names},
+			},
+		}
+	}
+	switch t {
+	case timeType:
+		return object{"type": "string", "format": "date-time"}
+	case rawJSONType:
+		return object{"description": "Any JSON value."}
+	case daySetType:
+		return object{
+			"description": "Bitmask (Monday is 0b1000000), or the comma separated days when sent, e.g. mon,tue.",
+			"oneOf": []object{
+				{"type": "integer"},
+				{"type": "string"},
+			},
+		}
+	}
+
+	switch t.Kind() {
+	case reflect.Struct:
+		if t.Name() == "" {
+			return g.object(t)
+		}
+		name := t.Name()
+		if _, ok := g.schemas[name]; !ok {
+			g.schemas[name] = object{} // Guard against recursion.
+			g.schemas[name] = g.object(t)
+		}
+		return object{"$ref": "#/components/schemas/" + name}
+	case reflect.Slice, reflect.Array:
+		return object{"type": "array", "items": g.schema(t.Elem())}
+	case reflect.Map:
+		return object{"type": "object", "additionalProperties": g.schema(t.Elem())}
+	case reflect.Bool:
+		return object{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return object{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return object{"type": "number"}
+	case reflect.String:
+		return object{"type": "string"}
+	default:
+		return object{}
+	}
+}
+
+// object returns the schema of the struct, following the rules of
+// encoding/json.
+func (g *schemaGen) object(t reflect.Type) object {
+	props := make(object)
+	g.fields(t, props)
+	return object{"type": "object", "properties": props}
+}
+
+func (g *schemaGen) fields(t reflect.Type, props object) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag := f.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name := strings.Split(tag, ",")[0]
+		if f.Anonymous && name == "" && f.Type.Kind() == reflect.Struct {
+			g.fields(f.Type, props)
+			continue
+		}
+		if f.PkgPath != "" {
+			continue // Unexported.
+		}
+		if name == "" {
+			name = f.Name
+		}
+		props[name] = g.schema(f.Type)
+	}
+}
@@ -0,0 +1,217 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"regexp"
+	"strings"
+	"text/tabwriter"
+)
+
+// outputFormats are the supported values for -output.
+var outputFormats = []string{"table", "json", "yaml"}
+
+func validOutput(format string) bool {
+	for _, f := range outputFormats {
+		if f == format {
+			return true
+		}
+	}
+	return false
+}
+
+// print writes v to stdout in the format selected by -output. The
+// table function is used for the table format, when nil the fields of
+// v are listed instead.
+func (o options) print(v interface{}, table func(w io.Writer)) error {
+	switch o.output {
+	case "json":
+		enc := json.NewEncoder(o.out)
+		enc.SetIndent("", "  ")
+		enc.SetEscapeHTML(false)
+		return enc.Encode(v)
+	case "yaml":
+		return writeYAML(o.out, v)
+	default:
+		w := tabwriter.NewWriter(o.out, 0, 4, 2, ' ', 0)
+		if table != nil {
+			table(w)
+		} else {
+			writeFields(w, v)
+		}
+		return w.Flush()
+	}
+}
+
+// writeFields writes the fields of the struct as a name / value table.
+func writeFields(w io.Writer, v interface{}) {
+	rv := reflect.Indirect(reflect.ValueOf(v))
+	if rv.Kind() != reflect.Struct {
+		fmt.Fprintln(w, v)
+		return
+	}
+	for i := 0; i < rv.NumField(); i++ {
+		if f := rv.Type().Field(i); f.PkgPath == "" {
+			fmt.Fprintf(w, "%s:\t%v\n", f.Name, rv.Field(i).Interface())
+		}
+	}
+}
+
+// writeYAML writes v as YAML. The value is encoded as JSON first so
+// that the keys and values are the same as with -output json, object
+// keys keep their order.
+func writeYAML(w io.Writer, v interface{}) error {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+	node, err := decodeOrdered(dec)
+	if err != nil {
+		return err
+	}
+
+	var buf bytes.Buffer
+	switch node.(type) {
+	case *orderedObject, []interface{}:
+		writeYAMLNode(&buf, node, "")
+	default:
+		buf.WriteString(yamlScalar(node) + "\n")
+	}
+	_, err = w.Write(buf.Bytes())
+	return err
+}
+
+type orderedObject struct {
+	keys   []string
+	values []interface{}
+}
+
+// decodeOrdered decodes the next JSON value, preserving the order of
+// object keys.
+func decodeOrdered(dec *json.Decoder) (interface{}, error) {
+	t, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+	d, ok := t.(json.Delim)
+	if !ok {
+		return t, nil
+	}
+	switch d {
+	case '{':
+		obj := &orderedObject{}
+		for dec.More() {
+			k, err := dec.Token()
+			if err != nil {
+				return nil, err
+			}
+			v, err := decodeOrdered(dec)
+			if err != nil {
+				return nil, err
+			}
+			obj.keys = append(obj.keys, k.(string))
+			obj.values = append(obj.values, v)
+		}
+		_, err = dec.Token()
+		return obj, err
+	default:
+		arr := []interface{}{}
+		for dec.More() {
+			v, err := decodeOrdered(dec)
+			if err != nil {
+				return nil, err
+			}
+			arr = append(arr, v)
+		}
+		_, err = dec.Token()
+		return arr, err
+	}
+}
+
+// writeYAMLNode writes an object or array in block style, each line
+// prefixed by indent.
+func writeYAMLNode(buf *bytes.Buffer, node interface{}, indent string) {
+	switch n := node.(type) {
+	case *orderedObject:
+		for i, k := range n.keys {
+			buf.WriteString(indent + yamlKey(k) + ":")
+			writeYAMLValue(buf, n.values[i], indent+"  ")
+		}
+	case []interface{}:
+		for _, v := range n {
+			switch v := v.(type) {
+			case *orderedObject, []interface{}:
+				if isEmpty(v) {
+					buf.WriteString(indent + "- " + yamlScalar(v) + "\n")
+					continue
+				}
+				// Render the element indented and replace the
+				// indentation of the first line with the dash.
+				var elem bytes.Buffer
+				writeYAMLNode(&elem, v, indent+"  ")
+				buf.WriteString(indent + "- ")
+				buf.Write(elem.Bytes()[len(indent)+2:])
+			default:
+				buf.WriteString(indent + "- " + yamlScalar(v) + "\n")
+			}
+		}
+	}
+}
+
+// writeYAMLValue writes the value of a mapping key.
+func writeYAMLValue(buf *bytes.Buffer, v interface{}, indent string) {
+	switch v.(type) {
+	case *orderedObject, []interface{}:
+		if !isEmpty(v) {
+			buf.WriteString("\n")
+			writeYAMLNode(buf, v, indent)
+			return
+		}
+	}
+	buf.WriteString(" " + yamlScalar(v) + "\n")
+}
+
+func isEmpty(v interface{}) bool {
+	switch v := v.(type) {
+	case *orderedObject:
+		return len(v.keys) == 0
+	case []interface{}:
+		return len(v) == 0
+	}
+	return false
+}
+
+var plainKey = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_.-]*$`)
+
+func yamlKey(k string) string {
+	if plainKey.MatchString(k) {
+		return k
+	}
+	return yamlScalar(k)
+}
+
+// yamlScalar formats the JSON token as a YAML scalar, strings are
+// double quoted since JSON strings are valid YAML.
+func yamlScalar(v interface{}) string {
+	switch v := v.(type) {
+	case nil:
+		return "null"
+	case *orderedObject:
+		return "{}"
+	case []interface{}:
+		return "[]"
+	case string:
+		var b strings.Builder
+		enc := json.NewEncoder(&b)
+		enc.SetEscapeHTML(false)
+		_ = enc.Encode(v)
+		return strings.TrimSuffix(b.String(), "\n")
+	default:
+		return fmt.Sprint(v)
+	}
+}
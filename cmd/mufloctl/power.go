@@ -0,0 +1,116 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"strings"
+	"time"
+
+	"github.com/mafredri/musicflow"
+	"github.com/mafredri/musicflow/api"
+)
+
+// snapshot of the speaker settings, saved before destructive
+// operations.
+type snapshot struct {
+	Time          time.Time          `json:"time"`
+	ProductInfo   *api.ProductInfo   `json:"product_info"`
+	SystemVersion *api.SystemVersion `json:"system_version"`
+	Settings      *api.Settings      `json:"settings"`
+	Equalizer     *api.EqualizerInfo `json:"equalizer"`
+	Alarms        []api.Alarm        `json:"alarms"`
+}
+
+// saveSnapshot saves the speaker settings to path, an empty path
+// generates a file name in the current directory.
+func (o options) saveSnapshot(ctx context.Context, c *musicflow.Client, path string) error {
+	var err error
+	s := snapshot{Time: time.Now()}
+	if s.ProductInfo, err = c.ProductInfo(ctx, s.Time, false); err != nil {
+		return err
+	}
+	if s.SystemVersion, err = c.SystemVersion(ctx); err != nil {
+		return err
+	}
+	if s.Settings, err = c.Settings(ctx); err != nil {
+		return err
+	}
+	if s.Equalizer, err = c.EqualizerInfo(ctx); err != nil {
+		return err
+	}
+	if s.Alarms, err = c.Alarms(ctx); err != nil {
+		return err
+	}
+
+	b, err := json.MarshalIndent(s, "", "\t")
+	if err != nil {
+		return err
+	}
+	if path == "" {
+		mac := strings.Replace(s.ProductInfo.Info.WirelessMAC, ":", "", -1)
+		path = fmt.Sprintf("mufloctl-snapshot-%s-%s.json", mac, s.Time.Format("20060102-150405"))
+	}
+	err = ioutil.WriteFile(path, append(b, '\n'), 0o600)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(o.out, "Settings saved to %s\n", path)
+	return nil
+}
+
+func resetCmd(ctx context.Context, o options, args []string) error {
+	fs := flag.NewFlagSet("reset", flag.ExitOnError)
+	yes := fs.Bool("yes-really", false, "Confirm the factory reset")
+	path := fs.String("snapshot", "", "Save the settings to `file` before resetting (default generated)")
+	_ = fs.Parse(args)
+
+	if !*yes {
+		fs.Usage()
+		return errors.New("reset: factory reset erases all settings, confirm with -yes-really")
+	}
+
+	c, err := o.dial(ctx)
+	if err != nil {
+		return err
+	}
+	defer o.close(c)
+
+	if err = o.saveSnapshot(ctx, c, *path); err != nil {
+		return fmt.Errorf("reset: snapshot failed, speaker not reset: %w", err)
+	}
+	err = c.FactoryReset(ctx, musicflow.ConfirmFactoryReset)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintln(o.out, "Speaker reset to factory settings.")
+	return nil
+}
+
+func poweroffCmd(ctx context.Context, o options, args []string) error {
+	fs := flag.NewFlagSet("poweroff", flag.ExitOnError)
+	path := fs.String("snapshot", "", "Save the settings to `file` before powering off (default generated)")
+	noSnapshot := fs.Bool("no-snapshot", false, "Do not save the settings")
+	_ = fs.Parse(args)
+
+	c, err := o.dial(ctx)
+	if err != nil {
+		return err
+	}
+	defer o.close(c)
+
+	if !*noSnapshot {
+		if err = o.saveSnapshot(ctx, c, *path); err != nil {
+			return fmt.Errorf("poweroff: snapshot failed, speaker not powered off: %w", err)
+		}
+	}
+	err = c.PowerOff(ctx, musicflow.ConfirmPowerOff)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintln(o.out, "Speaker powered off.")
+	return nil
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"text/tabwriter"
+	"time"
+
+	"github.com/mafredri/musicflow"
+)
+
+func defaultRegistryPath() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "mufloctl-registry.json"
+	}
+	return filepath.Join(dir, "mufloctl", "registry.json")
+}
+
+func registryCmd(ctx context.Context, o options, args []string) error {
+	fs := flag.NewFlagSet("registry", flag.ExitOnError)
+	path := fs.String("file", defaultRegistryPath(), "Registry `file`")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: registry [options] list|add NAME|rm NAME|resolve NAME\n\n")
+		fs.PrintDefaults()
+	}
+	_ = fs.Parse(args)
+	args = fs.Args()
+
+	if len(args) == 0 {
+		fs.Usage()
+		return errors.New("registry: missing subcommand")
+	}
+	sub, args := args[0], args[1:]
+	if sub != "list" && len(args) != 1 {
+		return fmt.Errorf("registry %s: expected speaker name", sub)
+	}
+
+	reg, err := musicflow.LoadRegistry(*path)
+	if err != nil {
+		return err
+	}
+
+	switch sub {
+	case "list":
+		w := tabwriter.NewWriter(o.out, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(w, "NAME\tADDRESS\tMODEL\tFIRMWARE\tMAC\tLAST SEEN")
+		for _, name := range reg.Names() {
+			e, _ := reg.Lookup(name)
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n",
+				name, e.Addr, e.Model, e.Firmware, e.WirelessMAC, e.LastSeen.Local().Format(time.RFC3339))
+		}
+		return w.Flush()
+
+	case "add":
+		o.addr, err = o.speakerAddr(ctx)
+		if err != nil {
+			return err
+		}
+		c, info, err := o.dialInfo(ctx)
+		if err != nil {
+			return err
+		}
+		defer c.Close()
+		reg.Add(args[0], musicflow.Speaker{
+			Addr:         o.addr,
+			Name:         info.Info.Name,
+			ModelName:    info.ModelName,
+			PetName:      info.PetName,
+			WirelessMAC:  info.Info.WirelessMAC,
+			BluetoothMAC: info.Info.BluetoothMAC,
+			Info:         info,
+		})
+		return reg.Save()
+
+	case "rm":
+		if _, ok := reg.Lookup(args[0]); !ok {
+			return fmt.Errorf("registry: speaker %q not found", args[0])
+		}
+		reg.Remove(args[0])
+		return reg.Save()
+
+	case "resolve":
+		dopt, err := dialOptions(key, iv, o.verbose)
+		if err != nil {
+			return err
+		}
+		c, err := reg.DialByName(ctx, args[0], dopt...)
+		if err != nil {
+			return err
+		}
+		c.Close()
+		// Print the host only, suitable for -addr.
+		e, _ := reg.Lookup(args[0])
+		host, _, err := net.SplitHostPort(e.Addr)
+		if err != nil {
+			host = e.Addr
+		}
+		fmt.Fprintln(o.out, host)
+		return nil
+
+	default:
+		return fmt.Errorf("registry: unknown subcommand %q", sub)
+	}
+}
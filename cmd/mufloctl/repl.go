@@ -0,0 +1,458 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mafredri/musicflow"
+	"github.com/mafredri/musicflow/api"
+)
+
+// replyTimeout is how long the shell waits for a reply, some requests
+// are only answered by a broadcast.
+const replyTimeout = 5 * time.Second
+
+// repl runs the interactive shell, requests are read from stdin and
+// replies and broadcasts are printed.
+func repl(ctx context.Context, addr, key, iv string, verbose bool) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	c, err := dial(ctx, addr, key, iv, verbose)
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+
+	_, err = c.ProductInfo(ctx, time.Now(), true)
+	if err != nil {
+		return connError{err}
+	}
+
+	sh := &shell{c: c}
+	ed, restore := newLineEditor("mufloctl> ", sh.complete)
+	defer restore()
+	sh.ed = ed
+
+	c.OnBroadcast(func(message string, data []byte) {
+		ed.printf("%s %s %s", time.Now().Format("15:04:05"), message, compactJSON(data))
+	})
+
+	ed.printf("Connected to %s, type help for usage (Ctrl+D to exit).", addr)
+	errC := make(chan error, 1)
+	go func() {
+		for {
+			line, err := ed.readLine()
+			if err != nil {
+				if err == io.EOF {
+					err = nil
+				}
+				errC <- err
+				return
+			}
+			if sh.exec(ctx, line) {
+				errC <- nil
+				return
+			}
+		}
+	}()
+
+	select {
+	case err = <-errC:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// shell executes the lines entered in the REPL.
+type shell struct {
+	c  *musicflow.Client
+	ed *lineEditor
+}
+
+// shortcut is a shell command that runs a mufloctl command on the
+// shell connection.
+type shortcut struct {
+	args   string
+	help   string
+	values func() []string // Completions for the first argument.
+	run    func(ctx context.Context, o options, args []string) error
+}
+
+var shortcuts = map[string]shortcut{
+	"vol":      {"[[+|-]N]", "Show or change the volume", nil, volumeCmd},
+	"mute":     {"[on|off|toggle]", "Show or change mute", onOffValues("toggle"), muteCmd},
+	"night":    {"[on|off]", "Show or change night mode", onOffValues(), nightModeCmd},
+	"input":    {"[name]", "Show or change the input", functionNames, inputCmd},
+	"eq":       {"[preset]", "Show or change the equalizer", equalizerNames, shEqualizer},
+	"bass":     {"N", "Set the bass level", nil, shLevel("bass")},
+	"treble":   {"N", "Set the treble level", nil, shLevel("treble")},
+	"woofer":   {"[N]", "Show or change the woofer level", nil, wooferCmd},
+	"sleep":    {"[minutes|off]", "Show or set the sleep timer", func() []string { return []string{"off"} }, sleepCmd},
+	"name":     {"[name]", "Show or change the speaker name", nil, nameCmd},
+	"info":     {"", "Show the speaker status", nil, infoCmd},
+	"settings": {"", "Show the settings", nil, settingsCmd},
+	"play":     {"", "Show what is playing", nil, playInfoCmd},
+	"version":  {"", "Show the firmware versions", nil, versionCmd},
+	"caps":     {"", "Show the speaker capabilities", nil, shCapabilities},
+}
+
+// exec executes the line, returns true when the shell should exit.
+func (sh *shell) exec(ctx context.Context, line string) (quit bool) {
+	line = strings.TrimSpace(line)
+	if line == "" {
+		return false
+	}
+	if strings.HasPrefix(line, "{") {
+		var req musicflow.Request
+		if err := json.Unmarshal([]byte(line), &req); err != nil {
+			sh.ed.printf("error: %v", err)
+			return false
+		}
+		sh.send(ctx, req)
+		return false
+	}
+
+	args, err := splitArgs(line)
+	if err != nil {
+		sh.ed.printf("error: %v", err)
+		return false
+	}
+	switch name := args[0]; {
+	case name == "quit" || name == "exit":
+		return true
+	case name == "help" || name == "?":
+		sh.help()
+	case shortcuts[name].run != nil:
+		sh.run(ctx, name, shortcuts[name].run, args[1:])
+	case isMessage(name):
+		req, err := parseRequest(line)
+		if err != nil {
+			sh.ed.printf("error: %v", err)
+			return false
+		}
+		sh.send(ctx, req)
+	default:
+		sh.ed.printf("error: unknown command %q, type help for usage", name)
+	}
+	return false
+}
+
+func (sh *shell) send(ctx context.Context, req musicflow.Request) {
+	ctx, cancel := context.WithTimeout(ctx, replyTimeout)
+	defer cancel()
+
+	var resp musicflow.Response
+	err := sh.c.Send(ctx, req, &resp)
+	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		sh.ed.printf("No reply to %s within %s (the speaker may reply with a broadcast)", req.Message, replyTimeout)
+	case err != nil:
+		sh.ed.printf("error: %v", err)
+	case len(resp.Data) == 0 || string(resp.Data) == "{}":
+		sh.ed.printf("%s %s", resp.Message, resp.Result)
+	default:
+		var buf bytes.Buffer
+		if json.Indent(&buf, resp.Data, "", "  ") != nil {
+			buf.Reset()
+			buf.Write(resp.Data)
+		}
+		sh.ed.printf("%s %s %s", resp.Message, resp.Result, buf.String())
+	}
+}
+
+// run runs the shortcut and prints the output.
+func (sh *shell) run(ctx context.Context, name string, run func(context.Context, options, []string) error, args []string) {
+	ctx, cancel := context.WithTimeout(ctx, replyTimeout)
+	defer cancel()
+
+	var out bytes.Buffer
+	err := run(ctx, options{output: "table", out: &out, client: sh.c}, args)
+	if s := strings.TrimRight(out.String(), "\n"); s != "" {
+		sh.ed.printf("%s", s)
+	}
+	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		sh.ed.printf("No reply to %s within %s", name, replyTimeout)
+	case err != nil:
+		sh.ed.printf("error: %v", err)
+	}
+}
+
+func (sh *shell) help() {
+	var b strings.Builder
+	b.WriteString("Commands:\n")
+	for _, name := range sortedShortcuts() {
+		fmt.Fprintf(&b, "  %-24s %s\n", name+" "+shortcuts[name].args, shortcuts[name].help)
+	}
+	fmt.Fprintf(&b, "  %-24s %s\n", "MESSAGE [key=value ...]", "Send a request, e.g. NIGHT_MODE_SET nightmode=true")
+	fmt.Fprintf(&b, "  %-24s %s\n", "MESSAGE {json}", "Send a request with JSON data")
+	fmt.Fprintf(&b, "  %-24s %s\n", `{"msg":...,"data":...}`, "Send a raw request")
+	fmt.Fprintf(&b, "  %-24s %s\n", "help", "Show this help")
+	fmt.Fprintf(&b, "  %-24s %s\n", "quit", "Exit (or Ctrl+D)")
+	b.WriteString("\nTab completes commands, messages and request fields. Broadcasts\nare shown as they arrive.")
+	sh.ed.printf("%s", b.String())
+}
+
+// complete returns the completions for the last word of line.
+func (sh *shell) complete(line string) (start int, candidates []string) {
+	start = strings.LastIndex(line, " ") + 1
+	word := line[start:]
+	prev := strings.Fields(line[:start])
+
+	if len(prev) == 0 {
+		for _, name := range append([]string{"help", "quit"}, sortedShortcuts()...) {
+			if strings.HasPrefix(name, word) {
+				candidates = append(candidates, name)
+			}
+		}
+		if word != "" {
+			for _, msg := range api.Messages() {
+				if strings.HasPrefix(msg, strings.ToUpper(word)) {
+					candidates = append(candidates, msg)
+				}
+			}
+		}
+		return start, candidates
+	}
+
+	if sc, ok := shortcuts[prev[0]]; ok {
+		if len(prev) == 1 && sc.values != nil {
+			for _, v := range sc.values() {
+				if strings.HasPrefix(v, strings.ToLower(word)) {
+					candidates = append(candidates, v)
+				}
+			}
+		}
+		return start, candidates
+	}
+
+	req := api.NewRequest(strings.ToUpper(prev[0]))
+	if req == nil || strings.Contains(word, "=") {
+		return start, nil
+	}
+	used := make(map[string]bool)
+	for _, arg := range prev[1:] {
+		used[strings.SplitN(arg, "=", 2)[0]] = true
+	}
+	for _, name := range requestFields(reflect.TypeOf(req)) {
+		if !used[name] && strings.HasPrefix(name, word) {
+			candidates = append(candidates, name+"=")
+		}
+	}
+	return start, candidates
+}
+
+func sortedShortcuts() []string {
+	var names []string
+	for name := range shortcuts {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// requestFields returns the JSON field names of the request type.
+func requestFields(t reflect.Type) []string {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	var names []string
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag := strings.Split(f.Tag.Get("json"), ",")[0]
+		switch {
+		case tag == "-":
+		case f.Anonymous && tag == "" && f.Type.Kind() == reflect.Struct:
+			names = append(names, requestFields(f.Type)...)
+		case f.PkgPath != "":
+		case tag == "":
+			names = append(names, f.Name)
+		default:
+			names = append(names, tag)
+		}
+	}
+	return names
+}
+
+// isMessage reports whether name is a known message or looks like one
+// (upper case with underscores), unknown messages can be sent too.
+func isMessage(name string) bool {
+	if api.NewRequest(strings.ToUpper(name)) != nil {
+		return true
+	}
+	for _, msg := range api.Messages() {
+		if msg == strings.ToUpper(name) {
+			return true
+		}
+	}
+	return strings.ToUpper(name) == name && strings.Contains(name, "_")
+}
+
+// parseRequest parses "MESSAGE key=value ..." or "MESSAGE {json}".
+// Values that are not valid JSON are sent as strings. Known requests
+// are decoded into the api type so that unknown keys and wrong types
+// are caught before sending, enums may be given by name.
+func parseRequest(line string) (musicflow.Request, error) {
+	msg, rest := line, ""
+	if i := strings.IndexAny(line, " \t"); i >= 0 {
+		msg, rest = line[:i], strings.TrimSpace(line[i:])
+	}
+	req := musicflow.Request{Message: strings.ToUpper(msg)}
+
+	var data []byte
+	if strings.HasPrefix(rest, "{") {
+		if !json.Valid([]byte(rest)) {
+			return req, errors.New("invalid JSON data")
+		}
+		data = []byte(rest)
+	} else if rest != "" {
+		args, err := splitArgs(rest)
+		if err != nil {
+			return req, err
+		}
+		obj := make(map[string]json.RawMessage)
+		for _, arg := range args {
+			kv := strings.SplitN(arg, "=", 2)
+			if len(kv) != 2 || kv[0] == "" {
+				return req, fmt.Errorf("expected key=value, got %q", arg)
+			}
+			v := []byte(kv[1])
+			if !json.Valid(v) {
+				v, _ = json.Marshal(kv[1])
+			}
+			obj[kv[0]] = v
+		}
+		data, _ = json.Marshal(obj)
+	}
+
+	if typed := api.NewRequest(req.Message); typed != nil {
+		if data != nil {
+			dec := json.NewDecoder(bytes.NewReader(data))
+			dec.DisallowUnknownFields()
+			if err := dec.Decode(typed); err != nil {
+				return req, fmt.Errorf("%s: %v", req.Message, err)
+			}
+		}
+		req.Data = typed
+	} else if data != nil {
+		req.Data = json.RawMessage(data)
+	}
+	return req, nil
+}
+
+// splitArgs splits the line on whitespace, double quotes group words.
+func splitArgs(line string) ([]string, error) {
+	var args []string
+	var cur strings.Builder
+	inArg, quoted := false, false
+	for _, r := range line {
+		switch {
+		case r == '"':
+			quoted = !quoted
+			inArg = true
+		case !quoted && (r == ' ' || r == '\t'):
+			if inArg {
+				args = append(args, cur.String())
+				cur.Reset()
+				inArg = false
+			}
+		default:
+			cur.WriteRune(r)
+			inArg = true
+		}
+	}
+	if quoted {
+		return nil, errors.New("unterminated quote")
+	}
+	if inArg {
+		args = append(args, cur.String())
+	}
+	return args, nil
+}
+
+func compactJSON(data []byte) string {
+	var buf bytes.Buffer
+	if json.Compact(&buf, data) != nil {
+		return string(data)
+	}
+	return buf.String()
+}
+
+func onOffValues(extra ...string) func() []string {
+	return func() []string { return append([]string{"on", "off"}, extra...) }
+}
+
+// completionName returns the name in the form accepted by the api
+// Parse functions, e.g. "opticalhdmiarc" for "Optical / HDMI ARC".
+func completionName(s string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(s) {
+		if ('a' <= r && r <= 'z') || ('0' <= r && r <= '9') {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+func functionNames() []string {
+	var names []string
+	for _, f := range api.FunctionValues() {
+		names = append(names, completionName(f.String()))
+	}
+	return names
+}
+
+func equalizerNames() []string {
+	var names []string
+	for _, e := range api.EqualizerValues() {
+		names = append(names, completionName(e.String()))
+	}
+	return names
+}
+
+// shEqualizer is eq limited to the preset, the flags of eq exit on
+// error.
+func shEqualizer(ctx context.Context, o options, args []string) error {
+	if len(args) > 1 || (len(args) == 1 && strings.HasPrefix(args[0], "-")) {
+		return errors.New("expected equalizer preset")
+	}
+	return eqCmd(ctx, o, args)
+}
+
+// shLevel returns a shortcut that sets the eq flag name.
+func shLevel(name string) func(ctx context.Context, o options, args []string) error {
+	return func(ctx context.Context, o options, args []string) error {
+		if len(args) != 1 {
+			return errors.New("expected level")
+		}
+		if _, err := strconv.Atoi(args[0]); err != nil {
+			return fmt.Errorf("invalid level %q", args[0])
+		}
+		return eqCmd(ctx, o, []string{"-" + name, args[0]})
+	}
+}
+
+func shCapabilities(ctx context.Context, o options, args []string) error {
+	c, err := o.dial(ctx)
+	if err != nil {
+		return err
+	}
+	defer o.close(c)
+
+	caps, err := c.Capabilities(ctx)
+	if err != nil {
+		return err
+	}
+	return o.print(caps, nil)
+}
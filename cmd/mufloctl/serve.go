@@ -0,0 +1,631 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mafredri/musicflow"
+	"github.com/mafredri/musicflow/api"
+)
+
+func serveCmd(ctx context.Context, o options, args []string) error {
+	fs := flag.NewFlagSet("serve", flag.ExitOnError)
+	listen := fs.String("listen", "localhost:9780", "HTTP listen `address`")
+	timeout := fs.Duration("timeout", 10*time.Second, "Timeout of the speaker requests")
+	history := fs.Int("history", 256, "Number of events kept per speaker for resuming event streams")
+	_ = fs.Parse(args)
+
+	targets, err := o.speakerTargets(ctx)
+	if err != nil {
+		return err
+	}
+	g, err := newGateway(targets, *timeout, *history)
+	if err != nil {
+		return err
+	}
+	defer g.Close()
+	g.start(ctx)
+
+	srv := &http.Server{Addr: *listen, Handler: g}
+	errC := make(chan error, 1)
+	go func() {
+		errC <- srv.ListenAndServe()
+	}()
+	fmt.Fprintf(o.out, "Serving %d speaker(s) on http://%s/speakers, API description at /openapi.json (Ctrl+C to exit)...\n", len(targets), *listen)
+
+	select {
+	case err = <-errC:
+		return err
+	case <-ctx.Done():
+		g.Close() // Ends the event streams.
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_ = srv.Shutdown(shutdownCtx)
+		return ctx.Err()
+	}
+}
+
+// speakerTargets returns the speakers selected by -all or -group, or
+// the single speaker.
+func (o options) speakerTargets(ctx context.Context) ([]target, error) {
+	if o.targets != nil {
+		return resolveTargets(ctx, o.targets)
+	}
+	addr, err := o.speakerAddr(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if addr == "" {
+		return nil, errors.New("speaker address must be provided (-addr, -speaker, -all or -group)")
+	}
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, err
+	}
+	name := o.speaker
+	if name == "" {
+		name = host
+	}
+	t := target{name: name}
+	t.Addr, t.Key, t.IV, t.Verbose = host, key, iv, &o.verbose
+	t.Port, _ = strconv.Atoi(port)
+	return []target{t}, nil
+}
+
+// gateway is the REST gateway (see serve), it keeps a connection to
+// each speaker.
+type gateway struct {
+	timeout  time.Duration
+	speakers map[string]*speakerConn
+	names    []string // In the order of the targets.
+
+	closeOnce sync.Once
+	done      chan struct{} // Closed by Close.
+}
+
+func newGateway(targets []target, timeout time.Duration, history int) (*gateway, error) {
+	g := &gateway{
+		timeout:  timeout,
+		speakers: make(map[string]*speakerConn),
+		done:     make(chan struct{}),
+	}
+	for _, t := range targets {
+		s, err := newSpeakerConn(t)
+		if err != nil {
+			return nil, err
+		}
+		s.events = newEventLog(history)
+		g.speakers[t.name] = s
+		g.names = append(g.names, t.name)
+	}
+	return g, nil
+}
+
+// start connects to the speakers and records their events until the
+// context is done.
+func (g *gateway) start(ctx context.Context) {
+	for _, s := range g.speakers {
+		s := s
+		go s.pump(ctx, g.timeout, func(_ *musicflow.Client, events <-chan musicflow.Event) {
+			for ev := range events {
+				s.events.add(newStreamEvent(s.name, ev))
+			}
+		})
+	}
+}
+
+func (g *gateway) Close() error {
+	g.closeOnce.Do(func() {
+		close(g.done)
+		for _, s := range g.speakers {
+			s.close(nil)
+		}
+	})
+	return nil
+}
+
+// speakerConn is a persistent connection to a speaker, established
+// on first use and re-established after it was lost (see pump).
+type speakerConn struct {
+	name    string
+	addr    string
+	opt     []musicflow.DialOption
+	events  *eventLog          // Recorded by the gateway.
+	metrics *musicflow.Metrics // Shared by the connections.
+
+	mu sync.Mutex
+	c  *musicflow.Client
+}
+
+func newSpeakerConn(t target) (*speakerConn, error) {
+	opt, err := dialOptions(t.Key, t.IV, t.verbose())
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", t.name, err)
+	}
+	metrics := new(musicflow.Metrics)
+	return &speakerConn{
+		name:    t.name,
+		addr:    net.JoinHostPort(t.Addr, strconv.Itoa(t.Port)),
+		opt:     append(opt, musicflow.WithMetrics(metrics)),
+		metrics: metrics,
+	}, nil
+}
+
+func (s *speakerConn) client(ctx context.Context) (*musicflow.Client, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.c != nil {
+		return s.c, nil
+	}
+	c, err := musicflow.Dial(ctx, s.addr, s.opt...)
+	if err != nil {
+		return nil, connError{err}
+	}
+	if _, err = c.ProductInfo(ctx, time.Now(), true); err != nil {
+		c.Close()
+		return nil, connError{err}
+	}
+	s.c = c
+	return c, nil
+}
+
+// close closes the connection if it is still c, nil closes any
+// connection.
+func (s *speakerConn) close(c *musicflow.Client) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.c != nil && (c == nil || c == s.c) {
+		s.c.Close()
+		s.c = nil
+	}
+}
+
+func (s *speakerConn) connected() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.c != nil
+}
+
+// route is a speaker endpoint of the gateway, relative to
+// /speakers/{speaker}. The body and reply types are used for decoding
+// and the API description (see openAPI).
+type route struct {
+	method  string
+	path    string // E.g. /alarms/{id}.
+	summary string
+	body    interface{} // Zero value of the request body type, if any.
+	reply   interface{} // Zero value of the reply type.
+	handle  func(ctx context.Context, c *musicflow.Client, req *routeRequest) (interface{}, error)
+}
+
+// routeRequest is a request to a route.
+type routeRequest struct {
+	*http.Request
+	params map[string]string // Path parameters.
+	body   interface{}       // Pointer to the decoded body.
+	raw    []byte            // Body as is.
+}
+
+// httpError is an error with an HTTP status code.
+type httpError struct {
+	status int
+	err    error
+}
+
+func (e httpError) Error() string { return e.err.Error() }
+func (e httpError) Unwrap() error { return e.err }
+
+// errorReply is the reply on errors.
+type errorReply struct {
+	Error string `json:"error"`
+}
+
+// speakerStatus is the reply of /speakers.
+type speakerStatus struct {
+	Name      string `json:"name"`
+	Addr      string `json:"addr"`
+	Connected bool   `json:"connected"`
+}
+
+var routes = []route{
+	{
+		method: "GET", path: "/info", summary: "Product info",
+		reply: api.ProductInfo{},
+		handle: func(ctx context.Context, c *musicflow.Client, _ *routeRequest) (interface{}, error) {
+			return c.ProductInfo(ctx, time.Now(), false)
+		},
+	},
+	{
+		method: "GET", path: "/settings", summary: "Settings",
+		reply: api.Settings{},
+		handle: func(ctx context.Context, c *musicflow.Client, _ *routeRequest) (interface{}, error) {
+			return c.Settings(ctx)
+		},
+	},
+	{
+		method: "GET", path: "/playinfo", summary: "What is playing",
+		reply: api.PlayInfo{},
+		handle: func(ctx context.Context, c *musicflow.Client, _ *routeRequest) (interface{}, error) {
+			return c.PlayInfo(ctx)
+		},
+	},
+	{
+		method: "GET", path: "/equalizer", summary: "Equalizer",
+		reply: api.EqualizerInfo{},
+		handle: func(ctx context.Context, c *musicflow.Client, _ *routeRequest) (interface{}, error) {
+			return c.EqualizerInfo(ctx)
+		},
+	},
+	{
+		method: "PATCH", path: "/equalizer", summary: "Change the equalizer, only the provided fields are changed",
+		body: api.EqualizerInfo{}, reply: api.EqualizerInfo{},
+		handle: func(ctx context.Context, c *musicflow.Client, req *routeRequest) (interface{}, error) {
+			var fields map[string]json.RawMessage
+			if err := json.Unmarshal(req.raw, &fields); err != nil {
+				return nil, httpError{http.StatusBadRequest, err}
+			}
+			eq := req.body.(*api.EqualizerInfo)
+			var set []musicflow.EqualizerSetting
+			if _, ok := fields["currenteq"]; ok {
+				set = append(set, musicflow.SetEqualizer(eq.CurrentEqualizer))
+			}
+			if _, ok := fields["bass"]; ok {
+				set = append(set, musicflow.SetBass(eq.Bass))
+			}
+			if _, ok := fields["treble"]; ok {
+				set = append(set, musicflow.SetTreble(eq.Treble))
+			}
+			if _, ok := fields["lrbal"]; ok {
+				set = append(set, musicflow.SetLeftRightBalance(eq.LeftRightBalance))
+			}
+			if err := c.Equalizer(ctx, set...); err != nil {
+				return nil, err
+			}
+			return c.EqualizerInfo(ctx)
+		},
+	},
+	{
+		method: "GET", path: "/volume", summary: "Volume",
+		reply:  api.VolumeChangeEvent{},
+		handle: getVolume,
+	},
+	{
+		method: "PUT", path: "/volume", summary: "Set the volume",
+		body: api.VolumeSettingRequest{}, reply: api.VolumeChangeEvent{},
+		handle: func(ctx context.Context, c *musicflow.Client, req *routeRequest) (interface{}, error) {
+			v := req.body.(*api.VolumeSettingRequest)
+			if err := c.Volume(ctx, v.Volume, v.FadeTime); err != nil {
+				return nil, err
+			}
+			return getVolume(ctx, c, req)
+		},
+	},
+	{
+		method: "GET", path: "/mute", summary: "Mute",
+		reply:  api.MuteChangeEvent{},
+		handle: getMute,
+	},
+	{
+		method: "PUT", path: "/mute", summary: "Mute or unmute",
+		body: api.MuteSetRequest{}, reply: api.MuteChangeEvent{},
+		handle: func(ctx context.Context, c *musicflow.Client, req *routeRequest) (interface{}, error) {
+			if err := c.Mute(ctx, req.body.(*api.MuteSetRequest).Mute); err != nil {
+				return nil, err
+			}
+			return getMute(ctx, c, req)
+		},
+	},
+	{
+		method: "GET", path: "/input", summary: "Input (function)",
+		reply: api.FunctionInfo{},
+		handle: func(ctx context.Context, c *musicflow.Client, _ *routeRequest) (interface{}, error) {
+			return c.FunctionInfo(ctx)
+		},
+	},
+	{
+		method: "PUT", path: "/input", summary: "Change the input, by name or number",
+		body: api.FunctionSetRequest{}, reply: api.FunctionInfo{},
+		handle: func(ctx context.Context, c *musicflow.Client, req *routeRequest) (interface{}, error) {
+			if err := c.Function(ctx, req.body.(*api.FunctionSetRequest).Type); err != nil {
+				return nil, err
+			}
+			return c.FunctionInfo(ctx)
+		},
+	},
+	{
+		method: "GET", path: "/alarms", summary: "Alarms",
+		reply: []api.Alarm{},
+		handle: func(ctx context.Context, c *musicflow.Client, _ *routeRequest) (interface{}, error) {
+			alarms, err := c.Alarms(ctx)
+			if alarms == nil && err == nil {
+				alarms = []api.Alarm{}
+			}
+			return alarms, err
+		},
+	},
+	{
+		method: "POST", path: "/alarms", summary: "Create an alarm",
+		body: api.Alarm{}, reply: api.Alarm{},
+		handle: func(ctx context.Context, c *musicflow.Client, req *routeRequest) (interface{}, error) {
+			id, err := c.AlarmCreate(ctx, *req.body.(*api.Alarm))
+			if err != nil {
+				return nil, err
+			}
+			return alarmByID(ctx, c, strconv.Itoa(id))
+		},
+	},
+	{
+		method: "GET", path: "/alarms/{id}", summary: "Alarm",
+		reply: api.Alarm{},
+		handle: func(ctx context.Context, c *musicflow.Client, req *routeRequest) (interface{}, error) {
+			return alarmByID(ctx, c, req.params["id"])
+		},
+	},
+	{
+		method: "DELETE", path: "/alarms/{id}", summary: "Delete an alarm",
+		reply: api.Alarm{},
+		handle: func(ctx context.Context, c *musicflow.Client, req *routeRequest) (interface{}, error) {
+			a, err := alarmByID(ctx, c, req.params["id"])
+			if err != nil {
+				return nil, err
+			}
+			return a, c.AlarmDelete(ctx, *a)
+		},
+	},
+	{
+		method: "POST", path: "/alarms/{id}/enable", summary: "Enable an alarm",
+		reply: api.Alarm{},
+		handle: func(ctx context.Context, c *musicflow.Client, req *routeRequest) (interface{}, error) {
+			a, err := alarmByID(ctx, c, req.params["id"])
+			if err != nil {
+				return nil, err
+			}
+			if err = c.AlarmEnable(ctx, *a); err != nil {
+				return nil, err
+			}
+			return alarmByID(ctx, c, req.params["id"])
+		},
+	},
+	{
+		method: "POST", path: "/alarms/{id}/disable", summary: "Disable an alarm",
+		reply: api.Alarm{},
+		handle: func(ctx context.Context, c *musicflow.Client, req *routeRequest) (interface{}, error) {
+			a, err := alarmByID(ctx, c, req.params["id"])
+			if err != nil {
+				return nil, err
+			}
+			if err = c.AlarmDisable(ctx, *a); err != nil {
+				return nil, err
+			}
+			return alarmByID(ctx, c, req.params["id"])
+		},
+	},
+	{
+		method: "POST", path: "/send", summary: "Send a raw message, the query parameters wait and result set the expected reply message and result (see musicflow.WaitFor). FACTORY_SET and POWER_OFF are forbidden (403), use mufloctl reset and poweroff",
+		body: musicflow.Request{}, reply: musicflow.Response{},
+		handle: func(ctx context.Context, c *musicflow.Client, req *routeRequest) (interface{}, error) {
+			var msg struct {
+				Message string          `json:"msg"`
+				Data    json.RawMessage `json:"data"`
+			}
+			if err := json.Unmarshal(req.raw, &msg); err != nil || msg.Message == "" {
+				return nil, httpError{http.StatusBadRequest, errors.New("expected {\"msg\": MESSAGE, \"data\": {...}}")}
+			}
+			switch msg.Message {
+			case api.MessageFactorySet, api.MessagePowerOff:
+				// Destructive, the speaker can't be recovered over the
+				// network (see musicflow.Confirmation).
+				return nil, httpError{http.StatusForbidden, fmt.Errorf("%s is not allowed", msg.Message)}
+			}
+			var opts []musicflow.SendOption
+			if q := req.URL.Query(); q.Get("wait") != "" {
+				opts = append(opts, musicflow.WaitFor(q.Get("wait"), q.Get("result")))
+			}
+			r := musicflow.Request{Message: msg.Message}
+			if len(msg.Data) > 0 {
+				r.Data = msg.Data
+			}
+			var reply musicflow.Response
+			if err := c.Send(ctx, r, &reply, opts...); err != nil {
+				return nil, err
+			}
+			return reply, nil
+		},
+	},
+}
+
+func getVolume(ctx context.Context, c *musicflow.Client, _ *routeRequest) (interface{}, error) {
+	info, err := c.ProductInfo(ctx, time.Now(), false)
+	if err != nil {
+		return nil, err
+	}
+	return api.VolumeChangeEvent{Volume: info.Info.Volume}, nil
+}
+
+func getMute(ctx context.Context, c *musicflow.Client, _ *routeRequest) (interface{}, error) {
+	info, err := c.ProductInfo(ctx, time.Now(), false)
+	if err != nil {
+		return nil, err
+	}
+	return api.MuteChangeEvent{Mute: info.Info.Mute}, nil
+}
+
+func alarmByID(ctx context.Context, c *musicflow.Client, arg string) (*api.Alarm, error) {
+	id, err := strconv.Atoi(arg)
+	if err != nil {
+		return nil, httpError{http.StatusBadRequest, fmt.Errorf("invalid alarm ID: %q", arg)}
+	}
+	alarms, err := c.Alarms(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, a := range alarms {
+		if a.ID == id {
+			return &a, nil
+		}
+	}
+	return nil, httpError{http.StatusNotFound, fmt.Errorf("alarm %d not found", id)}
+}
+
+// match returns the path parameters when the route matches path.
+func (rt route) match(path string) (map[string]string, bool) {
+	want := strings.Split(strings.Trim(rt.path, "/"), "/")
+	got := strings.Split(strings.Trim(path, "/"), "/")
+	if len(want) != len(got) {
+		return nil, false
+	}
+	params := make(map[string]string)
+	for i, w := range want {
+		if strings.HasPrefix(w, "{") && strings.HasSuffix(w, "}") {
+			params[strings.Trim(w, "{}")] = got[i]
+		} else if w != got[i] {
+			return nil, false
+		}
+	}
+	return params, true
+}
+
+func (g *gateway) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	path := strings.TrimSuffix(r.URL.Path, "/")
+	switch {
+	case path == "/openapi.json" && r.Method == "GET":
+		writeJSON(w, http.StatusOK, openAPI(g.names))
+		return
+	case path == "/metrics" && r.Method == "GET":
+		g.serveMetrics(w, r)
+		return
+	case path == "/speakers" && r.Method == "GET":
+		status := []speakerStatus{}
+		for _, name := range g.names {
+			s := g.speakers[name]
+			status = append(status, speakerStatus{Name: s.name, Addr: s.addr, Connected: s.connected()})
+		}
+		writeJSON(w, http.StatusOK, status)
+		return
+	case !strings.HasPrefix(path, "/speakers/"):
+		writeError(w, httpError{http.StatusNotFound, errors.New("not found")})
+		return
+	}
+
+	path = strings.TrimPrefix(path, "/speakers/")
+	i := strings.IndexByte(path, '/')
+	if i < 0 {
+		writeError(w, httpError{http.StatusNotFound, errors.New("not found")})
+		return
+	}
+	s, ok := g.speakers[path[:i]]
+	if !ok {
+		writeError(w, httpError{http.StatusNotFound, fmt.Errorf("unknown speaker %q", path[:i])})
+		return
+	}
+	path = path[i:]
+
+	switch {
+	case path == "/events" && r.Method == "GET":
+		g.serveSSE(w, r, s)
+		return
+	case path == "/events/ws" && r.Method == "GET":
+		g.serveWebSocket(w, r, s)
+		return
+	}
+
+	var allowed []string
+	for _, rt := range routes {
+		params, ok := rt.match(path)
+		if !ok {
+			continue
+		}
+		if rt.method != r.Method {
+			allowed = append(allowed, rt.method)
+			continue
+		}
+		g.serveRoute(w, r, s, rt, params)
+		return
+	}
+	if len(allowed) > 0 {
+		w.Header().Set("Allow", strings.Join(allowed, ", "))
+		writeError(w, httpError{http.StatusMethodNotAllowed, fmt.Errorf("method %s not allowed", r.Method)})
+		return
+	}
+	writeError(w, httpError{http.StatusNotFound, errors.New("not found")})
+}
+
+func (g *gateway) serveRoute(w http.ResponseWriter, r *http.Request, s *speakerConn, rt route, params map[string]string) {
+	req := &routeRequest{Request: r, params: params}
+	if rt.body != nil {
+		var err error
+		req.raw, err = ioutil.ReadAll(io.LimitReader(r.Body, 1<<20))
+		if err != nil {
+			writeError(w, httpError{http.StatusBadRequest, err})
+			return
+		}
+		req.body = reflect.New(reflect.TypeOf(rt.body)).Interface()
+		if err = json.Unmarshal(req.raw, req.body); err != nil {
+			writeError(w, httpError{http.StatusBadRequest, fmt.Errorf("invalid body: %w", err)})
+			return
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), g.timeout)
+	defer cancel()
+
+	c, err := s.client(ctx)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	v, err := rt.handle(ctx, c, req)
+	if err != nil {
+		if exitCode(err) == exitConnection {
+			// Reconnect on the next request.
+			s.close(c)
+		}
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, v)
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	enc.SetEscapeHTML(false)
+	_ = enc.Encode(v)
+}
+
+// writeError writes the error with the status code matching the exit
+// code of mufloctl.
+func writeError(w http.ResponseWriter, err error) {
+	status := http.StatusInternalServerError
+	var he httpError
+	switch {
+	case errors.As(err, &he):
+		status = he.status
+	case errors.Is(err, context.DeadlineExceeded):
+		status = http.StatusGatewayTimeout
+	default:
+		switch exitCode(err) {
+		case exitConnection:
+			status = http.StatusServiceUnavailable
+		case exitUnsupported:
+			status = http.StatusNotImplemented
+		case exitProtocol:
+			status = http.StatusBadGateway
+		}
+	}
+	writeJSON(w, status, errorReply{Error: err.Error()})
+}
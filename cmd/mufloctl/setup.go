@@ -0,0 +1,47 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+
+	"github.com/mafredri/musicflow"
+)
+
+func setupCmd(ctx context.Context, o options, args []string) error {
+	fs := flag.NewFlagSet("setup", flag.ExitOnError)
+	name := fs.String("name", "", "Speaker `name`")
+	ssid := fs.String("ssid", "", "Home network SSID")
+	tz := fs.String("tz", "", "IANA timezone `name` (default local timezone)")
+	agree := fs.Bool("agree", false, "Agree to the terms of service")
+	sharing := fs.Bool("sharing", false, "Share usage data")
+	tone := fs.Bool("tone", false, "Play the test tone to identify the speaker")
+	_ = fs.Parse(args)
+
+	if *name == "" {
+		fs.Usage()
+		return errors.New("setup: name must be provided")
+	}
+
+	c, err := dial(ctx, o.addr, key, iv, o.verbose)
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+
+	info, err := c.Provision(ctx, musicflow.ProvisionConfig{
+		Name:     *name,
+		SSID:     *ssid,
+		Agree:    *agree,
+		Sharing:  *sharing,
+		Timezone: *tz,
+		TestTone: *tone,
+		Progress: func(step string) { fmt.Fprintf(o.out, "%s...\n", step) },
+	})
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(o.out, "Speaker %q (%s) is set up.\n", info.Info.Name, info.ModelName)
+	return nil
+}
@@ -0,0 +1,289 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mafredri/musicflow"
+)
+
+// streamEvent is a speaker broadcast sent by the event stream of the
+// gateway.
+type streamEvent struct {
+	ID      uint64          `json:"id"` // Increasing per speaker, see Last-Event-ID.
+	Time    time.Time       `json:"time"`
+	Speaker string          `json:"speaker"`
+	Message string          `json:"message"`
+	Data    json.RawMessage `json:"data"` // Decoded via the api types, when known.
+}
+
+// eventLog is a bounded history of the speaker events that also
+// delivers them to the subscribers.
+type eventLog struct {
+	mu     sync.Mutex
+	size   int
+	events []streamEvent // Oldest first, at most size.
+	lastID uint64
+	subs   map[chan streamEvent]bool
+}
+
+func newEventLog(size int) *eventLog {
+	return &eventLog{size: size, subs: make(map[chan streamEvent]bool)}
+}
+
+// add assigns the ID and delivers the event. Subscribers that do not
+// keep up are dropped (their channel is closed), they can resume
+// from the history.
+func (l *eventLog) add(ev streamEvent) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.lastID++
+	ev.ID = l.lastID
+	l.events = append(l.events, ev)
+	if len(l.events) > l.size {
+		l.events = append(l.events[:0:0], l.events[len(l.events)-l.size:]...)
+	}
+	for ch := range l.subs {
+		select {
+		case ch <- ev:
+		default:
+			delete(l.subs, ch)
+			close(ch)
+		}
+	}
+}
+
+// subscribe returns the events after lastID still in the history
+// (none when resume is false) followed by new events on the channel.
+func (l *eventLog) subscribe(lastID uint64, resume bool) (backlog []streamEvent, events <-chan streamEvent, cancel func()) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if resume {
+		if lastID > l.lastID {
+			lastID = 0 // The gateway was restarted, send all.
+		}
+		for _, ev := range l.events {
+			if ev.ID > lastID {
+				backlog = append(backlog, ev)
+			}
+		}
+	}
+	ch := make(chan streamEvent, 64)
+	l.subs[ch] = true
+	return backlog, ch, func() {
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		if l.subs[ch] {
+			delete(l.subs, ch)
+			close(ch)
+		}
+	}
+}
+
+// pump keeps the speaker connected until the context is done, handle
+// is called for each connection and must drain the events (the
+// channel is closed when the connection is lost).
+func (s *speakerConn) pump(ctx context.Context, timeout time.Duration, handle func(c *musicflow.Client, events <-chan musicflow.Event)) {
+	const maxBackoff = 30 * time.Second
+	backoff := time.Second
+	for ctx.Err() == nil {
+		dialCtx, cancel := context.WithTimeout(ctx, timeout)
+		c, err := s.client(dialCtx)
+		cancel()
+		if err == nil {
+			backoff = time.Second
+			handle(c, c.Events(ctx))
+			s.close(c)
+		}
+
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+		}
+		if backoff *= 2; backoff > maxBackoff {
+			backoff = maxBackoff
+		}
+	}
+}
+
+func newStreamEvent(speaker string, ev musicflow.Event) streamEvent {
+	data := ev.Data
+	if ev.Value != nil {
+		if b, err := json.Marshal(ev.Value); err == nil {
+			data = b
+		}
+	}
+	if len(data) == 0 {
+		data = json.RawMessage("{}")
+	}
+	return streamEvent{Time: ev.Time, Speaker: speaker, Message: ev.Message, Data: data}
+}
+
+// streamRequest are the options of an event stream request.
+type streamRequest struct {
+	lastID uint64
+	resume bool
+	filter map[string]bool // Messages to send, all when empty.
+}
+
+// parseStreamRequest parses the Last-Event-ID header (or the
+// lastEventId query parameter) and the filter query parameter.
+func parseStreamRequest(r *http.Request) (streamRequest, error) {
+	var sr streamRequest
+	last := r.Header.Get("Last-Event-ID")
+	if last == "" {
+		last = r.URL.Query().Get("lastEventId")
+	}
+	if last != "" {
+		id, err := strconv.ParseUint(last, 10, 64)
+		if err != nil {
+			return sr, httpError{http.StatusBadRequest, fmt.Errorf("invalid last event ID: %q", last)}
+		}
+		sr.lastID, sr.resume = id, true
+	}
+	sr.filter = make(map[string]bool)
+	for _, msg := range strings.Split(r.URL.Query().Get("filter"), ",") {
+		if msg = strings.TrimSpace(msg); msg != "" {
+			sr.filter[strings.ToUpper(msg)] = true
+		}
+	}
+	return sr, nil
+}
+
+func (sr streamRequest) show(ev streamEvent) bool {
+	return len(sr.filter) == 0 || sr.filter[ev.Message]
+}
+
+// streamKeepAlive is the interval of keep-alive messages, so that
+// proxies do not close idle streams.
+const streamKeepAlive = 30 * time.Second
+
+// serveSSE serves the event stream of the speaker as server-sent
+// events.
+func (g *gateway) serveSSE(w http.ResponseWriter, r *http.Request, s *speakerConn) {
+	sr, err := parseStreamRequest(r)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeError(w, fmt.Errorf("streaming not supported by the server"))
+		return
+	}
+
+	backlog, events, cancel := s.events.subscribe(sr.lastID, sr.resume)
+	defer cancel()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprintf(w, "retry: 1000\n\n")
+	flusher.Flush()
+
+	send := func(ev streamEvent) bool {
+		if !sr.show(ev) {
+			return true
+		}
+		b, _ := json.Marshal(ev)
+		_, err := fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", ev.ID, ev.Message, b)
+		flusher.Flush()
+		return err == nil
+	}
+	for _, ev := range backlog {
+		if !send(ev) {
+			return
+		}
+	}
+
+	keepAlive := time.NewTicker(streamKeepAlive)
+	defer keepAlive.Stop()
+	for {
+		select {
+		case ev, ok := <-events:
+			if !ok || !send(ev) {
+				return
+			}
+		case <-keepAlive.C:
+			if _, err := fmt.Fprintf(w, ": keep-alive\n\n"); err != nil {
+				return
+			}
+			flusher.Flush()
+		case <-r.Context().Done():
+			return
+		case <-g.done:
+			return
+		}
+	}
+}
+
+// serveWebSocket serves the event stream of the speaker over a
+// WebSocket, one JSON text message per event.
+func (g *gateway) serveWebSocket(w http.ResponseWriter, r *http.Request, s *speakerConn) {
+	sr, err := parseStreamRequest(r)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	ws, err := upgradeWebSocket(w, r)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	defer ws.Close()
+
+	backlog, events, cancel := s.events.subscribe(sr.lastID, sr.resume)
+	defer cancel()
+
+	closed := make(chan struct{})
+	go func() {
+		defer close(closed)
+		_ = ws.readLoop()
+	}()
+
+	send := func(ev streamEvent) bool {
+		if !sr.show(ev) {
+			return true
+		}
+		b, _ := json.Marshal(ev)
+		return ws.writeFrame(wsText, b) == nil
+	}
+	for _, ev := range backlog {
+		if !send(ev) {
+			return
+		}
+	}
+
+	keepAlive := time.NewTicker(streamKeepAlive)
+	defer keepAlive.Stop()
+	for {
+		select {
+		case ev, ok := <-events:
+			if !ok {
+				// Too slow, the client can resume from the last ID.
+				_ = ws.writeFrame(wsClose, []byte{0x03, 0xf0}) // 1008, policy violation.
+				return
+			}
+			if !send(ev) {
+				return
+			}
+		case <-keepAlive.C:
+			if ws.writeFrame(wsPing, nil) != nil {
+				return
+			}
+		case <-closed:
+			return
+		case <-g.done:
+			_ = ws.writeFrame(wsClose, []byte{0x03, 0xe9}) // 1001, going away.
+			return
+		}
+	}
+}
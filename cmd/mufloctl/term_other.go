@@ -0,0 +1,12 @@
+//go:build !linux && !darwin
+// +build !linux,!darwin
+
+package main
+
+import "errors"
+
+// makeRaw is not supported on this platform, the REPL falls back to
+// reading lines without editing.
+func makeRaw(fd int) (restore func(), err error) {
+	return nil, errors.New("raw terminal mode not supported")
+}
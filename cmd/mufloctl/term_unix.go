@@ -0,0 +1,39 @@
+//go:build linux || darwin
+// +build linux darwin
+
+package main
+
+import (
+	"syscall"
+	"unsafe"
+)
+
+// makeRaw puts the terminal into raw mode, keeping output processing
+// so that "\n" still moves to the start of the line. Returns an error
+// when fd is not a terminal.
+func makeRaw(fd int) (restore func(), err error) {
+	var old syscall.Termios
+	if err := ioctlTermios(fd, ioctlGetTermios, &old); err != nil {
+		return nil, err
+	}
+
+	t := old
+	t.Iflag &^= syscall.IGNBRK | syscall.BRKINT | syscall.PARMRK | syscall.ISTRIP | syscall.INLCR | syscall.IGNCR | syscall.ICRNL | syscall.IXON
+	t.Lflag &^= syscall.ECHO | syscall.ECHONL | syscall.ICANON | syscall.ISIG | syscall.IEXTEN
+	t.Cflag &^= syscall.CSIZE | syscall.PARENB
+	t.Cflag |= syscall.CS8
+	t.Cc[syscall.VMIN] = 1
+	t.Cc[syscall.VTIME] = 0
+	if err := ioctlTermios(fd, ioctlSetTermios, &t); err != nil {
+		return nil, err
+	}
+	return func() { _ = ioctlTermios(fd, ioctlSetTermios, &old) }, nil
+}
+
+func ioctlTermios(fd int, req uintptr, t *syscall.Termios) error {
+	_, _, errno := syscall.Syscall(syscall.SYS_IOCTL, uintptr(fd), req, uintptr(unsafe.Pointer(t)))
+	if errno != 0 {
+		return errno
+	}
+	return nil
+}
@@ -9,7 +9,6 @@ import (
 	"os"
 	"time"
 
-	"github.com/mafredri/goodspeaker"
 	"github.com/mafredri/musicflow"
 	"github.com/mafredri/musicflow/api"
 )
@@ -20,19 +19,12 @@ func testRun(ctx context.Context, addr, key, iv string) error {
 
 	log.Printf("Connecting to %s...", addr)
 
-	var gsOpt []goodspeaker.Option
-	if key != "" && iv != "" {
-		aes, err := goodspeaker.WithAES([]byte(key), []byte(iv))
-		if err != nil {
-			return err
-		}
-		gsOpt = append(gsOpt, aes)
-	}
-	opt := []musicflow.DialOption{
-		musicflow.WithGoodspeakerOption(gsOpt...),
-		musicflow.WithLogger(log.New(os.Stderr, "[musicflow] ", log.Flags())),
+	opt, err := dialOptions(key, iv, true)
+	if err != nil {
+		return err
 	}
-
+	// Report differences between the api types and the firmware.
+	opt = append(opt, musicflow.WithStrictDecoding())
 	c, err := musicflow.Dial(ctx, addr, opt...)
 	if err != nil {
 		return err
@@ -81,13 +73,22 @@ func testRun(ctx context.Context, addr, key, iv string) error {
 		return err
 	}
 
+	caps, err := c.Capabilities(ctx)
+	if err != nil {
+		return err
+	}
+	log.Printf("%#v", caps)
+
 	// Change equalizer settings (without saving).
-	err = c.Equalizer(ctx,
-		musicflow.SetBass(eqinfo.Bass+2),
-		musicflow.SetTreble(eqinfo.Treble+2),
+	eq := []musicflow.EqualizerSetting{
+		musicflow.SetBass(eqinfo.Bass + 2),
+		musicflow.SetTreble(eqinfo.Treble + 2),
 		musicflow.SetEqualizer(api.EqualizerASC),
-		musicflow.SetLeftRightBalance(eqinfo.LeftRightBalance-2),
-	)
+	}
+	if caps.Balance {
+		eq = append(eq, musicflow.SetLeftRightBalance(eqinfo.LeftRightBalance-2))
+	}
+	err = c.Equalizer(ctx, eq...)
 	if err != nil {
 		return err
 	}
@@ -120,7 +121,7 @@ func testRun(ctx context.Context, addr, key, iv string) error {
 	}
 	log.Printf("%#v", funcinfo)
 
-	err = c.Function(ctx, api.FunctionARC)
+	err = c.Function(ctx, api.FunctionOpticalARC)
 	if err != nil {
 		return err
 	}
@@ -172,6 +173,10 @@ func testRun(ctx context.Context, addr, key, iv string) error {
 	}
 	log.Printf("AlarmSate: %v", alarmOn)
 
+	for _, d := range c.SchemaDrift() {
+		log.Printf("Schema drift: %s (%s): unknown keys %v, missing keys %v", d.Message, d.Type, d.Unknown, d.Missing)
+	}
+
 	go func() {
 		s := bufio.NewScanner(os.Stdin)
 		for s.Scan() {
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+
+	"github.com/mafredri/musicflow"
+)
+
+func updateCmd(ctx context.Context, o options, args []string) error {
+	fs := flag.NewFlagSet("update", flag.ExitOnError)
+	check := fs.Bool("check", false, "Only check if an update is available")
+	_ = fs.Parse(args)
+
+	c, err := o.dial(ctx)
+	if err != nil {
+		return err
+	}
+	defer o.close(c)
+
+	available, err := c.CheckForUpdate(ctx)
+	if err != nil {
+		return err
+	}
+	if !available {
+		fmt.Fprintln(o.out, "No update available.")
+		return nil
+	}
+	if *check {
+		fmt.Fprintln(o.out, "Update available.")
+		return nil
+	}
+
+	fmt.Fprintln(o.out, "Updating firmware, do not power off the speaker...")
+	var last musicflow.UpdateStatus
+	err = c.Update(ctx, func(s musicflow.UpdateStatus) {
+		// Only print every 10% to avoid flooding the terminal.
+		if s.Phase == last.Phase && s.Progress/10 == last.Progress/10 && s.Progress != 0 {
+			return
+		}
+		last = s
+		fmt.Fprintln(o.out, s)
+	})
+	if err != nil {
+		return err
+	}
+
+	v, err := c.SystemVersion(ctx)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(o.out, "Update complete, firmware version: %s\n", v.Be)
+	return nil
+}
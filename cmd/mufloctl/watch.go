@@ -0,0 +1,185 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/mafredri/musicflow"
+	"github.com/mafredri/musicflow/api"
+)
+
+func watchCmd(ctx context.Context, o options, args []string) error {
+	fs := flag.NewFlagSet("watch", flag.ExitOnError)
+	filter := fs.String("filter", "", "Comma separated `messages` to show, e.g. VOLUME_CHANGE,MUTE_CHANGE (default all)")
+	jsonLines := fs.Bool("json", false, "Print JSON lines (also with -output json)")
+	_ = fs.Parse(args)
+
+	show := make(map[string]bool)
+	for _, msg := range strings.Split(*filter, ",") {
+		if msg = strings.TrimSpace(msg); msg != "" {
+			show[strings.ToUpper(msg)] = true
+		}
+	}
+
+	c, err := o.dial(ctx)
+	if err != nil {
+		return err
+	}
+	defer o.close(c)
+
+	w, err := newWatcher(ctx, c)
+	if err != nil {
+		return err
+	}
+	enc := json.NewEncoder(o.out)
+	enc.SetEscapeHTML(false)
+
+	for ev := range c.Events(ctx) {
+		text := w.describe(ev)
+		if len(show) > 0 && !show[ev.Message] {
+			continue
+		}
+		if *jsonLines || o.output == "json" {
+			line := watchLine{
+				Time:    ev.Time.Format(time.RFC3339Nano),
+				Message: ev.Message,
+				Text:    text,
+				Data:    ev.Data,
+			}
+			if len(line.Data) == 0 {
+				line.Data = json.RawMessage("{}")
+			}
+			if err = enc.Encode(line); err != nil {
+				return err
+			}
+			continue
+		}
+		fmt.Fprintf(o.out, "%s %s\n", ev.Time.Format("15:04:05"), text)
+	}
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	return connError{errors.New("watch: connection lost")}
+}
+
+// watchLine is the JSON output of watch, one per line.
+type watchLine struct {
+	Time    string          `json:"time"` // RFC 3339.
+	Message string          `json:"message"`
+	Text    string          `json:"text"` // Same as the timeline.
+	Data    json.RawMessage `json:"data"` // As sent by the speaker.
+}
+
+// watcher keeps track of the speaker state to describe changes.
+type watcher struct {
+	volume   int
+	function api.Function
+	connect  int
+}
+
+func newWatcher(ctx context.Context, c *musicflow.Client) (*watcher, error) {
+	info, err := c.ProductInfo(ctx, time.Now(), false)
+	if err != nil {
+		return nil, err
+	}
+	fn, err := c.FunctionInfo(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &watcher{
+		volume:   info.Info.Volume,
+		function: fn.Type,
+		connect:  fn.Connect,
+	}, nil
+}
+
+// describe returns a human-readable description of the event and
+// updates the state.
+func (w *watcher) describe(ev musicflow.Event) string {
+	switch v := ev.Value.(type) {
+	case *api.VolumeChangeEvent:
+		old := w.volume
+		w.volume = v.Volume
+		if old != v.Volume {
+			return fmt.Sprintf("volume %d→%d", old, v.Volume)
+		}
+		return fmt.Sprintf("volume %d", v.Volume)
+	case *api.MuteChangeEvent:
+		if v.Mute {
+			return "muted"
+		}
+		return "unmuted"
+	case *api.FunctionInfoEvent:
+		return w.describeFunction(&v.FunctionInfo)
+	case *api.FunctionInfo:
+		return w.describeFunction(v)
+	case *api.AlarmStateEvent:
+		if v.On {
+			return "alarm started"
+		}
+		return "alarm stopped"
+	case *api.SpeakerNameChangeEvent:
+		return fmt.Sprintf("name changed to %q", v.Name)
+	case *api.UpdateProgressEvent:
+		return fmt.Sprintf("firmware update %d%%", v.Progress)
+	case *api.UpdateDownResultEvent:
+		return "firmware download " + succeeded(v.Result)
+	case *api.UpdateStartWriteEvent:
+		return "firmware update writing"
+	case *api.UpdateStartRebootEvent:
+		return "firmware update rebooting"
+	case *api.UpdateCompleteEvent:
+		return "firmware update " + succeeded(v.Complete)
+	case *api.UpdateResultEvent:
+		return "firmware update " + succeeded(v.Result)
+	case *api.SpeakerAddEvent:
+		return "speaker added"
+	case *api.BluetoothStandbyStateEvent:
+		return "bluetooth standby " + onOff(v.On)
+	case *api.BluetoothLimitSetEvent:
+		return "bluetooth connection limit " + onOff(v.Limit)
+	case *api.GroupCompressStateEvent:
+		return fmt.Sprintf("group compress %d", v.Status)
+	case *api.UsageShareSetEvent:
+		return "usage sharing " + onOff(v.Sharing)
+	case *api.EqualizerInfo:
+		return fmt.Sprintf("equalizer %s (bass %d, treble %d)", v.CurrentEqualizer, v.Bass, v.Treble)
+	case *api.PlayInfo:
+		if v.Title == "" {
+			return "nothing playing"
+		}
+		if v.Artist != "" {
+			return fmt.Sprintf("playing %q by %s", v.Title, v.Artist)
+		}
+		return fmt.Sprintf("playing %q", v.Title)
+	default:
+		return strings.TrimSpace(ev.Message + " " + compactJSON(ev.Data))
+	}
+}
+
+func (w *watcher) describeFunction(fn *api.FunctionInfo) string {
+	old, oldConnect := w.function, w.connect
+	w.function, w.connect = fn.Type, fn.Connect
+	switch {
+	case old != fn.Type:
+		return fmt.Sprintf("input changed to %s%s", fn.Type, connected(fn))
+	case oldConnect != fn.Connect && fn.Connect != 0:
+		return fmt.Sprintf("input %s connected", fn.Type)
+	case oldConnect != fn.Connect:
+		return fmt.Sprintf("input %s disconnected", fn.Type)
+	default:
+		return fmt.Sprintf("input %s%s", fn.Type, connected(fn))
+	}
+}
+
+func succeeded(ok bool) string {
+	if ok {
+		return "succeeded"
+	}
+	return "failed"
+}
@@ -0,0 +1,187 @@
+package main
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// websocketGUID is used in the opening handshake, see RFC 6455.
+const websocketGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+// WebSocket opcodes.
+const (
+	wsText  = 0x1
+	wsClose = 0x8
+	wsPing  = 0x9
+	wsPong  = 0xa
+)
+
+// wsConn is a minimal server side WebSocket connection, enough for
+// sending text messages. Messages from the client are only read for
+// the control frames.
+type wsConn struct {
+	conn net.Conn
+	r    *bufio.Reader
+
+	mu sync.Mutex // Protects writes.
+	w  *bufio.Writer
+}
+
+// upgradeWebSocket performs the opening handshake.
+func upgradeWebSocket(w http.ResponseWriter, r *http.Request) (*wsConn, error) {
+	if !headerHas(r.Header, "Connection", "upgrade") || !strings.EqualFold(r.Header.Get("Upgrade"), "websocket") {
+		return nil, httpError{http.StatusBadRequest, errors.New("expected a WebSocket upgrade")}
+	}
+	if r.Header.Get("Sec-WebSocket-Version") != "13" {
+		w.Header().Set("Sec-WebSocket-Version", "13")
+		return nil, httpError{http.StatusUpgradeRequired, errors.New("unsupported WebSocket version")}
+	}
+	key := r.Header.Get("Sec-WebSocket-Key")
+	if key == "" {
+		return nil, httpError{http.StatusBadRequest, errors.New("missing Sec-WebSocket-Key")}
+	}
+	hj, ok := w.(http.Hijacker)
+	if !ok {
+		return nil, errors.New("WebSocket not supported by the server")
+	}
+	conn, rw, err := hj.Hijack()
+	if err != nil {
+		return nil, err
+	}
+
+	sum := sha1.Sum([]byte(key + websocketGUID))
+	fmt.Fprintf(rw, "HTTP/1.1 101 Switching Protocols\r\n"+
+		"Upgrade: websocket\r\n"+
+		"Connection: Upgrade\r\n"+
+		"Sec-WebSocket-Accept: %s\r\n\r\n", base64.StdEncoding.EncodeToString(sum[:]))
+	if err = rw.Flush(); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return &wsConn{conn: conn, r: rw.Reader, w: rw.Writer}, nil
+}
+
+// headerHas reports whether the comma separated header contains
+// value, case-insensitively.
+func headerHas(h http.Header, name, value string) bool {
+	for _, v := range h[http.CanonicalHeaderKey(name)] {
+		for _, s := range strings.Split(v, ",") {
+			if strings.EqualFold(strings.TrimSpace(s), value) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (ws *wsConn) Close() error {
+	return ws.conn.Close()
+}
+
+// writeFrame writes an unfragmented frame, server frames are not
+// masked.
+func (ws *wsConn) writeFrame(op byte, payload []byte) error {
+	ws.mu.Lock()
+	defer ws.mu.Unlock()
+
+	_ = ws.conn.SetWriteDeadline(time.Now().Add(10 * time.Second))
+	header := []byte{0x80 | op, 0}
+	switch n := len(payload); {
+	case n < 126:
+		header[1] = byte(n)
+	case n <= 0xffff:
+		header[1] = 126
+		header = append(header, 0, 0)
+		binary.BigEndian.PutUint16(header[2:], uint16(n))
+	default:
+		header[1] = 127
+		header = append(header, 0, 0, 0, 0, 0, 0, 0, 0)
+		binary.BigEndian.PutUint64(header[2:], uint64(n))
+	}
+	if _, err := ws.w.Write(header); err != nil {
+		return err
+	}
+	if _, err := ws.w.Write(payload); err != nil {
+		return err
+	}
+	return ws.w.Flush()
+}
+
+// maxWebSocketPayload limits the frames read from the client.
+const maxWebSocketPayload = 1 << 16
+
+// readFrame reads a frame from the client, client frames must be
+// masked.
+func (ws *wsConn) readFrame() (op byte, payload []byte, err error) {
+	var header [2]byte
+	if _, err = io.ReadFull(ws.r, header[:]); err != nil {
+		return 0, nil, err
+	}
+	op = header[0] & 0x0f
+	if header[1]&0x80 == 0 {
+		return 0, nil, errors.New("websocket: unmasked client frame")
+	}
+	n := uint64(header[1] & 0x7f)
+	switch n {
+	case 126:
+		var b [2]byte
+		if _, err = io.ReadFull(ws.r, b[:]); err != nil {
+			return 0, nil, err
+		}
+		n = uint64(binary.BigEndian.Uint16(b[:]))
+	case 127:
+		var b [8]byte
+		if _, err = io.ReadFull(ws.r, b[:]); err != nil {
+			return 0, nil, err
+		}
+		n = binary.BigEndian.Uint64(b[:])
+	}
+	if n > maxWebSocketPayload {
+		return 0, nil, errors.New("websocket: frame too large")
+	}
+	var mask [4]byte
+	if _, err = io.ReadFull(ws.r, mask[:]); err != nil {
+		return 0, nil, err
+	}
+	payload = make([]byte, n)
+	if _, err = io.ReadFull(ws.r, payload); err != nil {
+		return 0, nil, err
+	}
+	for i := range payload {
+		payload[i] ^= mask[i%4]
+	}
+	return op, payload, nil
+}
+
+// readLoop answers the control frames from the client and returns
+// when the connection is closed.
+func (ws *wsConn) readLoop() error {
+	for {
+		op, payload, err := ws.readFrame()
+		if err != nil {
+			return err
+		}
+		switch op {
+		case wsPing:
+			if err = ws.writeFrame(wsPong, payload); err != nil {
+				return err
+			}
+		case wsClose:
+			if len(payload) > 2 {
+				payload = payload[:2] // Echo the status code.
+			}
+			_ = ws.writeFrame(wsClose, payload)
+			return nil
+		}
+	}
+}
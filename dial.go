@@ -17,9 +17,11 @@ func (c *connWrapper) Conn() net.Conn { return c.c }
 func (c *connWrapper) Close() error   { return c.c.Close() }
 
 type dialOptions struct {
-	addr   string
-	gsOpts []goodspeaker.Option
-	logger Logger
+	addr    string
+	gsOpts  []goodspeaker.Option
+	logger  Logger
+	strict  bool
+	metrics *Metrics
 }
 
 // A DialOption sets custom options for Dial.
@@ -44,6 +46,7 @@ func Dial(ctx context.Context, addr string, opts ...DialOption) (*Client, error)
 	if err != nil {
 		return nil, err
 	}
+	o.metrics.connected(false)
 	return newClient(conn, o), nil
 }
 
@@ -0,0 +1,215 @@
+package musicflow
+
+import (
+	"context"
+	"net"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	errors "golang.org/x/xerrors"
+
+	"github.com/mafredri/musicflow/api"
+)
+
+// DefaultPort is the port Music Flow speakers listen on.
+const DefaultPort = 9741
+
+// Speaker represents a discovered Music Flow speaker.
+type Speaker struct {
+	Addr         string // Address (host:port) of the speaker.
+	Name         string
+	ModelName    string
+	PetName      string
+	WirelessMAC  string
+	BluetoothMAC string
+
+	Info *api.ProductInfo
+}
+
+type discoverOptions struct {
+	port        int
+	hosts       []string
+	timeout     time.Duration
+	concurrency int
+	dialOpts    []DialOption
+}
+
+// A DiscoverOption sets custom options for Discover.
+type DiscoverOption func(*discoverOptions)
+
+// WithDiscoverPort sets the port that is probed, defaults to
+// DefaultPort.
+func WithDiscoverPort(port int) DiscoverOption {
+	return func(o *discoverOptions) {
+		o.port = port
+	}
+}
+
+// WithDiscoverHosts probes the provided hosts (or host:port) instead
+// of scanning the local networks, e.g. for probing known addresses or
+// a local fake speaker.
+func WithDiscoverHosts(hosts ...string) DiscoverOption {
+	return func(o *discoverOptions) {
+		o.hosts = append(o.hosts, hosts...)
+	}
+}
+
+// WithDiscoverTimeout sets the time spent on each host, defaults to
+// two seconds.
+func WithDiscoverTimeout(d time.Duration) DiscoverOption {
+	return func(o *discoverOptions) {
+		o.timeout = d
+	}
+}
+
+// WithDiscoverDialOption sets the option(s) used when connecting to a
+// speaker, e.g. the encryption.
+func WithDiscoverDialOption(opt ...DialOption) DiscoverOption {
+	return func(o *discoverOptions) {
+		o.dialOpts = append(o.dialOpts, opt...)
+	}
+}
+
+// Discover finds Music Flow speakers by probing the speaker port on
+// all hosts in the local IPv4 networks (at most a /24 per interface).
+// Each speaker that answers is identified via product info.
+//
+// The speakers might also announce themselves via multicast, but this
+// has not been observed and is not used.
+func Discover(ctx context.Context, opts ...DiscoverOption) ([]Speaker, error) {
+	o := discoverOptions{
+		port:        DefaultPort,
+		timeout:     2 * time.Second,
+		concurrency: 64,
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	hosts := o.hosts
+	if len(hosts) == 0 {
+		var err error
+		hosts, err = localHosts()
+		if err != nil {
+			return nil, errors.Errorf("Discover failed: %w", err)
+		}
+	}
+
+	var (
+		mu       sync.Mutex
+		speakers []Speaker
+		sem      = make(chan struct{}, o.concurrency)
+		done     sync.WaitGroup
+	)
+	for _, host := range hosts {
+		addr := host
+		if _, _, err := net.SplitHostPort(host); err != nil {
+			addr = net.JoinHostPort(host, strconv.Itoa(o.port))
+		}
+
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+			done.Wait()
+			return nil, ctx.Err()
+		}
+		done.Add(1)
+		go func() {
+			defer done.Done()
+			defer func() { <-sem }()
+
+			s, err := probe(ctx, addr, o)
+			if err != nil {
+				return
+			}
+			mu.Lock()
+			speakers = append(speakers, s)
+			mu.Unlock()
+		}()
+	}
+	done.Wait()
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	sort.Slice(speakers, func(i, j int) bool {
+		return speakers[i].Addr < speakers[j].Addr
+	})
+	return speakers, nil
+}
+
+// probe identifies the speaker at addr.
+func probe(ctx context.Context, addr string, o discoverOptions) (Speaker, error) {
+	ctx, cancel := context.WithTimeout(ctx, o.timeout)
+	defer cancel()
+
+	c, err := Dial(ctx, addr, o.dialOpts...)
+	if err != nil {
+		return Speaker{}, err
+	}
+	defer c.Close()
+
+	info, err := c.ProductInfo(ctx, time.Now(), false)
+	if err != nil {
+		return Speaker{}, err
+	}
+	return Speaker{
+		Addr:         addr,
+		Name:         info.Info.Name,
+		ModelName:    info.ModelName,
+		PetName:      info.PetName,
+		WirelessMAC:  info.Info.WirelessMAC,
+		BluetoothMAC: info.Info.BluetoothMAC,
+		Info:         info,
+	}, nil
+}
+
+// localHosts returns all hosts in the local IPv4 networks, excluding
+// our own addresses. Networks larger than /24 are limited to the /24
+// containing our address.
+func localHosts() ([]string, error) {
+	addrs, err := net.InterfaceAddrs()
+	if err != nil {
+		return nil, err
+	}
+
+	var hosts []string
+	seen := make(map[string]bool)
+	for _, a := range addrs {
+		n, ok := a.(*net.IPNet)
+		if !ok {
+			continue
+		}
+		ip := n.IP.To4()
+		if ip == nil || ip.IsLoopback() || ip.IsLinkLocalUnicast() {
+			continue
+		}
+		seen[ip.String()] = true
+
+		ones, bits := n.Mask.Size()
+		if bits == 8*net.IPv6len {
+			ones -= 8 * (net.IPv6len - net.IPv4len)
+		}
+		if ones < 24 {
+			ones = 24
+		}
+		network := ip.Mask(net.CIDRMask(ones, 32))
+		size := 1 << uint(32-ones)
+		// Skip network and broadcast addresses.
+		for i := 1; i < size-1; i++ {
+			h := make(net.IP, 4)
+			copy(h, network)
+			for b, j := 3, i; b >= 0 && j > 0; b, j = b-1, j>>8 {
+				h[b] |= byte(j)
+			}
+			if !seen[h.String()] {
+				seen[h.String()] = true
+				hosts = append(hosts, h.String())
+			}
+		}
+	}
+	return hosts, nil
+}
@@ -0,0 +1,154 @@
+package musicflow
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"runtime"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mafredri/goodspeaker"
+
+	"github.com/mafredri/musicflow/api"
+)
+
+// fakeSpeaker answers requests on 127.0.0.1 with plain text messages,
+// reply returns the response to a request (ok is false for no reply).
+type fakeSpeaker struct {
+	l     net.Listener
+	reply func(req Response) (resp Response, ok bool)
+	wg    sync.WaitGroup
+
+	mu    sync.Mutex
+	conns []net.Conn
+}
+
+func newFakeSpeaker(t *testing.T, reply func(req Response) (Response, bool)) *fakeSpeaker {
+	t.Helper()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &fakeSpeaker{l: l, reply: reply}
+	s.wg.Add(1)
+	go s.serve()
+	t.Cleanup(s.close)
+	return s
+}
+
+func (s *fakeSpeaker) addr() string { return s.l.Addr().String() }
+
+func (s *fakeSpeaker) serve() {
+	defer s.wg.Done()
+	for {
+		conn, err := s.l.Accept()
+		if err != nil {
+			return
+		}
+		s.mu.Lock()
+		s.conns = append(s.conns, conn)
+		s.mu.Unlock()
+
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			defer conn.Close()
+
+			dec := json.NewDecoder(goodspeaker.NewReader(conn))
+			w := goodspeaker.NewWriter(conn)
+			for {
+				var req Response
+				if err := dec.Decode(&req); err != nil {
+					return
+				}
+				resp, ok := s.reply(req)
+				if !ok {
+					continue
+				}
+				b, _ := json.Marshal(resp)
+				if _, err := w.Write(b); err != nil {
+					return
+				}
+			}
+		}()
+	}
+}
+
+func (s *fakeSpeaker) close() {
+	s.l.Close()
+	s.mu.Lock()
+	for _, conn := range s.conns {
+		conn.Close()
+	}
+	s.mu.Unlock()
+	s.wg.Wait()
+}
+
+func productInfoReply(name string) func(Response) (Response, bool) {
+	return func(req Response) (Response, bool) {
+		if req.Message != api.MessageProductInfo {
+			return Response{}, false
+		}
+		data, _ := json.Marshal(api.ProductInfo{
+			ModelName: "LAS750M",
+			Info:      api.ProductInfoInfo{Name: name, WirelessMAC: "00:11:22:33:44:55"},
+		})
+		return Response{Message: req.Message, Result: "OK", Data: data}, true
+	}
+}
+
+func TestDiscover(t *testing.T) {
+	s := newFakeSpeaker(t, productInfoReply("Living room"))
+
+	// Nothing listens on a closed listener.
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	closed := l.Addr().String()
+	l.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	before := runtime.NumGoroutine()
+
+	speakers, err := Discover(ctx, WithDiscoverHosts(s.addr(), closed), WithDiscoverTimeout(time.Second))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(speakers) != 1 {
+		t.Fatalf("Discover() found %d speakers, want 1: %+v", len(speakers), speakers)
+	}
+	got := speakers[0]
+	if got.Addr != s.addr() || got.Name != "Living room" || got.ModelName != "LAS750M" || got.WirelessMAC != "00:11:22:33:44:55" {
+		t.Errorf("Discover() = %+v, want the fake speaker at %s", got, s.addr())
+	}
+
+	// The probe clients must not leave goroutines behind.
+	for i := 0; runtime.NumGoroutine() > before; i++ {
+		if i == 100 {
+			buf := make([]byte, 1<<16)
+			t.Fatalf("goroutines leaked, %d > %d:\n%s", runtime.NumGoroutine(), before, buf[:runtime.Stack(buf, true)])
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestDiscoverNoReply(t *testing.T) {
+	// Accepts the connection but never answers, e.g. another service.
+	s := newFakeSpeaker(t, func(Response) (Response, bool) { return Response{}, false })
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	speakers, err := Discover(ctx, WithDiscoverHosts(s.addr()), WithDiscoverTimeout(100*time.Millisecond))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(speakers) != 0 {
+		t.Errorf("Discover() = %+v, want none", speakers)
+	}
+}
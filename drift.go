@@ -0,0 +1,221 @@
+package musicflow
+
+import (
+	"encoding/json"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/mafredri/musicflow/api"
+)
+
+// WithStrictDecoding compares every reply and known event (see
+// api.NewEvent) to the api types and records the differences, see
+// Client.SchemaDrift. Useful for keeping the api types up to date as
+// the firmware evolves. Differences are logged but never fail a
+// request.
+func WithStrictDecoding() DialOption {
+	return func(o *dialOptions) {
+		o.strict = true
+	}
+}
+
+// SchemaDrift describes the differences between the JSON sent by the
+// speaker and the api type for a message. Nested keys are separated
+// by dots and array elements are denoted by [], e.g. "info.eqlist" or
+// "c4acplist[].pkgId".
+type SchemaDrift struct {
+	Message string
+	Type    string   // The api type, e.g. "*api.ProductInfo".
+	Unknown []string // Keys sent by the speaker, not in the api type.
+	Missing []string // Keys in the api type, not sent by the speaker.
+	Count   int      // Number of messages with differences.
+}
+
+// driftReport collects the schema drift per message.
+type driftReport struct {
+	mu sync.Mutex
+	m  map[string]*SchemaDrift
+}
+
+// SchemaDrift returns the differences between the api types and the
+// messages received on this client, sorted by message. Always empty
+// unless WithStrictDecoding is used.
+func (c *Client) SchemaDrift() []SchemaDrift {
+	c.drift.mu.Lock()
+	defer c.drift.mu.Unlock()
+
+	var drift []SchemaDrift
+	for _, d := range c.drift.m {
+		d := *d
+		d.Unknown = append([]string(nil), d.Unknown...)
+		d.Missing = append([]string(nil), d.Missing...)
+		drift = append(drift, d)
+	}
+	sort.Slice(drift, func(i, j int) bool {
+		return drift[i].Message < drift[j].Message
+	})
+	return drift
+}
+
+// checkDrift compares data to v and records the differences.
+func (c *Client) checkDrift(message string, data []byte, v interface{}) {
+	if len(data) == 0 {
+		data = []byte("{}")
+	}
+	var unknown, missing []string
+	compareSchema(data, reflect.TypeOf(v), "", &unknown, &missing)
+	if len(unknown) == 0 && len(missing) == 0 {
+		return
+	}
+
+	c.drift.mu.Lock()
+	defer c.drift.mu.Unlock()
+	if c.drift.m == nil {
+		c.drift.m = make(map[string]*SchemaDrift)
+	}
+	d, ok := c.drift.m[message]
+	if !ok {
+		d = &SchemaDrift{Message: message, Type: reflect.TypeOf(v).String()}
+		c.drift.m[message] = d
+	}
+	d.Count++
+	for _, k := range unknown {
+		if addKey(&d.Unknown, k) {
+			c.log().Printf("Schema drift: %s: unknown key %q (%s)", message, k, d.Type)
+		}
+	}
+	for _, k := range missing {
+		if addKey(&d.Missing, k) {
+			c.log().Printf("Schema drift: %s: missing key %q (%s)", message, k, d.Type)
+		}
+	}
+}
+
+// addKey adds key to the sorted keys, returns false if already present.
+func addKey(keys *[]string, key string) bool {
+	i := sort.SearchStrings(*keys, key)
+	if i < len(*keys) && (*keys)[i] == key {
+		return false
+	}
+	*keys = append(*keys, "")
+	copy((*keys)[i+1:], (*keys)[i:])
+	(*keys)[i] = key
+	return true
+}
+
+var jsonUnmarshaler = reflect.TypeOf((*json.Unmarshaler)(nil)).Elem()
+
+// compareSchema compares the JSON keys in data to the fields of t,
+// recursing into nested objects and arrays. Types that implement
+// json.Unmarshaler are not inspected.
+func compareSchema(data []byte, t reflect.Type, prefix string, unknown, missing *[]string) {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if reflect.PtrTo(t).Implements(jsonUnmarshaler) {
+		return
+	}
+
+	switch t.Kind() {
+	case reflect.Slice, reflect.Array:
+		var elems []json.RawMessage
+		if json.Unmarshal(data, &elems) != nil {
+			return
+		}
+		for _, e := range elems {
+			compareSchema(e, t.Elem(), prefix+"[]", unknown, missing)
+		}
+
+	case reflect.Struct:
+		var obj map[string]json.RawMessage
+		if json.Unmarshal(data, &obj) != nil {
+			return
+		}
+		if prefix != "" {
+			prefix += "."
+		}
+		fields := jsonFields(t)
+		seen := make(map[string]bool)
+		for key, value := range obj {
+			f, ok := fields[key]
+			if !ok {
+				// Matches encoding/json, keys are case insensitive.
+				for name, ff := range fields {
+					if strings.EqualFold(name, key) {
+						f, ok = ff, true
+						break
+					}
+				}
+			}
+			if !ok {
+				*unknown = append(*unknown, prefix+key)
+				continue
+			}
+			seen[f.name] = true
+			compareSchema(value, f.typ, prefix+key, unknown, missing)
+		}
+		for name, f := range fields {
+			if !seen[name] && !f.omitempty {
+				*missing = append(*missing, prefix+name)
+			}
+		}
+	}
+}
+
+type jsonField struct {
+	name      string
+	typ       reflect.Type
+	omitempty bool
+}
+
+// jsonFields returns the JSON fields of the struct, including fields
+// of embedded structs.
+func jsonFields(t reflect.Type) map[string]jsonField {
+	fields := make(map[string]jsonField)
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag := f.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		if f.Anonymous && tag == "" {
+			ft := f.Type
+			if ft.Kind() == reflect.Ptr {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				for name, ef := range jsonFields(ft) {
+					if _, ok := fields[name]; !ok {
+						fields[name] = ef
+					}
+				}
+				continue
+			}
+		}
+		if f.PkgPath != "" {
+			continue // Unexported.
+		}
+		opts := strings.Split(tag, ",")
+		name := opts[0]
+		if name == "" {
+			name = f.Name
+		}
+		omitempty := false
+		for _, o := range opts[1:] {
+			if o == "omitempty" {
+				omitempty = true
+			}
+		}
+		fields[name] = jsonField{name: name, typ: f.Type, omitempty: omitempty}
+	}
+	return fields
+}
+
+// checkEventDrift checks broadcasts of known events.
+func (c *Client) checkEventDrift(resp Response) {
+	if ev := api.NewEvent(resp.Message); ev != nil {
+		c.checkDrift(resp.Message, resp.Data, ev)
+	}
+}
@@ -0,0 +1,94 @@
+package musicflow
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/mafredri/musicflow/api"
+)
+
+// Event is a broadcast received from the speaker.
+type Event struct {
+	Time    time.Time // When the broadcast was received.
+	Message string
+	Data    json.RawMessage
+
+	// Value is the decoded data (see DecodeEvent), e.g.
+	// *api.VolumeChangeEvent. Nil when the message is unknown or
+	// the data could not be decoded.
+	Value interface{}
+}
+
+// DecodeEvent decodes the broadcast data into the api event type for
+// the message (see api.NewEvent) or, for broadcasted replies, the
+// reply type (see api.NewReply). Returns nil when the message is
+// unknown.
+func DecodeEvent(message string, data []byte) (interface{}, error) {
+	var v interface{}
+	if ev := api.NewEvent(message); ev != nil {
+		v = ev
+	} else if v = api.NewReply(message); v == nil {
+		return nil, nil
+	}
+	err := decodeEvent(Response{Message: message, Data: data}, v)
+	if err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// Events returns a channel that receives the broadcasts, decoded by
+// DecodeEvent. The channel is closed when the context is done or the
+// connection is lost. Like OnBroadcast, replies to requests sent by
+// this client are not included. Events are dropped if the channel is
+// not drained.
+func (c *Client) Events(ctx context.Context) <-chan Event {
+	broadcasts, unsubscribe := c.subscribe()
+	lost := c.lost()
+	events := make(chan Event)
+
+	go func() {
+		defer close(events)
+		defer unsubscribe()
+
+		send := func(resp Response) bool {
+			ev := Event{
+				Time:    time.Now(),
+				Message: resp.Message,
+				Data:    resp.Data,
+			}
+			ev.Value, _ = DecodeEvent(resp.Message, resp.Data)
+			select {
+			case events <- ev:
+				return true
+			case <-ctx.Done():
+				return false
+			}
+		}
+		for {
+			select {
+			case resp := <-broadcasts:
+				if !send(resp) {
+					return
+				}
+			case <-lost:
+				// Deliver the broadcasts received before the
+				// connection was lost.
+				for {
+					select {
+					case resp := <-broadcasts:
+						if !send(resp) {
+							return
+						}
+					default:
+						return
+					}
+				}
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return events
+}
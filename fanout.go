@@ -0,0 +1,165 @@
+package musicflow
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Target is a speaker that FanOut runs the operation against.
+type Target struct {
+	Name    string // Used in the report, defaults to Addr.
+	Addr    string // Address (host:port) of the speaker.
+	Options []DialOption
+}
+
+func (t Target) String() string {
+	if t.Name != "" {
+		return t.Name
+	}
+	return t.Addr
+}
+
+// Result is the outcome of the operation on one target.
+type Result struct {
+	Target   Target
+	Err      error // Nil on success.
+	Duration time.Duration
+}
+
+// Report contains the results of FanOut, in the order of the targets.
+type Report []Result
+
+// Failed returns the results with an error.
+func (r Report) Failed() Report {
+	var failed Report
+	for _, res := range r {
+		if res.Err != nil {
+			failed = append(failed, res)
+		}
+	}
+	return failed
+}
+
+// Err returns a *FanOutError when the operation failed on any of the
+// targets, nil otherwise.
+func (r Report) Err() error {
+	if failed := r.Failed(); len(failed) > 0 {
+		return &FanOutError{Report: r, Failed: failed}
+	}
+	return nil
+}
+
+// FanOutError is returned by Report.Err.
+type FanOutError struct {
+	Report Report // All results.
+	Failed Report // Results with an error.
+}
+
+func (e *FanOutError) Error() string {
+	var errs []string
+	for _, res := range e.Failed {
+		errs = append(errs, fmt.Sprintf("%s: %v", res.Target, res.Err))
+	}
+	return fmt.Sprintf("%d of %d speakers failed: %s", len(e.Failed), len(e.Report), strings.Join(errs, "; "))
+}
+
+// Unwrap returns the error of the first failed target.
+func (e *FanOutError) Unwrap() error { return e.Failed[0].Err }
+
+type fanOutOptions struct {
+	concurrency int
+	timeout     time.Duration
+	dialOpts    []DialOption
+}
+
+// A FanOutOption sets custom options for FanOut.
+type FanOutOption func(*fanOutOptions)
+
+// WithFanOutConcurrency sets the maximum number of speakers that are
+// operated on at the same time, defaults to 8.
+func WithFanOutConcurrency(n int) FanOutOption {
+	return func(o *fanOutOptions) {
+		o.concurrency = n
+	}
+}
+
+// WithFanOutTimeout limits the time spent on each speaker, including
+// connecting. Defaults to no limit.
+func WithFanOutTimeout(d time.Duration) FanOutOption {
+	return func(o *fanOutOptions) {
+		o.timeout = d
+	}
+}
+
+// WithFanOutDialOption sets the option(s) used when connecting to the
+// speakers, before the options of the target.
+func WithFanOutDialOption(opt ...DialOption) FanOutOption {
+	return func(o *fanOutOptions) {
+		o.dialOpts = append(o.dialOpts, opt...)
+	}
+}
+
+// FanOut connects to the targets concurrently and runs fn against
+// each of them, the client is closed when fn returns. An error from
+// one target does not stop the others, the errors are reported per
+// target (see Report.Err). Targets that were not started before the
+// context is done fail with the context error.
+func FanOut(ctx context.Context, targets []Target, fn func(ctx context.Context, t Target, c *Client) error, opts ...FanOutOption) Report {
+	o := fanOutOptions{concurrency: 8}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if o.concurrency < 1 {
+		o.concurrency = 1
+	}
+
+	report := make(Report, len(targets))
+	sem := make(chan struct{}, o.concurrency)
+	var done sync.WaitGroup
+	for i, t := range targets {
+		report[i].Target = t
+		if err := ctx.Err(); err != nil {
+			report[i].Err = err
+			continue
+		}
+
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+			report[i].Err = ctx.Err()
+			continue
+		}
+		done.Add(1)
+		go func(res *Result) {
+			defer done.Done()
+			defer func() { <-sem }()
+
+			start := time.Now()
+			res.Err = fanOutRun(ctx, res.Target, fn, o)
+			res.Duration = time.Since(start)
+		}(&report[i])
+	}
+	done.Wait()
+
+	return report
+}
+
+func fanOutRun(ctx context.Context, t Target, fn func(ctx context.Context, t Target, c *Client) error, o fanOutOptions) error {
+	if o.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, o.timeout)
+		defer cancel()
+	}
+
+	dialOpts := append(append([]DialOption(nil), o.dialOpts...), t.Options...)
+	c, err := Dial(ctx, t.Addr, dialOpts...)
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+
+	return fn(ctx, t, c)
+}
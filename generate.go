@@ -0,0 +1,5 @@
+package musicflow
+
+// The api types and enum methods, the Client methods (client_gen.go)
+// and PROTOCOL.md are generated from the protocol description.
+//go:generate go run ./internal/protogen
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+// genDoc generates the protocol documentation (Markdown).
+func genDoc(w *bytes.Buffer, p *protocol) error {
+	w.WriteString("<!-- Code generated by protogen from api/protocol.json. DO NOT EDIT. -->\n\n")
+	w.WriteString(`# Music Flow protocol
+
+Requests are sent to the speaker as JSON objects, ` + "`" + `{"msg": "MESSAGE", "data": {...}}` + "`" + `,
+where ` + "`" + `data` + "`" + ` is omitted when the request has no payload. The speaker
+replies with the same message and ` + "`" + `"result": "OK"` + "`" + `, some requests
+are answered by a notification instead. Events (notifications) are
+broadcasted by the speaker without a result.
+
+Messages marked as not observed have been inferred and might not
+work as described.
+
+| Message | Direction | Go type | Reply | Notification | Client method |
+| ------- | --------- | ------- | ----- | ------------ | ------------- |
+`)
+	for _, m := range p.Messages {
+		goType, reply := "", ""
+		if m.Direction == "request" {
+			goType = "`api." + m.Request.Name + "`"
+			if r := m.replyObject(p); r != nil {
+				reply = "`api." + r.Name + "`"
+			}
+		} else {
+			goType = "`api." + m.Event.Name + "`"
+		}
+		notify, method := "", ""
+		if m.Notify != "" {
+			notify = fmt.Sprintf("[`%s`](#%s)", m.Notify, anchor(m.Notify))
+		}
+		if m.Method != nil {
+			method = "`" + m.Method.Name + "`"
+		}
+		fmt.Fprintf(w, "| [`%s`](#%s) | %s | %s | %s | %s | %s |\n", m.Message, anchor(m.Message), m.Direction, goType, reply, notify, method)
+	}
+
+	for _, m := range p.Messages {
+		fmt.Fprintf(w, "\n## %s\n\n", m.Message)
+		var doc string
+		if m.Direction == "request" {
+			doc = m.Request.Doc
+		} else {
+			doc = m.Event.Doc
+		}
+		if doc != "" {
+			w.WriteString(doc + "\n\n")
+		}
+		if m.Method != nil && m.Method.Doc != "" {
+			w.WriteString("Client method: " + m.Method.Doc + "\n\n")
+		}
+
+		switch m.Direction {
+		case "request":
+			writeFields(w, p, "Request", m.Request)
+			if m.Reply != nil && !m.Reply.Unused {
+				writeFields(w, p, "Reply", m.Reply)
+			} else if !m.NotifyOnly {
+				w.WriteString("Reply: no payload.\n\n")
+			}
+			if m.Notify != "" {
+				if m.NotifyOnly {
+					fmt.Fprintf(w, "Answered by [`%s`](#%s) instead of a reply.\n\n", m.Notify, anchor(m.Notify))
+				} else {
+					fmt.Fprintf(w, "Followed by [`%s`](#%s).\n\n", m.Notify, anchor(m.Notify))
+				}
+			}
+		case "event":
+			writeFields(w, p, "Payload", m.Event)
+		}
+	}
+
+	described := make(map[string]bool)
+	for _, m := range p.Messages {
+		described[m.Message] = true
+	}
+	w.WriteString("\n## Messages without description\n\n")
+	for _, msg := range p.order {
+		if !described[msg] {
+			fmt.Fprintf(w, "- `%s` (`api.%s`)\n", msg, p.consts[msg])
+		}
+	}
+	return nil
+}
+
+func anchor(message string) string {
+	return strings.ToLower(message)
+}
+
+func writeFields(w *bytes.Buffer, p *protocol, title string, o *object) {
+	fields := p.fields(o)
+	if len(fields) == 0 {
+		fmt.Fprintf(w, "%s: no payload.\n\n", title)
+		return
+	}
+	fmt.Fprintf(w, "%s (`api.%s`):\n\n", title, o.Name)
+	w.WriteString("| Key | Type | Description |\n| --- | ---- | ----------- |\n")
+	for _, f := range fields {
+		fmt.Fprintf(w, "| `%s` | `%s` | %s |\n", f.JSON, f.Type, f.Doc)
+	}
+	w.WriteString("\n")
+}
+
+// fields returns the fields of o, including embedded and external
+// (hand-written) types.
+func (p *protocol) fields(o *object) []field {
+	if o.External {
+		return p.expand(o.Name)
+	}
+	if o.Embed != "" {
+		return p.expand(o.Embed)
+	}
+	return o.Fields
+}
+
+func (p *protocol) expand(typ string) []field {
+	var fields []field
+	for _, f := range p.structs[typ] {
+		if f.JSON == "" {
+			fields = append(fields, p.expand(f.Type)...)
+			continue
+		}
+		fields = append(fields, f)
+	}
+	return fields
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// enum is an integer type of the api package with named values, the
+// type and the constants are hand-written (documentation), the String
+// and text methods are generated.
+type enum struct {
+	Name     string            `json:"name"`     // Go type name.
+	Receiver string            `json:"receiver"` // Method receiver name.
+	Values   []enumValue       `json:"values"`   // Contiguous from zero, in order.
+	Aliases  map[string]string `json:"aliases"`  // Normalized name -> constant.
+}
+
+type enumValue struct {
+	Const string `json:"const"` // Go constant name.
+	Text  string `json:"text"`  // Name returned by String.
+}
+
+func (e *enum) validate(p *protocol) error {
+	if e.Receiver == "" {
+		return fmt.Errorf("no receiver")
+	}
+	if len(e.Values) == 0 {
+		return fmt.Errorf("no values")
+	}
+	listed := make(map[string]bool)
+	for i, v := range e.Values {
+		c, ok := p.values[v.Const]
+		if !ok || c.Type != e.Name {
+			return fmt.Errorf("%s: no %s constant in package api", v.Const, e.Name)
+		}
+		if c.Value != i {
+			return fmt.Errorf("%s: value %d, want %d (values are contiguous from zero)", v.Const, c.Value, i)
+		}
+		if v.Text == "" {
+			return fmt.Errorf("%s: no text", v.Const)
+		}
+		listed[v.Const] = true
+	}
+	for name, c := range p.values {
+		if c.Type == e.Name && !listed[name] {
+			return fmt.Errorf("%s: constant not described", name)
+		}
+	}
+	for alias, name := range e.Aliases {
+		if !listed[name] {
+			return fmt.Errorf("alias %s: unknown value %s", alias, name)
+		}
+		if alias != normalizeName(alias) {
+			return fmt.Errorf("alias %s: not normalized, want %s", alias, normalizeName(alias))
+		}
+	}
+	return nil
+}
+
+// normalizeName is api.normalizeName, aliases are matched against the
+// normalized input.
+func normalizeName(s string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(s) {
+		if ('a' <= r && r <= 'z') || ('0' <= r && r <= '9') {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// genEnums generates the String, parse and text marshalling methods of
+// the enums, see api/text.go for the shared parts.
+func genEnums(w *bytes.Buffer, p *protocol) error {
+	w.WriteString(header)
+	w.WriteString("package api\n\nimport (\n\"fmt\"\n\"strconv\"\n)\n\n")
+
+	w.WriteString("// Number of known values per enum, the values are contiguous from zero.\nconst (\n")
+	for _, e := range p.Enums {
+		fmt.Fprintf(w, "%sCount = int(%s) + 1\n", lowerFirst(e.Name), e.Values[len(e.Values)-1].Const)
+	}
+	w.WriteString(")\n\n")
+
+	for _, e := range p.Enums {
+		name, r, v := e.Name, e.Receiver, lowerFirst(e.Name)
+		a := article(name)
+
+		writeDoc(w, "", fmt.Sprintf("String returns the name of the %s, e.g. %q, or %s(N) for unknown values.", name, e.Values[0].Text, name))
+		fmt.Fprintf(w, "func (%s %s) String() string {\nswitch %s {\n", r, name, r)
+		for _, val := range e.Values {
+			fmt.Fprintf(w, "case %s:\nreturn %q\n", val.Const, val.Text)
+		}
+		fmt.Fprintf(w, "default:\nreturn fmt.Sprintf(\"%s(%%d)\", %s)\n}\n}\n\n", name, r)
+
+		fmt.Fprintf(w, "// %sValues returns all known %s values.\n", name, name)
+		fmt.Fprintf(w, "func %sValues() []%s {\nv := make([]%s, %sCount)\nfor i := range v {\nv[i] = %s(i)\n}\nreturn v\n}\n\n", name, name, name, v, name)
+
+		aliases := "nil"
+		if len(e.Aliases) > 0 {
+			aliases = v + "Aliases"
+			var keys []string
+			for k := range e.Aliases {
+				keys = append(keys, k)
+			}
+			sort.Strings(keys)
+			fmt.Fprintf(w, "var %s = map[string]int{\n", aliases)
+			for _, k := range keys {
+				fmt.Fprintf(w, "%q: int(%s),\n", k, e.Aliases[k])
+			}
+			w.WriteString("}\n\n")
+		}
+
+		fmt.Fprintf(w, "// Parse%s parses the name or number of %s %s.\n", name, a, name)
+		fmt.Fprintf(w, "func Parse%s(s string) (%s, error) {\nv, err := parseEnum(%q, s, %sCount, func(i int) string { return %s(i).String() }, %s)\nreturn %s(v), err\n}\n\n", name, name, name, v, name, aliases, name)
+
+		fmt.Fprintf(w, "// MarshalText implements encoding.TextMarshaler.\nfunc (%s %s) MarshalText() ([]byte, error) {\nreturn []byte(%s.String()), nil\n}\n\n", r, name, r)
+		fmt.Fprintf(w, "// UnmarshalText implements encoding.TextUnmarshaler.\nfunc (%s *%s) UnmarshalText(b []byte) error {\nv, err := Parse%s(string(b))\nif err != nil {\nreturn err\n}\n*%s = v\nreturn nil\n}\n\n", r, name, name, r)
+		fmt.Fprintf(w, "// MarshalJSON implements json.Marshaler, the numeric value is used.\nfunc (%s %s) MarshalJSON() ([]byte, error) {\nreturn strconv.AppendInt(nil, int64(%s), 10), nil\n}\n\n", r, name, r)
+		fmt.Fprintf(w, "// UnmarshalJSON implements json.Unmarshaler, both numbers and names\n// are accepted.\nfunc (%s *%s) UnmarshalJSON(b []byte) error {\nv, err := unmarshalEnumJSON(b, func(s string) (int, error) {\nv, err := Parse%s(s)\nreturn int(v), err\n})\nif err != nil {\nreturn err\n}\n*%s = %s(v)\nreturn nil\n}\n\n", r, name, name, r, name)
+	}
+	return nil
+}
+
+func lowerFirst(s string) string {
+	r := []rune(s)
+	r[0] = unicode.ToLower(r[0])
+	return string(r)
+}
+
+// article returns the indefinite article for the name.
+func article(name string) string {
+	if strings.ContainsRune("AEIOU", rune(name[0])) {
+		return "an"
+	}
+	return "a"
+}
@@ -0,0 +1,323 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+const header = "// Code generated by protogen from api/protocol.json. DO NOT EDIT.\n\n"
+
+// genAPI generates the request, reply and event types.
+func genAPI(w *bytes.Buffer, p *protocol) error {
+	w.WriteString(header)
+	w.WriteString("package api\n\n")
+	if p.usesJSON() {
+		w.WriteString("import \"encoding/json\"\n\n")
+	}
+
+	for _, t := range p.Types {
+		writeDoc(w, "", t.Doc)
+		fmt.Fprintf(w, "type %s ", t.Name)
+		writeStruct(w, t, "struct{}")
+		w.WriteString("\n")
+	}
+
+	for _, m := range p.Messages {
+		switch m.Direction {
+		case "request":
+			req, reply := m.Request, m.Reply
+			if reply != nil && !reply.External {
+				w.WriteString("type (\n")
+				writeDoc(w, "\t", req.Doc)
+				fmt.Fprintf(w, "%s ", req.Name)
+				writeStruct(w, req, "struct {\nemptyMessage\n}")
+				writeDoc(w, "\t", reply.Doc)
+				fmt.Fprintf(w, "%s ", reply.Name)
+				writeStruct(w, reply, "struct{}")
+				w.WriteString(")\n\n")
+			} else {
+				writeDoc(w, "", req.Doc)
+				fmt.Fprintf(w, "type %s ", req.Name)
+				writeStruct(w, req, "struct {\nemptyMessage\n}")
+				w.WriteString("\n")
+			}
+			fmt.Fprintf(w, "func (%s) Message() string { return %s }\n", req.Name, m.Const)
+			if reply != nil && !reply.Unused {
+				fmt.Fprintf(w, "func (%s) Reply() *%s { return &%s{%s} }", req.Name, reply.Name, reply.Name, reply.Init)
+				if reply.InitDoc != "" {
+					fmt.Fprintf(w, " // %s", reply.InitDoc)
+				}
+				w.WriteString("\n")
+			}
+			w.WriteString("\n")
+
+		case "event":
+			ev := m.Event
+			writeDoc(w, "", ev.Doc)
+			fmt.Fprintf(w, "type %s ", ev.Name)
+			writeStruct(w, ev, "struct{}")
+			fmt.Fprintf(w, "\nfunc (%s) Message() string { return %s }\n\n", ev.Name, m.Const)
+		}
+	}
+
+	w.WriteString("// NewEvent returns a new event for the message, or nil when the\n// message is not a known event.\n")
+	w.WriteString("func NewEvent(message string) interface{ Message() string } {\nswitch message {\n")
+	for _, m := range p.Messages {
+		if m.Direction == "event" {
+			fmt.Fprintf(w, "case %s:\nreturn &%s{}\n", m.Const, m.Event.Name)
+		}
+	}
+	w.WriteString("}\nreturn nil\n}\n\n")
+
+	w.WriteString("// NewRequest returns a new request for the message, or nil when the\n// message is not a known request.\n")
+	w.WriteString("func NewRequest(message string) interface{ Message() string } {\nswitch message {\n")
+	for _, m := range p.Messages {
+		if m.Direction == "request" {
+			fmt.Fprintf(w, "case %s:\nreturn &%s{}\n", m.Const, m.Request.Name)
+		}
+	}
+	w.WriteString("}\nreturn nil\n}\n\n")
+
+	w.WriteString("// NewReply returns a new reply for the request message, or nil when\n// the message is not a known request with a reply.\n")
+	w.WriteString("func NewReply(message string) interface{} {\nswitch message {\n")
+	for _, m := range p.Messages {
+		if m.Direction == "request" && m.Reply != nil && !m.Reply.Unused {
+			fmt.Fprintf(w, "case %s:\nreturn %s{}.Reply()\n", m.Const, m.Request.Name)
+		}
+	}
+	w.WriteString("}\nreturn nil\n}\n\n")
+
+	var messages []string
+	for msg := range p.consts {
+		messages = append(messages, msg)
+	}
+	sort.Strings(messages)
+	w.WriteString("// Messages returns all known messages (the Message constants),\n// sorted.\n")
+	w.WriteString("func Messages() []string {\nreturn []string{\n")
+	for _, msg := range messages {
+		fmt.Fprintf(w, "%s,\n", p.consts[msg])
+	}
+	w.WriteString("}\n}\n")
+	return nil
+}
+
+func (p *protocol) usesJSON() bool {
+	var objs []*object
+	objs = append(objs, p.Types...)
+	for _, m := range p.Messages {
+		objs = append(objs, m.Request, m.Reply, m.Event)
+	}
+	for _, o := range objs {
+		if o == nil {
+			continue
+		}
+		for _, f := range o.Fields {
+			if strings.Contains(f.Type, "json.") {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func writeStruct(w *bytes.Buffer, o *object, empty string) {
+	switch {
+	case o.Embed != "":
+		fmt.Fprintf(w, "struct {\n%s\n}\n", o.Embed)
+	case len(o.Fields) == 0:
+		w.WriteString(empty + "\n")
+	default:
+		w.WriteString("struct {\n")
+		for _, f := range o.Fields {
+			tag := f.JSON
+			if f.OmitEmpty {
+				tag += ",omitempty"
+			}
+			fmt.Fprintf(w, "%s %s `json:\"%s\"`", f.Name, f.Type, tag)
+			if f.Doc != "" {
+				fmt.Fprintf(w, " // %s", f.Doc)
+			}
+			w.WriteString("\n")
+		}
+		w.WriteString("}\n")
+	}
+}
+
+// writeDoc writes the comment wrapped at roughly 70 columns, the
+// paragraphs are separated by a blank line in doc.
+func writeDoc(w *bytes.Buffer, indent, doc string) {
+	if doc == "" {
+		return
+	}
+	for i, para := range strings.Split(doc, "\n\n") {
+		if i > 0 {
+			w.WriteString(indent + "//\n")
+		}
+		line := indent + "//"
+		for _, word := range strings.Fields(para) {
+			if len(line)+1+len(word) > 70 && line != indent+"//" {
+				w.WriteString(line + "\n")
+				line = indent + "//"
+			}
+			line += " " + word
+		}
+		w.WriteString(line + "\n")
+	}
+}
+
+// genClient generates the Client methods.
+func genClient(w *bytes.Buffer, p *protocol) error {
+	w.WriteString(header)
+	w.WriteString("package musicflow\n\n")
+	w.WriteString("import (\n\"context\"\n\nerrors \"golang.org/x/xerrors\"\n\n\"github.com/mafredri/musicflow/api\"\n)\n\n")
+
+	for _, m := range p.Messages {
+		mt := m.Method
+		if mt == nil {
+			continue
+		}
+		reply := m.replyObject(p)
+
+		var params []string
+		var args []string
+		for _, f := range m.Request.Fields {
+			name := f.param()
+			params = append(params, fmt.Sprintf("%s %s", name, qualify(f.Type)))
+			args = append(args, fmt.Sprintf("%s: %s", f.Name, name))
+		}
+
+		ret, zero := "error", ""
+		switch {
+		case mt.Return != "":
+			t := qualify(reply.field(mt.Return).Type)
+			ret, zero = fmt.Sprintf("(%s, error)", t), zeroValue(t)+", "
+		case reply != nil && !mt.Echo && !m.NotifyOnly:
+			ret, zero = fmt.Sprintf("(*api.%s, error)", reply.Name), "nil, "
+		}
+
+		writeDoc(w, "", mt.Doc)
+		fmt.Fprintf(w, "func (c *Client) %s(%s) %s {\n", mt.Name, strings.Join(append([]string{"ctx context.Context"}, params...), ", "), ret)
+
+		decl := ":="
+		if mt.Capability != "" {
+			name := mt.Feature
+			if name == "" {
+				name = feature(mt.Name)
+			}
+			fmt.Fprintf(w, "err := c.supports(ctx, %q, func(caps *Capabilities) bool {\nreturn caps.%s\n})\n", name, mt.Capability)
+			fmt.Fprintf(w, "if err != nil {\nreturn %serrors.Errorf(\"%s failed: %%w\", err)\n}\n", zero, mt.Name)
+			decl = "="
+		}
+
+		fmt.Fprintf(w, "req := api.%s{%s}\n", m.Request.Name, strings.Join(args, ", "))
+		replyArg := "nil"
+		switch {
+		case reply == nil:
+		case m.Reply != nil && !m.Reply.Unused:
+			w.WriteString("reply := req.Reply()\n")
+			replyArg = "reply"
+		default:
+			fmt.Fprintf(w, "reply := &api.%s{}\n", reply.Name)
+			replyArg = "reply"
+		}
+		opts := ""
+		if m.NotifyOnly {
+			opts = fmt.Sprintf(", WaitFor(api.%s, \"\")", p.byName[m.Notify].Const)
+		}
+		fmt.Fprintf(w, "err %s c.Send(ctx, newRequest(req), %s%s)\n", decl, replyArg, opts)
+		fmt.Fprintf(w, "if err != nil {\nreturn %serrors.Errorf(\"%s failed: %%w\", err)\n}\n", zero, mt.Name)
+
+		switch {
+		case mt.Echo:
+			fmt.Fprintf(w, "if reply.%s != %s {\nreturn errors.Errorf(\"%s: wrong return value: %%w\", ErrProtocol)\n}\nreturn nil\n", reply.Fields[0].Name, m.Request.Fields[0].param(), mt.Name)
+		case mt.Return != "":
+			fmt.Fprintf(w, "return reply.%s, nil\n", mt.Return)
+		case replyArg != "nil" && !m.NotifyOnly:
+			w.WriteString("return reply, nil\n")
+		default:
+			w.WriteString("return nil\n")
+		}
+		w.WriteString("}\n\n")
+	}
+	return nil
+}
+
+var builtin = map[string]bool{"bool": true, "int": true, "string": true, "float64": true}
+
+// qualify qualifies api types with the package name.
+func qualify(t string) string {
+	prefix := ""
+	for strings.HasPrefix(t, "[]") || strings.HasPrefix(t, "*") {
+		n := 1
+		if t[0] == '[' {
+			n = 2
+		}
+		prefix, t = prefix+t[:n], t[n:]
+	}
+	if builtin[t] || strings.Contains(t, ".") {
+		return prefix + t
+	}
+	return prefix + "api." + t
+}
+
+func zeroValue(t string) string {
+	switch {
+	case t == "bool":
+		return "false"
+	case t == "string":
+		return `""`
+	case builtin[t]:
+		return "0"
+	case strings.HasPrefix(t, "[]"), strings.HasPrefix(t, "*"):
+		return "nil"
+	default:
+		return t + "{}"
+	}
+}
+
+// splitName splits the Go name into words, keeping acronyms intact,
+// e.g. "AVSync" becomes "AV", "Sync".
+func splitName(name string) []string {
+	var words []string
+	r := []rune(name)
+	start := 0
+	for i := 1; i < len(r); i++ {
+		upper := unicode.IsUpper(r[i])
+		if upper && (!unicode.IsUpper(r[i-1]) || (i+1 < len(r) && unicode.IsLower(r[i+1]))) {
+			words = append(words, string(r[start:i]))
+			start = i
+		}
+	}
+	return append(words, string(r[start:]))
+}
+
+// param returns the parameter name of the field in Client methods.
+func (f field) param() string {
+	if f.Param != "" {
+		return f.Param
+	}
+	return paramName(f.Name)
+}
+
+// paramName returns the parameter name for the field, e.g. "avSync"
+// for "AVSync".
+func paramName(name string) string {
+	words := splitName(name)
+	words[0] = strings.ToLower(words[0])
+	return strings.Join(words, "")
+}
+
+// feature returns the feature name used in errors, e.g. "auto display"
+// for "AutoDisplay".
+func feature(name string) string {
+	words := splitName(name)
+	for i, w := range words {
+		if strings.ToUpper(w) != w {
+			words[i] = strings.ToLower(w)
+		}
+	}
+	return strings.Join(words, " ")
+}
@@ -0,0 +1,218 @@
+// Command protogen generates the request, reply and event types and
+// the enum methods (api package), the Client methods and the protocol
+// documentation from the protocol description in api/protocol.json.
+//
+// Usage (from the repository root):
+//
+//	go generate
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"go/format"
+	"io/ioutil"
+	"log"
+)
+
+func main() {
+	var (
+		schemaPath = flag.String("schema", "api/protocol.json", "Protocol description")
+		apiDir     = flag.String("api", "api", "Directory of the api package")
+		apiOut     = flag.String("api-out", "api/request_gen.go", "Output file for api types")
+		enumOut    = flag.String("enum-out", "api/enum_gen.go", "Output file for api enum methods")
+		clientOut  = flag.String("client-out", "client_gen.go", "Output file for Client methods")
+		docOut     = flag.String("doc-out", "PROTOCOL.md", "Output file for documentation")
+	)
+	flag.Parse()
+
+	log.SetFlags(0)
+	log.SetPrefix("protogen: ")
+
+	p, err := load(*schemaPath, *apiDir)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	for _, out := range []struct {
+		path  string
+		gen   func(*bytes.Buffer, *protocol) error
+		gofmt bool
+	}{
+		{*apiOut, genAPI, true},
+		{*enumOut, genEnums, true},
+		{*clientOut, genClient, true},
+		{*docOut, genDoc, false},
+	} {
+		var buf bytes.Buffer
+		if err := out.gen(&buf, p); err != nil {
+			log.Fatalf("%s: %v", out.path, err)
+		}
+		b := buf.Bytes()
+		if out.gofmt {
+			b, err = format.Source(b)
+			if err != nil {
+				log.Fatalf("%s: format: %v\n%s", out.path, err, buf.Bytes())
+			}
+		}
+		if err := ioutil.WriteFile(out.path, b, 0o644); err != nil {
+			log.Fatal(err)
+		}
+	}
+}
+
+// protocol is the protocol description (api/protocol.json).
+type protocol struct {
+	Types    []*object  `json:"types"`
+	Enums    []*enum    `json:"enums"`
+	Messages []*message `json:"messages"`
+
+	consts  map[string]string   // Message -> constant name.
+	values  map[string]constant // Typed constants of the api package by name.
+	order   []string            // Messages in api.go order.
+	structs map[string][]field  // Struct fields of hand-written api types.
+	byName  map[string]*message // Message -> description.
+}
+
+type message struct {
+	Message    string  `json:"message"`   // Message on the wire, e.g. "EQ_SETTING".
+	Direction  string  `json:"direction"` // "request" (to the speaker) or "event" (from the speaker).
+	Request    *object `json:"request"`
+	Reply      *object `json:"reply"`
+	Event      *object `json:"event"`
+	Notify     string  `json:"notify"`     // Notification sent by the speaker after the request.
+	NotifyOnly bool    `json:"notifyOnly"` // Notification is sent instead of the reply.
+	Method     *method `json:"method"`
+
+	Const string `json:"-"`
+}
+
+type object struct {
+	Name     string  `json:"name"`     // Go type name.
+	Doc      string  `json:"doc"`      // Type documentation.
+	Embed    string  `json:"embed"`    // Embedded api type, instead of fields.
+	Fields   []field `json:"fields"`   // Fields of the type.
+	External bool    `json:"external"` // Hand-written type, not generated.
+	Unused   bool    `json:"unused"`   // Reply type not returned by Reply.
+	Init     string  `json:"init"`     // Initial values for the reply.
+	InitDoc  string  `json:"initDoc"`  // Reason for the initial values.
+}
+
+type field struct {
+	Name      string `json:"name"`
+	JSON      string `json:"json"`
+	Type      string `json:"type"`
+	Doc       string `json:"doc"`
+	OmitEmpty bool   `json:"omitempty"`
+	Param     string `json:"param"` // Client method parameter, derived from the name by default.
+}
+
+type method struct {
+	Name       string `json:"name"`       // Client method name.
+	Doc        string `json:"doc"`        // Method documentation.
+	Echo       bool   `json:"echo"`       // Verify that the reply echoes the request.
+	Return     string `json:"return"`     // Reply field to return instead of the reply.
+	Capability string `json:"capability"` // Capabilities field required.
+	Feature    string `json:"feature"`    // Feature name in errors, derived from the name by default.
+}
+
+func load(path, apiDir string) (*protocol, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	p := new(protocol)
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(p); err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+
+	p.consts, p.order, p.structs, p.values, err = parseAPI(apiDir)
+	if err != nil {
+		return nil, err
+	}
+	for _, e := range p.Enums {
+		if err := e.validate(p); err != nil {
+			return nil, fmt.Errorf("enum %s: %v", e.Name, err)
+		}
+	}
+
+	p.byName = make(map[string]*message)
+	for _, m := range p.Messages {
+		if p.byName[m.Message] != nil {
+			return nil, fmt.Errorf("%s: duplicate message", m.Message)
+		}
+		p.byName[m.Message] = m
+		m.Const = p.consts[m.Message]
+		if m.Const == "" {
+			return nil, fmt.Errorf("%s: no Message constant in package api", m.Message)
+		}
+	}
+	for _, m := range p.Messages {
+		if err := m.validate(p); err != nil {
+			return nil, fmt.Errorf("%s: %v", m.Message, err)
+		}
+	}
+	return p, nil
+}
+
+func (m *message) validate(p *protocol) error {
+	switch m.Direction {
+	case "request":
+		if m.Request == nil || m.Event != nil {
+			return fmt.Errorf("request must (only) have request and reply")
+		}
+	case "event":
+		if m.Event == nil || m.Request != nil || m.Reply != nil {
+			return fmt.Errorf("event must (only) have event")
+		}
+	default:
+		return fmt.Errorf("unknown direction %q", m.Direction)
+	}
+	if m.Notify != "" && p.byName[m.Notify] == nil {
+		return fmt.Errorf("notification %s not described", m.Notify)
+	}
+	if m.NotifyOnly && m.Notify == "" {
+		return fmt.Errorf("notifyOnly without notify")
+	}
+	if mt := m.Method; mt != nil {
+		reply := m.replyObject(p)
+		if (mt.Echo || mt.Return != "") && reply == nil {
+			return fmt.Errorf("method %s: no reply", mt.Name)
+		}
+		if mt.Echo && (len(m.Request.Fields) != 1 || len(reply.Fields) != 1) {
+			return fmt.Errorf("method %s: echo requires a single request and reply field", mt.Name)
+		}
+		if mt.Return != "" && reply.field(mt.Return) == nil {
+			return fmt.Errorf("method %s: reply has no field %s", mt.Name, mt.Return)
+		}
+		if m.Request.Embed != "" {
+			return fmt.Errorf("method %s: embedded request not supported", mt.Name)
+		}
+	}
+	return nil
+}
+
+// replyObject returns the reply for the request, the notification
+// event when it replaces the reply.
+func (m *message) replyObject(p *protocol) *object {
+	if m.Reply != nil {
+		return m.Reply
+	}
+	if m.NotifyOnly {
+		return p.byName[m.Notify].Event
+	}
+	return nil
+}
+
+func (o *object) field(name string) *field {
+	for i := range o.Fields {
+		if o.Fields[i].Name == name {
+			return &o.Fields[i]
+		}
+	}
+	return nil
+}
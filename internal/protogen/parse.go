@@ -0,0 +1,161 @@
+package main
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// constant is a typed integer constant, e.g. an enum value.
+type constant struct {
+	Type  string
+	Value int
+}
+
+// parseAPI parses the hand-written part of the api package for the
+// Message constants, the struct types used by the protocol and the
+// typed integer constants (enum values).
+func parseAPI(dir string) (consts map[string]string, order []string, structs map[string][]field, values map[string]constant, err error) {
+	fset := token.NewFileSet()
+	pkgs, err := parser.ParseDir(fset, dir, func(fi os.FileInfo) bool {
+		name := fi.Name()
+		return !strings.HasSuffix(name, "_test.go") && !strings.HasSuffix(name, "_gen.go")
+	}, parser.ParseComments)
+	if err != nil {
+		return nil, nil, nil, nil, err
+	}
+
+	consts = make(map[string]string)
+	structs = make(map[string][]field)
+	values = make(map[string]constant)
+	for _, pkg := range pkgs {
+		for _, f := range pkg.Files {
+			for _, decl := range f.Decls {
+				gd, ok := decl.(*ast.GenDecl)
+				if !ok {
+					continue
+				}
+				if gd.Tok == token.CONST {
+					constValues(gd, values)
+				}
+				for _, spec := range gd.Specs {
+					switch s := spec.(type) {
+					case *ast.ValueSpec:
+						if len(s.Names) != 1 || len(s.Values) != 1 || !strings.HasPrefix(s.Names[0].Name, "Message") {
+							continue
+						}
+						lit, ok := s.Values[0].(*ast.BasicLit)
+						if !ok || lit.Kind != token.STRING {
+							continue
+						}
+						v, err := strconv.Unquote(lit.Value)
+						if err != nil {
+							return nil, nil, nil, nil, err
+						}
+						consts[v] = s.Names[0].Name
+						order = append(order, v)
+					case *ast.TypeSpec:
+						st, ok := s.Type.(*ast.StructType)
+						if !ok {
+							continue
+						}
+						structs[s.Name.Name] = structFields(st)
+					}
+				}
+			}
+		}
+	}
+	return consts, order, structs, values, nil
+}
+
+// constValues adds the typed constants of the declaration that are
+// integer literals or iota, an omitted type and value repeats the
+// previous ones.
+func constValues(gd *ast.GenDecl, values map[string]constant) {
+	var typ ast.Expr
+	var exprs []ast.Expr
+	for index, spec := range gd.Specs {
+		s := spec.(*ast.ValueSpec)
+		if s.Type != nil || len(s.Values) > 0 {
+			typ, exprs = s.Type, s.Values
+		}
+		if typ == nil {
+			continue
+		}
+		for i, name := range s.Names {
+			if i >= len(exprs) {
+				break
+			}
+			var v int
+			switch e := exprs[i].(type) {
+			case *ast.BasicLit:
+				n, err := strconv.Atoi(e.Value)
+				if e.Kind != token.INT || err != nil {
+					continue
+				}
+				v = n
+			case *ast.Ident:
+				if e.Name != "iota" {
+					continue
+				}
+				v = index
+			default:
+				continue
+			}
+			values[name.Name] = constant{Type: exprString(typ), Value: v}
+		}
+	}
+}
+
+func structFields(st *ast.StructType) []field {
+	var fields []field
+	for _, f := range st.Fields.List {
+		typ := exprString(f.Type)
+		var tag reflect.StructTag
+		if f.Tag != nil {
+			s, _ := strconv.Unquote(f.Tag.Value)
+			tag = reflect.StructTag(s)
+		}
+		doc := strings.TrimSpace(f.Comment.Text())
+		if len(f.Names) == 0 {
+			// Embedded, JSON left empty.
+			fields = append(fields, field{Name: typ, Type: typ, Doc: doc})
+			continue
+		}
+		for _, name := range f.Names {
+			if !name.IsExported() {
+				continue
+			}
+			js := strings.Split(tag.Get("json"), ",")
+			fields = append(fields, field{
+				Name:      name.Name,
+				JSON:      js[0],
+				Type:      typ,
+				Doc:       doc,
+				OmitEmpty: len(js) > 1 && js[1] == "omitempty",
+			})
+		}
+	}
+	return fields
+}
+
+func exprString(e ast.Expr) string {
+	switch e := e.(type) {
+	case *ast.Ident:
+		return e.Name
+	case *ast.StarExpr:
+		return "*" + exprString(e.X)
+	case *ast.ArrayType:
+		return "[]" + exprString(e.Elt)
+	case *ast.MapType:
+		return "map[" + exprString(e.Key) + "]" + exprString(e.Value)
+	case *ast.SelectorExpr:
+		return exprString(e.X) + "." + e.Sel.Name
+	default:
+		return "?"
+	}
+}
@@ -0,0 +1,179 @@
+package musicflow
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	errors "golang.org/x/xerrors"
+)
+
+// WithMetrics records the requests, broadcasts and reconnects of the
+// client in m. A Metrics can be shared by several clients, e.g. to keep
+// counting when the speaker is dialed again.
+func WithMetrics(m *Metrics) DialOption {
+	return func(o *dialOptions) {
+		o.metrics = m
+	}
+}
+
+// LatencyBuckets are the upper bounds, in seconds, of the request
+// latency histogram buckets (see Histogram).
+var LatencyBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+// Metrics counts the client activity, see WithMetrics. The zero value
+// is ready to use.
+type Metrics struct {
+	mu          sync.Mutex
+	requests    map[RequestCount]uint64 // Count is always zero in the key.
+	latency     map[string]*Histogram
+	broadcasts  map[string]uint64
+	parseErrors uint64
+	connects    uint64
+	reconnects  uint64
+}
+
+// RequestCount is the number of requests sent for a message with the
+// same result. The result is one of:
+//
+//	ok              the expected reply was received
+//	protocol_error  the request was rejected or the reply could not be decoded (ErrProtocol)
+//	canceled        the context was done before the reply was received
+//	error           e.g. the connection was lost
+type RequestCount struct {
+	Message string
+	Result  string
+	Count   uint64
+}
+
+// Histogram is the latency of the replies to a message, requests
+// without reply are not included.
+type Histogram struct {
+	Message string
+	Buckets []uint64 // Cumulative counts per bucket in LatencyBuckets.
+	Count   uint64
+	Sum     time.Duration
+}
+
+// MetricsSnapshot is a copy of the metrics, see Metrics.Snapshot.
+type MetricsSnapshot struct {
+	Requests    []RequestCount    // Sorted by message and result.
+	Latency     []Histogram       // Sorted by message.
+	Broadcasts  map[string]uint64 // By message.
+	ParseErrors uint64            // Unparseable requests (PARSING_ERROR), replies and messages.
+	Connects    uint64            // Successful dials and reconnects.
+	Reconnects  uint64            // Connects after the first one.
+}
+
+// Snapshot returns a copy of the metrics.
+func (m *Metrics) Snapshot() MetricsSnapshot {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	s := MetricsSnapshot{
+		Broadcasts:  make(map[string]uint64, len(m.broadcasts)),
+		ParseErrors: m.parseErrors,
+		Connects:    m.connects,
+		Reconnects:  m.reconnects,
+	}
+	for rc, n := range m.requests {
+		rc.Count = n
+		s.Requests = append(s.Requests, rc)
+	}
+	sort.Slice(s.Requests, func(i, j int) bool {
+		a, b := s.Requests[i], s.Requests[j]
+		return a.Message < b.Message || (a.Message == b.Message && a.Result < b.Result)
+	})
+	for _, h := range m.latency {
+		h := *h
+		h.Buckets = append([]uint64(nil), h.Buckets...)
+		s.Latency = append(s.Latency, h)
+	}
+	sort.Slice(s.Latency, func(i, j int) bool {
+		return s.Latency[i].Message < s.Latency[j].Message
+	})
+	for msg, n := range m.broadcasts {
+		s.Broadcasts[msg] = n
+	}
+	return s
+}
+
+// request records a request sent at start, err is the result of Send.
+// Safe to call on a nil Metrics, like the other recording methods.
+func (m *Metrics) request(message string, start time.Time, err error) {
+	if m == nil {
+		return
+	}
+	d := time.Since(start)
+
+	result := "ok"
+	replied := true
+	switch {
+	case err == nil:
+	case errors.Is(err, ErrProtocol):
+		result = "protocol_error"
+	case errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded):
+		result, replied = "canceled", false
+	default:
+		result, replied = "error", false
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.requests == nil {
+		m.requests = make(map[RequestCount]uint64)
+		m.latency = make(map[string]*Histogram)
+	}
+	m.requests[RequestCount{Message: message, Result: result}]++
+	if !replied {
+		return
+	}
+	h, ok := m.latency[message]
+	if !ok {
+		h = &Histogram{Message: message, Buckets: make([]uint64, len(LatencyBuckets))}
+		m.latency[message] = h
+	}
+	for i, le := range LatencyBuckets {
+		if d.Seconds() <= le {
+			h.Buckets[i]++
+		}
+	}
+	h.Count++
+	h.Sum += d
+}
+
+func (m *Metrics) broadcast(message string) {
+	if m == nil {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.broadcasts == nil {
+		m.broadcasts = make(map[string]uint64)
+	}
+	m.broadcasts[message]++
+}
+
+func (m *Metrics) parseError() {
+	if m == nil {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.parseErrors++
+}
+
+// connected records a connection, reconnect is true when the client
+// was reconnected.
+func (m *Metrics) connected(reconnect bool) {
+	if m == nil {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if reconnect || m.connects > 0 {
+		m.reconnects++
+	}
+	m.connects++
+}
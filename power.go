@@ -0,0 +1,51 @@
+package musicflow
+
+import (
+	"context"
+
+	errors "golang.org/x/xerrors"
+
+	"github.com/mafredri/musicflow/api"
+)
+
+// Confirmation guards destructive operations, the matching constant
+// must be passed to confirm the operation.
+type Confirmation string
+
+// Confirmations for destructive operations.
+const (
+	ConfirmFactoryReset Confirmation = "yes, reset to factory settings"
+	ConfirmPowerOff     Confirmation = "yes, power off"
+)
+
+// ErrNotConfirmed is returned when a destructive operation was not
+// confirmed.
+var ErrNotConfirmed = errors.New("operation not confirmed")
+
+// FactoryReset resets the speaker to factory settings, all settings
+// (name, network, alarms, etc.) are lost. Requires ConfirmFactoryReset.
+func (c *Client) FactoryReset(ctx context.Context, confirm Confirmation) error {
+	if confirm != ConfirmFactoryReset {
+		return errors.Errorf("FactoryReset: %w", ErrNotConfirmed)
+	}
+	req := api.FactorySetRequest{}
+	err := c.Send(ctx, newRequest(req), nil)
+	if err != nil {
+		return errors.Errorf("FactoryReset failed: %w", err)
+	}
+	return nil
+}
+
+// PowerOff powers off the speaker, it may not be possible to power it
+// back on over the network. Requires ConfirmPowerOff.
+func (c *Client) PowerOff(ctx context.Context, confirm Confirmation) error {
+	if confirm != ConfirmPowerOff {
+		return errors.Errorf("PowerOff: %w", ErrNotConfirmed)
+	}
+	req := api.PowerOffRequest{}
+	err := c.Send(ctx, newRequest(req), nil)
+	if err != nil {
+		return errors.Errorf("PowerOff failed: %w", err)
+	}
+	return nil
+}
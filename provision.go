@@ -0,0 +1,150 @@
+package musicflow
+
+import (
+	"context"
+	"time"
+
+	errors "golang.org/x/xerrors"
+
+	"github.com/mafredri/musicflow/api"
+)
+
+// ProvisionConfig configures the setup of a (freshly reset) speaker.
+type ProvisionConfig struct {
+	Name    string // Speaker name.
+	Icon    int    // Speaker icon, usually 0.
+	SSID    string // Home network SSID.
+	Channel int    // Home network channel, usually 0.
+
+	// ShareNetwork (optional) is shared with the speaker via
+	// SHARE_NW_WIRELESS or SHARE_NW_WIRED depending on the network type.
+	ShareNetwork *api.NetworkInfo
+
+	Agree    bool   // Agree to the terms of service.
+	Sharing  bool   // Share usage data with LG.
+	Timezone string // IANA timezone name, defaults to the local timezone.
+	TestTone bool   // Play the test tone to identify the speaker.
+
+	// NotificationTimeout is the time waited for each notification
+	// from the speaker, defaults to 10 seconds.
+	NotificationTimeout time.Duration
+
+	// Progress (optional) is called before each step.
+	Progress func(step string)
+}
+
+// Provision sets up the speaker the same way the Music Flow app does,
+// waiting for the notifications the speaker sends along the way.
+// Returns the product info of the speaker after setup.
+func (c *Client) Provision(ctx context.Context, cfg ProvisionConfig) (*api.ProductInfo, error) {
+	if cfg.Name == "" {
+		return nil, errors.New("Provision: name must be provided")
+	}
+	if cfg.Timezone == "" {
+		tz, err := timezoneName(time.Local)
+		if err != nil {
+			return nil, errors.Errorf("Provision failed: %w", err)
+		}
+		cfg.Timezone = tz
+	}
+	if cfg.NotificationTimeout == 0 {
+		cfg.NotificationTimeout = 10 * time.Second
+	}
+	progress := cfg.Progress
+	if progress == nil {
+		progress = func(string) {}
+	}
+
+	broadcasts, unsubscribe := c.subscribe()
+	defer unsubscribe()
+
+	if cfg.SSID != "" {
+		progress("Sharing home network")
+		req := api.ShareHomeSSIDRequest{Channel: cfg.Channel, SSID: cfg.SSID}
+		err := c.Send(ctx, newRequest(req), nil)
+		if err != nil {
+			return nil, errors.Errorf("Provision failed: %w", err)
+		}
+	}
+
+	progress("Adding speaker")
+	addReq := api.SpeakerAddSetRequest{Icon: cfg.Icon, Name: cfg.Name}
+	err := c.Send(ctx, newRequest(addReq), nil)
+	if err != nil {
+		return nil, errors.Errorf("Provision failed: %w", err)
+	}
+	_, err = waitBroadcast(ctx, broadcasts, api.MessageSpeakerAddNotification, cfg.NotificationTimeout)
+	if err != nil {
+		return nil, errors.Errorf("Provision failed: %w", err)
+	}
+
+	progress("Setting time")
+	_, err = c.ProductInfo(ctx, time.Now(), true)
+	if err != nil {
+		return nil, errors.Errorf("Provision failed: %w", err)
+	}
+
+	if n := cfg.ShareNetwork; n != nil {
+		progress("Sharing network settings")
+		var req interface{ Message() string }
+		if n.Network == api.NetworkWired {
+			req = api.ShareNetworkWiredRequest{NetworkInfo: *n}
+		} else {
+			req = api.ShareNetworkWirelessRequest{NetworkInfo: *n}
+		}
+		err = c.Send(ctx, newRequest(req), nil)
+		if err != nil {
+			return nil, errors.Errorf("Provision failed: %w", err)
+		}
+	}
+
+	if cfg.TestTone {
+		progress("Playing test tone")
+		err = c.TestTone(ctx)
+		if err != nil {
+			return nil, errors.Errorf("Provision failed: %w", err)
+		}
+	}
+
+	progress("Setting name")
+	err = c.SetName(ctx, cfg.Name)
+	if err != nil {
+		return nil, errors.Errorf("Provision failed: %w", err)
+	}
+	// Not sent if the name did not change, the setup can continue.
+	_, err = waitBroadcast(ctx, broadcasts, api.MessageSpeakerNameChange, cfg.NotificationTimeout)
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		c.log().Printf("Provision: %v", err)
+	}
+
+	// The app checks the terms of service state before initializing,
+	// the result does not affect the setup.
+	progress("Checking terms of service")
+	tosReq := api.C4ATOSGetRequest{}
+	err = c.Send(ctx, newRequest(tosReq), tosReq.Reply())
+	if err != nil {
+		return nil, errors.Errorf("Provision failed: %w", err)
+	}
+
+	progress("Initializing")
+	initReq := api.InitializationSetRequest{
+		Initialization: api.Initialization{
+			Agree:    cfg.Agree,
+			Sharing:  cfg.Sharing,
+			Timezone: cfg.Timezone,
+		},
+	}
+	err = c.Send(ctx, newRequest(initReq), nil)
+	if err != nil {
+		return nil, errors.Errorf("Provision failed: %w", err)
+	}
+
+	info, err := c.ProductInfo(ctx, time.Now(), false)
+	if err != nil {
+		return nil, errors.Errorf("Provision failed: %w", err)
+	}
+	return info, nil
+}
@@ -0,0 +1,237 @@
+package musicflow
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	errors "golang.org/x/xerrors"
+
+	"github.com/mafredri/musicflow/api"
+)
+
+// RegistryEntry represents a known speaker, identified by its MAC
+// addresses.
+type RegistryEntry struct {
+	WirelessMAC  string    `json:"wireless_mac,omitempty"`
+	BluetoothMAC string    `json:"bluetooth_mac,omitempty"`
+	Addr         string    `json:"addr"` // Last known address (host:port).
+	Model        string    `json:"model,omitempty"`
+	Firmware     string    `json:"firmware,omitempty"`
+	LastSeen     time.Time `json:"last_seen"`
+}
+
+// matches returns true if the product info belongs to this speaker.
+func (e RegistryEntry) matches(info *api.ProductInfo) bool {
+	same := func(a, b string) bool {
+		return a != "" && strings.EqualFold(a, b)
+	}
+	return same(e.WirelessMAC, info.Info.WirelessMAC) || same(e.BluetoothMAC, info.Info.BluetoothMAC)
+}
+
+// Registry maps friendly names to speakers and keeps track of their
+// addresses, which can change due to DHCP. The registry is persisted
+// as a JSON file.
+type Registry struct {
+	path string
+
+	mu       sync.Mutex
+	speakers map[string]RegistryEntry
+}
+
+type registryFile struct {
+	Speakers map[string]RegistryEntry `json:"speakers"`
+}
+
+// LoadRegistry loads the registry from path, a missing file results
+// in an empty registry.
+func LoadRegistry(path string) (*Registry, error) {
+	r := &Registry{
+		path:     path,
+		speakers: make(map[string]RegistryEntry),
+	}
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return r, nil
+		}
+		return nil, errors.Errorf("LoadRegistry failed: %w", err)
+	}
+	var f registryFile
+	err = json.Unmarshal(b, &f)
+	if err != nil {
+		return nil, errors.Errorf("LoadRegistry: parse %s failed: %w", path, err)
+	}
+	for name, e := range f.Speakers {
+		r.speakers[name] = e
+	}
+	return r, nil
+}
+
+// Save writes the registry to disk.
+func (r *Registry) Save() error {
+	r.mu.Lock()
+	b, err := json.MarshalIndent(registryFile{Speakers: r.speakers}, "", "\t")
+	r.mu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	err = os.MkdirAll(filepath.Dir(r.path), 0o755)
+	if err != nil {
+		return errors.Errorf("Registry: save failed: %w", err)
+	}
+	// Write to a temporary file first to avoid a corrupt registry, the
+	// name is unique so that concurrent saves don't clobber each other.
+	f, err := ioutil.TempFile(filepath.Dir(r.path), filepath.Base(r.path)+".*.tmp")
+	if err != nil {
+		return errors.Errorf("Registry: save failed: %w", err)
+	}
+	_, err = f.Write(append(b, '\n'))
+	if err == nil {
+		err = f.Chmod(0o644)
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(f.Name(), r.path)
+	}
+	if err != nil {
+		os.Remove(f.Name())
+		return errors.Errorf("Registry: save failed: %w", err)
+	}
+	return nil
+}
+
+// Names returns the names of all speakers in the registry, sorted.
+func (r *Registry) Names() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	names := make([]string, 0, len(r.speakers))
+	for name := range r.speakers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Lookup returns the speaker by name.
+func (r *Registry) Lookup(name string) (RegistryEntry, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	e, ok := r.speakers[name]
+	return e, ok
+}
+
+// Add adds or replaces the speaker by name.
+func (r *Registry) Add(name string, s Speaker) {
+	e := RegistryEntry{
+		WirelessMAC:  s.WirelessMAC,
+		BluetoothMAC: s.BluetoothMAC,
+		Addr:         s.Addr,
+		Model:        s.ModelName,
+		LastSeen:     time.Now().UTC(),
+	}
+	if s.Info != nil {
+		e.Firmware = s.Info.Info.BeVer
+	}
+	r.mu.Lock()
+	r.speakers[name] = e
+	r.mu.Unlock()
+}
+
+// Remove removes the speaker by name.
+func (r *Registry) Remove(name string) {
+	r.mu.Lock()
+	delete(r.speakers, name)
+	r.mu.Unlock()
+}
+
+// seen updates the entry after the speaker was found at addr.
+func (r *Registry) seen(name, addr string, info *api.ProductInfo) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	e := r.speakers[name]
+	e.Addr = addr
+	e.Model = info.ModelName
+	e.Firmware = info.Info.BeVer
+	e.LastSeen = time.Now().UTC()
+	r.speakers[name] = e
+}
+
+// DialByName connects to the named speaker. The last known address
+// is tried first and the speaker verified by MAC address, if it does
+// not match (or cannot be reached) the speaker is located via Discover.
+// A changed address is saved to the registry, the other updates (e.g.
+// LastSeen) are only kept in memory until the next Save.
+func (r *Registry) DialByName(ctx context.Context, name string, opts ...DialOption) (*Client, error) {
+	e, ok := r.Lookup(name)
+	if !ok {
+		return nil, errors.Errorf("DialByName: speaker %q not in registry", name)
+	}
+
+	c, info, err := dialVerify(ctx, e.Addr, opts)
+	if err == nil && e.matches(info) {
+		r.seen(name, e.Addr, info)
+		return c, nil
+	}
+	if c != nil {
+		c.Close()
+	}
+
+	port := DefaultPort
+	if _, p, err := net.SplitHostPort(e.Addr); err == nil {
+		if n, err := strconv.Atoi(p); err == nil {
+			port = n
+		}
+	}
+	speakers, err := Discover(ctx, WithDiscoverPort(port), WithDiscoverDialOption(opts...))
+	if err != nil {
+		return nil, errors.Errorf("DialByName failed: %w", err)
+	}
+	for _, s := range speakers {
+		if !e.matches(s.Info) {
+			continue
+		}
+		c, info, err := dialVerify(ctx, s.Addr, opts)
+		if err != nil {
+			return nil, errors.Errorf("DialByName failed: %w", err)
+		}
+		r.seen(name, s.Addr, info)
+		if s.Addr == e.Addr {
+			return c, nil
+		}
+		if err = r.Save(); err != nil {
+			c.Close()
+			return nil, errors.Errorf("DialByName failed: %w", err)
+		}
+		return c, nil
+	}
+	return nil, errors.Errorf("DialByName: speaker %q not found", name)
+}
+
+// dialVerify connects to addr and requests the product info, the
+// client is returned as long as the connection succeeded.
+func dialVerify(ctx context.Context, addr string, opts []DialOption) (*Client, *api.ProductInfo, error) {
+	dctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	c, err := Dial(dctx, addr, opts...)
+	if err != nil {
+		return nil, nil, err
+	}
+	info, err := c.ProductInfo(dctx, time.Now(), false)
+	if err != nil {
+		return c, nil, err
+	}
+	return c, info, nil
+}
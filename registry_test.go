@@ -0,0 +1,50 @@
+package musicflow
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRegistrySave(t *testing.T) {
+	dir, err := ioutil.TempDir("", "musicflow")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "registry", "registry.json")
+
+	r, err := LoadRegistry(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.Add("kitchen", Speaker{Addr: "192.168.1.20:9741", WirelessMAC: "00:11:22:33:44:55"})
+	for i := 0; i < 2; i++ { // Replaces the file.
+		if err = r.Save(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	files, err := ioutil.ReadDir(filepath.Dir(path))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 1 || files[0].Name() != "registry.json" {
+		var names []string
+		for _, f := range files {
+			names = append(names, f.Name())
+		}
+		t.Errorf("files = %v, want only registry.json", names)
+	} else if mode := files[0].Mode().Perm(); mode != 0o644 {
+		t.Errorf("mode = %v, want 0644", mode)
+	}
+
+	r, err = LoadRegistry(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e, ok := r.Lookup("kitchen"); !ok || e.Addr != "192.168.1.20:9741" {
+		t.Errorf("Lookup(kitchen) = %+v, %v; want the saved entry", e, ok)
+	}
+}
@@ -0,0 +1,125 @@
+package musicflow
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	errors "golang.org/x/xerrors"
+
+	"github.com/mafredri/musicflow/api"
+)
+
+// SleepTimer represents the state of the sleep timer.
+type SleepTimer struct {
+	Active    bool
+	Remaining time.Duration // Minute precision.
+}
+
+func (t SleepTimer) String() string {
+	if !t.Active {
+		return "Disabled"
+	}
+	return fmt.Sprintf("%s remaining", t.Remaining)
+}
+
+// SleepInfo returns the state of the sleep timer.
+func (c *Client) SleepInfo(ctx context.Context) (SleepTimer, error) {
+	req := api.SleepInfoRequest{}
+	reply := req.Reply()
+	err := c.Send(ctx, newRequest(req), reply)
+	if err != nil {
+		return SleepTimer{}, errors.Errorf("SleepInfo failed: %w", err)
+	}
+	if reply.Time < 0 {
+		return SleepTimer{}, nil
+	}
+	return SleepTimer{
+		Active:    true,
+		Remaining: time.Duration(reply.Time) * time.Minute,
+	}, nil
+}
+
+// sleepRestoreDelay is the time waited after the sleep timer fires
+// before restoring the volume.
+var sleepRestoreDelay = 10 * time.Second
+
+// SleepFadeOut sets the sleep timer (rounded up to whole minutes) and
+// lowers the volume step by step during the final fade duration. Once
+// the speaker has gone to sleep, the original volume is restored so
+// that it starts at the same volume next time.
+//
+// SleepFadeOut blocks until done. If the context is canceled or the
+// sleep timer is disabled by someone else, the fade is aborted and the
+// original volume restored, the sleep timer is left as is.
+func (c *Client) SleepFadeOut(ctx context.Context, after, fade time.Duration) error {
+	minutes := int((after + time.Minute - 1) / time.Minute)
+	if minutes <= 0 {
+		return errors.New("SleepFadeOut: sleep time must be positive")
+	}
+	after = time.Duration(minutes) * time.Minute
+	if fade > after {
+		fade = after
+	}
+
+	info, err := c.ProductInfo(ctx, time.Now(), false)
+	if err != nil {
+		return errors.Errorf("SleepFadeOut failed: %w", err)
+	}
+	volume := info.Info.Volume
+
+	err = c.SleepAfter(ctx, minutes)
+	if err != nil {
+		return errors.Errorf("SleepFadeOut failed: %w", err)
+	}
+	sleepAt := time.Now().Add(after)
+
+	restore := func() error {
+		// Use a new context, ctx may already be done.
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		err := c.Volume(ctx, volume, 0)
+		if err != nil {
+			return errors.Errorf("SleepFadeOut: restore volume failed: %w", err)
+		}
+		return nil
+	}
+	wait := func(d time.Duration) error {
+		t := time.NewTimer(d)
+		defer t.Stop()
+		select {
+		case <-t.C:
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	if err = wait(time.Until(sleepAt.Add(-fade))); err != nil {
+		return err // Volume not changed yet.
+	}
+
+	// Abort if the sleep timer was disabled in the meantime.
+	timer, err := c.SleepInfo(ctx)
+	if err != nil {
+		return errors.Errorf("SleepFadeOut failed: %w", err)
+	}
+	if !timer.Active {
+		return nil
+	}
+
+	_, err = c.VolumeTo(ctx, 0, time.Until(sleepAt), CurveLinear)
+	if err != nil {
+		_ = restore()
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		return errors.Errorf("SleepFadeOut failed: %w", err)
+	}
+
+	if err = wait(time.Until(sleepAt.Add(sleepRestoreDelay))); err != nil {
+		_ = restore()
+		return err
+	}
+	return restore()
+}
@@ -0,0 +1,135 @@
+package musicflow
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+
+	errors "golang.org/x/xerrors"
+
+	"github.com/mafredri/musicflow/api"
+)
+
+// SongServer serves local files over HTTP so that the speaker can
+// play them, e.g. as an alarm sound.
+//
+// Experimental: the speaker has not been observed playing from a song
+// server, the API may change or be removed.
+type SongServer struct {
+	ip  net.IP
+	l   net.Listener
+	srv *http.Server
+
+	mu    sync.Mutex
+	songs map[string]string // URL path to local file.
+}
+
+// NewSongServer starts a song server listening on the local address
+// used to communicate with the speaker (the speaker can reach it).
+// Port zero picks a random port.
+func (c *Client) NewSongServer(port int) (*SongServer, error) {
+	ip, err := c.localIP()
+	if err != nil {
+		return nil, errors.Errorf("NewSongServer failed: %w", err)
+	}
+	l, err := net.Listen("tcp", net.JoinHostPort(ip.String(), strconv.Itoa(port)))
+	if err != nil {
+		return nil, errors.Errorf("NewSongServer failed: %w", err)
+	}
+
+	s := &SongServer{
+		ip:    ip,
+		l:     l,
+		songs: make(map[string]string),
+	}
+	s.srv = &http.Server{Handler: http.HandlerFunc(s.serveHTTP)}
+	go func() { _ = s.srv.Serve(l) }()
+
+	return s, nil
+}
+
+// Serve makes the file at path available to the speaker and returns
+// the song that can be set on an alarm. Only files added via Serve are
+// accessible. The song has both the IP and the full URL since it is
+// not known which the speaker uses.
+func (s *SongServer) Serve(path string) (api.AlarmSong, error) {
+	path, err := filepath.Abs(path)
+	if err != nil {
+		return api.AlarmSong{}, err
+	}
+	fi, err := os.Stat(path)
+	if err != nil {
+		return api.AlarmSong{}, err
+	}
+	if fi.IsDir() {
+		return api.AlarmSong{}, errors.Errorf("SongServer: %s is a directory", path)
+	}
+
+	// Random prefix to avoid guessable URLs.
+	var b [8]byte
+	if _, err = rand.Read(b[:]); err != nil {
+		return api.AlarmSong{}, err
+	}
+	name := filepath.Base(path)
+	u := url.URL{
+		Scheme: "http",
+		Host:   s.l.Addr().String(),
+		Path:   fmt.Sprintf("/song/%s/%s", hex.EncodeToString(b[:]), name),
+	}
+
+	s.mu.Lock()
+	s.songs[u.Path] = path
+	s.mu.Unlock()
+
+	return api.AlarmSong{
+		Title:    strings.TrimSuffix(name, filepath.Ext(name)),
+		IPAddr:   s.ip.String(),
+		SongPath: u.String(),
+	}, nil
+}
+
+func (s *SongServer) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	path, ok := s.songs[r.URL.Path]
+	s.mu.Unlock()
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	http.ServeFile(w, r, path)
+}
+
+// Addr returns the address the song server is listening on.
+func (s *SongServer) Addr() net.Addr {
+	return s.l.Addr()
+}
+
+// Close stops the song server.
+func (s *SongServer) Close() error {
+	return s.srv.Close()
+}
+
+// localIP returns the local IP used to communicate with the speaker.
+func (c *Client) localIP() (net.IP, error) {
+	c.mu.RLock()
+	conn := c.conn
+	c.mu.RUnlock()
+
+	nc, ok := conn.(interface{ Conn() net.Conn })
+	if !ok {
+		return nil, errors.New("local address unknown, client was not created via Dial")
+	}
+	addr, ok := nc.Conn().LocalAddr().(*net.TCPAddr)
+	if !ok || addr.IP == nil {
+		return nil, errors.New("local address unknown")
+	}
+	return addr.IP, nil
+}
@@ -0,0 +1,221 @@
+package musicflow
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	errors "golang.org/x/xerrors"
+
+	"github.com/mafredri/musicflow/api"
+)
+
+// UpdatePhase represents the phase of a firmware update.
+type UpdatePhase int
+
+// UpdatePhase enums.
+const (
+	UpdateDownloading UpdatePhase = iota
+	UpdateWriting
+	UpdateRebooting
+	UpdateDone
+)
+
+func (p UpdatePhase) String() string {
+	switch p {
+	case UpdateDownloading:
+		return "Downloading"
+	case UpdateWriting:
+		return "Writing"
+	case UpdateRebooting:
+		return "Rebooting"
+	case UpdateDone:
+		return "Done"
+	default:
+		return fmt.Sprintf("UpdatePhase(%d)", p)
+	}
+}
+
+// UpdateStatus represents the progress of a firmware update.
+type UpdateStatus struct {
+	Phase    UpdatePhase
+	Progress int // Percentage of the current phase, 0-100.
+}
+
+func (s UpdateStatus) String() string {
+	return fmt.Sprintf("%s %d%%", s.Phase, s.Progress)
+}
+
+// reconnectInterval is the time waited between reconnect attempts
+// while the speaker reboots.
+var reconnectInterval = 5 * time.Second
+
+// versionSearchTimeout is how long CheckForUpdate waits for a reply
+// to NEW_VER_SEARCH.
+var versionSearchTimeout = 2 * time.Second
+
+// CheckForUpdate makes the speaker search for new firmware and
+// reports whether or not an update is available.
+func (c *Client) CheckForUpdate(ctx context.Context) (bool, error) {
+	// The reply to NEW_VER_SEARCH has not been observed, don't wait
+	// for it and rely on the update flag in product info instead.
+	searchCtx, cancel := context.WithTimeout(ctx, versionSearchTimeout)
+	err := c.Send(searchCtx, newRequest(api.NewVersionSearchRequest{}), nil)
+	cancel()
+	if err != nil && !(errors.Is(err, context.DeadlineExceeded) && ctx.Err() == nil) {
+		return false, errors.Errorf("CheckForUpdate failed: %w", err)
+	}
+
+	info, err := c.ProductInfo(ctx, time.Now(), false)
+	if err != nil {
+		return false, errors.Errorf("CheckForUpdate failed: %w", err)
+	}
+	return info.Info.Update, nil
+}
+
+// Update starts a firmware update and waits for it to complete. The
+// progress function (optional) is called whenever the status changes.
+//
+// The update is done on UPDATE_COMPLETE. Should the speaker reboot
+// before that and the client was created via Dial, the connection is
+// re-established once the speaker is back up. The context should have
+// a generous timeout since the update can take several minutes.
+func (c *Client) Update(ctx context.Context, progress func(UpdateStatus)) error {
+	if progress == nil {
+		progress = func(UpdateStatus) {}
+	}
+
+	broadcasts, unsubscribe := c.subscribe()
+	defer unsubscribe()
+
+	lost := c.lost()
+
+	req := api.UpdateStartRequest{Mandatory: true, Update: true}
+	reply := req.Reply()
+	err := c.Send(ctx, newRequest(req), reply)
+	if err != nil {
+		return errors.Errorf("Update failed: %w", err)
+	}
+	if !reply.Update {
+		return errors.New("Update: speaker refused to start the update")
+	}
+
+	status := UpdateStatus{Phase: UpdateDownloading}
+	setStatus := func(s UpdateStatus) {
+		status = s
+		progress(status)
+	}
+	setStatus(status)
+
+	downloaded := false
+	for {
+		var resp Response
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-lost:
+			if !downloaded {
+				return errors.New("Update: connection lost during download")
+			}
+			// Expected, the speaker reboots after writing the firmware.
+			setStatus(UpdateStatus{Phase: UpdateRebooting})
+			err = c.waitReconnect(ctx)
+			if err != nil {
+				return errors.Errorf("Update failed: %w", err)
+			}
+			_, err = c.ProductInfo(ctx, time.Now(), true)
+			if err != nil {
+				return errors.Errorf("Update failed: %w", err)
+			}
+			setStatus(UpdateStatus{Phase: UpdateDone, Progress: 100})
+			return nil
+		case resp = <-broadcasts:
+		}
+
+		switch resp.Message {
+		case api.MessageUpdateProgress:
+			var ev api.UpdateProgressEvent
+			if err = decodeEvent(resp, &ev); err != nil {
+				return errors.Errorf("Update failed: %w", err)
+			}
+			setStatus(UpdateStatus{Phase: status.Phase, Progress: ev.Progress})
+
+		case api.MessageUpdateDownResult:
+			var ev api.UpdateDownResultEvent
+			if err = decodeEvent(resp, &ev); err != nil {
+				return errors.Errorf("Update failed: %w", err)
+			}
+			if !ev.Result {
+				return errors.New("Update: firmware download failed")
+			}
+			downloaded = true
+
+		case api.MessageUpdateStartWrite:
+			downloaded = true
+			setStatus(UpdateStatus{Phase: UpdateWriting})
+
+		case api.MessageUpdateStartReboot:
+			setStatus(UpdateStatus{Phase: UpdateRebooting})
+
+		case api.MessageUpdateComplete:
+			var ev api.UpdateCompleteEvent
+			if err = decodeEvent(resp, &ev); err != nil {
+				return errors.Errorf("Update failed: %w", err)
+			}
+			if !ev.Complete {
+				return errors.New("Update: firmware update did not complete")
+			}
+			// The session carries on over the same connection
+			// after UPDATE_COMPLETE (UPDATE_RESULT has not been
+			// observed), the firmware is in place.
+			setStatus(UpdateStatus{Phase: UpdateDone, Progress: 100})
+			return nil
+
+		case api.MessageUpdateResult:
+			var ev api.UpdateResultEvent
+			if err = decodeEvent(resp, &ev); err != nil {
+				return errors.Errorf("Update failed: %w", err)
+			}
+			if !ev.Result {
+				return errors.New("Update: firmware update failed")
+			}
+			setStatus(UpdateStatus{Phase: UpdateDone, Progress: 100})
+			return nil
+		}
+	}
+}
+
+// waitReconnect tries to reconnect to the speaker until it succeeds
+// or the context is done.
+func (c *Client) waitReconnect(ctx context.Context) error {
+	for {
+		err := c.reconnect(ctx)
+		if err == nil {
+			return nil
+		}
+		if c.o.addr == "" {
+			return err
+		}
+		c.log().Printf("Reconnect failed, retrying in %s: %v", reconnectInterval, err)
+
+		select {
+		case <-time.After(reconnectInterval):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// decodeEvent decodes the data of a broadcasted event, events without
+// data are left as is.
+func decodeEvent(resp Response, v interface{}) error {
+	if len(resp.Data) == 0 {
+		return nil
+	}
+	err := json.Unmarshal(resp.Data, v)
+	if err != nil {
+		return errors.Errorf("unmarshal %s event into %T failed: %w", resp.Message, v, err)
+	}
+	return nil
+}
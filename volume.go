@@ -0,0 +1,168 @@
+package musicflow
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+
+	errors "golang.org/x/xerrors"
+
+	"github.com/mafredri/musicflow/api"
+)
+
+// VolumeCurve decides how the volume progresses during a ramp.
+type VolumeCurve int
+
+// VolumeCurve enums.
+const (
+	// CurveLinear changes the volume at a constant rate.
+	CurveLinear VolumeCurve = iota
+	// CurveLogarithmic changes the volume quickly at first and slows
+	// down towards the target.
+	CurveLogarithmic
+)
+
+func (v VolumeCurve) String() string {
+	switch v {
+	case CurveLinear:
+		return "Linear"
+	case CurveLogarithmic:
+		return "Logarithmic"
+	default:
+		return fmt.Sprintf("VolumeCurve(%d)", v)
+	}
+}
+
+// at returns the point in time (0-1) when the curve reaches
+// progress p (0-1).
+func (v VolumeCurve) at(p float64) float64 {
+	switch v {
+	case CurveLogarithmic:
+		// Inverse of p = log10(1 + 9t).
+		return (math.Pow(10, p) - 1) / 9
+	default:
+		return p
+	}
+}
+
+// VolumeUp raises the volume by step and returns the new volume.
+func (c *Client) VolumeUp(ctx context.Context, step int) (int, error) {
+	v, err := c.volumeStep(ctx, step)
+	if err != nil {
+		return 0, errors.Errorf("VolumeUp failed: %w", err)
+	}
+	return v, nil
+}
+
+// VolumeDown lowers the volume by step and returns the new volume.
+func (c *Client) VolumeDown(ctx context.Context, step int) (int, error) {
+	v, err := c.volumeStep(ctx, -step)
+	if err != nil {
+		return 0, errors.Errorf("VolumeDown failed: %w", err)
+	}
+	return v, nil
+}
+
+// volumeStep changes the volume by delta. The current volume is read
+// and the new one set, VOLUME_UP and VOLUME_DOWN have not been
+// observed and are not used.
+func (c *Client) volumeStep(ctx context.Context, delta int) (int, error) {
+	info, err := c.ProductInfo(ctx, time.Now(), false)
+	if err != nil {
+		return 0, err
+	}
+	volume := info.Info.Volume + delta
+	if volume < 0 {
+		volume = 0
+	}
+	if volume == info.Info.Volume {
+		return volume, nil
+	}
+	return c.setVolume(ctx, volume)
+}
+
+// setVolume sets the volume and returns the volume confirmed by the
+// speaker.
+func (c *Client) setVolume(ctx context.Context, volume int) (int, error) {
+	broadcasts, unsubscribe := c.subscribe()
+	defer unsubscribe()
+
+	err := c.Volume(ctx, volume, 0)
+	if err != nil {
+		return 0, err
+	}
+	if v, ok := volumeChanged(broadcasts); ok {
+		return v, nil
+	}
+	// No change broadcasted, the volume was already set.
+	return volume, nil
+}
+
+// volumeChanged returns the last volume change on broadcasts. Changes
+// are broadcasted before the reply so there is no need to wait.
+func volumeChanged(broadcasts <-chan Response) (volume int, ok bool) {
+	for {
+		select {
+		case resp := <-broadcasts:
+			if resp.Message != api.MessageVolumeChange {
+				continue
+			}
+			var ev api.VolumeChangeEvent
+			if decodeEvent(resp, &ev) == nil {
+				volume, ok = ev.Volume, true
+			}
+		default:
+			return volume, ok
+		}
+	}
+}
+
+// VolumeTo ramps the volume from the current volume to target over
+// the duration, one step at a time following the curve. Returns the
+// volume confirmed by the speaker. When the context is canceled the
+// ramp stops and the volume reached so far is returned together with
+// the context error.
+func (c *Client) VolumeTo(ctx context.Context, target int, duration time.Duration, curve VolumeCurve) (int, error) {
+	info, err := c.ProductInfo(ctx, time.Now(), false)
+	if err != nil {
+		return 0, errors.Errorf("VolumeTo failed: %w", err)
+	}
+	from := info.Info.Volume
+
+	steps := target - from
+	dir := 1
+	if steps < 0 {
+		steps, dir = -steps, -1
+	}
+	if steps == 0 {
+		return from, nil
+	}
+	if duration <= 0 {
+		v, err := c.setVolume(ctx, target)
+		if err != nil {
+			return from, errors.Errorf("VolumeTo failed: %w", err)
+		}
+		return v, nil
+	}
+
+	start := time.Now()
+	volume := from
+	for i := 1; i <= steps; i++ {
+		at := start.Add(time.Duration(curve.at(float64(i)/float64(steps)) * float64(duration)))
+		timer := time.NewTimer(time.Until(at))
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return volume, ctx.Err()
+		}
+
+		v, err := c.setVolume(ctx, from+i*dir)
+		if err != nil {
+			return volume, errors.Errorf("VolumeTo failed: %w", err)
+		}
+		volume = v
+	}
+	return volume, nil
+}